@@ -0,0 +1,89 @@
+// Package batch records the progress of batched jobs in Laravel's job_batches
+// table, so batches can be shared between Go and PHP with Bus::batch() and Bus::findBatch().
+package batch
+
+import (
+	"context"
+	"time"
+)
+
+// Batch is a batch of jobs as stored in the job_batches table
+type Batch struct {
+	ID           string
+	Name         string
+	TotalJobs    int
+	PendingJobs  int
+	FailedJobs   int
+	FailedJobIDs []string
+	Options      Options
+	CreatedAt    time.Time
+	CancelledAt  time.Time // Zero unless the batch was cancelled
+	FinishedAt   time.Time // Zero until every job has completed
+}
+
+// ProcessedJobs returns the number of jobs that have run, whether they succeeded or failed
+func (b *Batch) ProcessedJobs() int {
+	return b.TotalJobs - b.PendingJobs
+}
+
+// Progress returns the percentage of processed jobs, like Batch::progress
+func (b *Batch) Progress() int {
+	if b.TotalJobs == 0 {
+		return 0
+	}
+	return b.ProcessedJobs() * 100 / b.TotalJobs
+}
+
+// Finished reports whether every job of the batch has completed
+func (b *Batch) Finished() bool {
+	return !b.FinishedAt.IsZero()
+}
+
+// Cancelled reports whether the batch was cancelled
+func (b *Batch) Cancelled() bool {
+	return !b.CancelledAt.IsZero()
+}
+
+// HasFailures reports whether any job of the batch has failed
+func (b *Batch) HasFailures() bool {
+	return b.FailedJobs > 0
+}
+
+// UpdatedBatchJobCounts holds the job counts of a batch after recording a job
+type UpdatedBatchJobCounts struct {
+	PendingJobs int
+	FailedJobs  int
+}
+
+// AllJobsHaveRanExactlyOnce reports whether every pending job has failed,
+// meaning each job of the batch has run at least once
+func (c UpdatedBatchJobCounts) AllJobsHaveRanExactlyOnce() bool {
+	return c.PendingJobs-c.FailedJobs == 0
+}
+
+// Repository stores batches, like Laravel's BatchRepository
+type Repository interface {
+	// Find returns a batch by id, or nil if it does not exist
+	Find(ctx context.Context, id string) (*Batch, error)
+
+	// Store creates an empty batch
+	Store(ctx context.Context, name string, options Options) (*Batch, error)
+
+	// IncrementTotalJobs adds jobs to a batch
+	IncrementTotalJobs(ctx context.Context, id string, amount int) error
+
+	// DecrementPendingJobs records a job that completed successfully
+	DecrementPendingJobs(ctx context.Context, id string, jobID string) (UpdatedBatchJobCounts, error)
+
+	// IncrementFailedJobs records a job that failed
+	IncrementFailedJobs(ctx context.Context, id string, jobID string) (UpdatedBatchJobCounts, error)
+
+	// MarkAsFinished sets the time the batch finished
+	MarkAsFinished(ctx context.Context, id string) error
+
+	// Cancel marks the batch as cancelled and finished
+	Cancel(ctx context.Context, id string) error
+
+	// Delete removes a batch
+	Delete(ctx context.Context, id string) error
+}
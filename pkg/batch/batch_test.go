@@ -0,0 +1,249 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pixelvide/laravel-go/pkg/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepository implements Repository in memory
+type memoryRepository struct {
+	mu      sync.Mutex
+	batches map[string]*Batch
+	nextID  int
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{batches: make(map[string]*Batch)}
+}
+
+func (r *memoryRepository) Find(ctx context.Context, id string) (*Batch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.batches[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *b
+	copied.FailedJobIDs = append([]string(nil), b.FailedJobIDs...)
+	return &copied, nil
+}
+
+func (r *memoryRepository) Store(ctx context.Context, name string, options Options) (*Batch, error) {
+	r.mu.Lock()
+	r.nextID++
+	id := fmt.Sprintf("batch-%d", r.nextID)
+	r.batches[id] = &Batch{ID: id, Name: name, Options: options, CreatedAt: time.Now()}
+	r.mu.Unlock()
+	return r.Find(ctx, id)
+}
+
+func (r *memoryRepository) IncrementTotalJobs(ctx context.Context, id string, amount int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches[id].TotalJobs += amount
+	r.batches[id].PendingJobs += amount
+	return nil
+}
+
+func (r *memoryRepository) DecrementPendingJobs(ctx context.Context, id string, jobID string) (UpdatedBatchJobCounts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.batches[id]
+	b.PendingJobs--
+	return UpdatedBatchJobCounts{PendingJobs: b.PendingJobs, FailedJobs: b.FailedJobs}, nil
+}
+
+func (r *memoryRepository) IncrementFailedJobs(ctx context.Context, id string, jobID string) (UpdatedBatchJobCounts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.batches[id]
+	b.FailedJobs++
+	b.FailedJobIDs = append(b.FailedJobIDs, jobID)
+	return UpdatedBatchJobCounts{PendingJobs: b.PendingJobs, FailedJobs: b.FailedJobs}, nil
+}
+
+func (r *memoryRepository) MarkAsFinished(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches[id].FinishedAt = time.Now()
+	return nil
+}
+
+func (r *memoryRepository) Cancel(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches[id].CancelledAt = time.Now()
+	r.batches[id].FinishedAt = time.Now()
+	return nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.batches, id)
+	return nil
+}
+
+// pushRecorder implements queue.Driver, recording pushed jobs
+type pushRecorder struct {
+	pushed []*queue.Job
+	err    error
+}
+
+func (d *pushRecorder) Pop(ctx context.Context, queueName string) (*queue.Job, error) {
+	return nil, nil
+}
+
+func (d *pushRecorder) Push(ctx context.Context, queueName string, body []byte) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.pushed = append(d.pushed, &queue.Job{Queue: queueName, Body: body})
+	return nil
+}
+
+func (d *pushRecorder) Ack(ctx context.Context, job *queue.Job) error {
+	return nil
+}
+
+func TestPendingBatch_Dispatch(t *testing.T) {
+	repo := newMemoryRepository()
+	driver := &pushRecorder{}
+
+	b, err := New(repo, queue.NewPublisher(driver),
+		Job{Name: "App\\Jobs\\ImportCsv", Args: map[string]interface{}{"line": 1}},
+		Job{Name: "App\\Jobs\\ImportCsv", Args: map[string]interface{}{"line": 2}},
+	).Name("import").AllowFailures().OnQueue("imports").Dispatch(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "import", b.Name)
+	assert.Equal(t, 2, b.TotalJobs)
+	assert.Equal(t, 2, b.PendingJobs)
+	assert.True(t, b.Options.AllowFailures)
+
+	require.Len(t, driver.pushed, 2)
+	for _, job := range driver.pushed {
+		assert.Equal(t, "imports", job.Queue)
+
+		var payload queue.LaravelJob
+		require.NoError(t, json.Unmarshal(job.Body, &payload))
+		job.UnserializedData, err = queue.UnserializeCommand(payload.Data)
+		require.NoError(t, err)
+		assert.Equal(t, b.ID, ID(job))
+	}
+}
+
+func TestPendingBatch_DispatchDeletesBatchWhenPushFails(t *testing.T) {
+	repo := newMemoryRepository()
+	driver := &pushRecorder{err: errors.New("connection refused")}
+
+	_, err := New(repo, queue.NewPublisher(driver), Job{Name: "App\\Jobs\\ImportCsv"}).Dispatch(context.Background())
+	assert.Error(t, err)
+	assert.Empty(t, repo.batches)
+}
+
+func TestRecorder_RecordSuccessfulJob(t *testing.T) {
+	repo := newMemoryRepository()
+	stored, _ := repo.Store(context.Background(), "recorder-success", Options{})
+	_ = repo.IncrementTotalJobs(context.Background(), stored.ID, 2)
+
+	var then, finally int
+	Then("recorder-success", func(ctx context.Context, b *Batch) error {
+		then++
+		assert.True(t, b.Finished())
+		return nil
+	})
+	Finally("recorder-success", func(ctx context.Context, b *Batch) error {
+		finally++
+		return nil
+	})
+
+	recorder := NewRecorder(repo)
+	require.NoError(t, recorder.RecordSuccessfulJob(context.Background(), stored.ID, "job-1"))
+	assert.Equal(t, 0, then)
+
+	require.NoError(t, recorder.RecordSuccessfulJob(context.Background(), stored.ID, "job-2"))
+	assert.Equal(t, 1, then)
+	assert.Equal(t, 1, finally)
+
+	b, _ := repo.Find(context.Background(), stored.ID)
+	assert.True(t, b.Finished())
+	assert.Equal(t, 100, b.Progress())
+}
+
+func TestRecorder_RecordFailedJob(t *testing.T) {
+	tests := []struct {
+		name          string
+		allowFailures bool
+	}{
+		{"recorder-cancel", false},
+		{"recorder-allow-failures", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemoryRepository()
+			stored, _ := repo.Store(context.Background(), tt.name, Options{AllowFailures: tt.allowFailures})
+			_ = repo.IncrementTotalJobs(context.Background(), stored.ID, 2)
+
+			var caught error
+			Catch(tt.name, func(ctx context.Context, b *Batch, err error) error {
+				caught = err
+				return nil
+			})
+
+			recorder := NewRecorder(repo)
+			jobErr := errors.New("boom")
+			require.NoError(t, recorder.RecordFailedJob(context.Background(), stored.ID, "job-1", jobErr))
+
+			assert.Equal(t, jobErr, caught)
+			b, _ := repo.Find(context.Background(), stored.ID)
+			assert.Equal(t, !tt.allowFailures, b.Cancelled())
+			assert.Equal(t, []string{"job-1"}, b.FailedJobIDs)
+		})
+	}
+}
+
+func TestRecorder_IgnoresMissingBatch(t *testing.T) {
+	recorder := NewRecorder(newMemoryRepository())
+	assert.NoError(t, recorder.RecordSuccessfulJob(context.Background(), "missing", "job-1"))
+	assert.NoError(t, recorder.RecordFailedJob(context.Background(), "missing", "job-1", errors.New("boom")))
+}
+
+func TestSkipIfCancelled(t *testing.T) {
+	repo := newMemoryRepository()
+	stored, _ := repo.Store(context.Background(), "", Options{})
+	_ = repo.Cancel(context.Background(), stored.ID)
+
+	ran := false
+	handler := queue.Chain(func(ctx context.Context, job *queue.Job) error {
+		ran = true
+		return nil
+	}, SkipIfCancelled(repo))
+
+	command := queue.NewCommand("App\\Jobs\\ImportCsv", map[string]interface{}{"batchId": stored.ID})
+	job := &queue.Job{UnserializedData: command}
+
+	assert.NoError(t, handler(context.Background(), job))
+	assert.False(t, ran)
+}
+
+func TestOptions_RoundTrip(t *testing.T) {
+	serialized, err := SerializeOptions(Options{AllowFailures: true, Queue: "imports"})
+	require.NoError(t, err)
+	assert.Equal(t, Options{AllowFailures: true, Queue: "imports"}, UnserializeOptions(serialized))
+
+	// Options holding closures PHP serialized are searched for allowFailures
+	withClosure := `a:2:{s:13:"allowFailures";b:1;s:4:"then";a:1:{i:0;O:47:"Laravel\SerializableClosure\SerializableClosure":1:{s:12:"serializable";r:1;}}}`
+	assert.True(t, UnserializeOptions(withClosure).AllowFailures)
+	assert.Equal(t, Options{}, UnserializeOptions("a:0:{}"))
+}
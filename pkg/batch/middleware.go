@@ -0,0 +1,37 @@
+package batch
+
+import (
+	"context"
+
+	"github.com/pixelvide/laravel-go/pkg/queue"
+	"github.com/rs/zerolog"
+)
+
+// ID returns the id of the batch a job belongs to, or an empty string if it is not batched
+func ID(job *queue.Job) string {
+	id, _ := job.GetArg("batchId").(string)
+	return id
+}
+
+// SkipIfCancelled is job middleware that skips jobs of cancelled batches,
+// like Laravel's SkipIfBatchCancelled. Skipped jobs count as completed.
+func SkipIfCancelled(repository Repository) queue.Middleware {
+	return func(next queue.Handler) queue.Handler {
+		return func(ctx context.Context, job *queue.Job) error {
+			batchID := ID(job)
+			if batchID == "" {
+				return next(ctx, job)
+			}
+
+			batch, err := repository.Find(ctx, batchID)
+			if err != nil {
+				return err
+			}
+			if batch != nil && batch.Cancelled() {
+				zerolog.Ctx(ctx).Info().Str("batch_id", batchID).Msg("Skipping job of cancelled batch")
+				return nil
+			}
+			return next(ctx, job)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package batch
+
+import (
+	"strings"
+
+	"github.com/yvasiyarov/php_session_decoder/php_serialize"
+)
+
+// Options are the options of a batch Go understands. Laravel stores them as a
+// serialized PHP array, which also holds the batch callbacks as closures.
+type Options struct {
+	AllowFailures bool
+	Queue         string
+	Connection    string
+}
+
+// SerializeOptions serializes options the way PendingBatch stores them
+func SerializeOptions(options Options) (string, error) {
+	array := php_serialize.PhpArray{}
+	if options.AllowFailures {
+		array["allowFailures"] = true
+	}
+	if options.Queue != "" {
+		array["queue"] = options.Queue
+	}
+	if options.Connection != "" {
+		array["connection"] = options.Connection
+	}
+	return php_serialize.NewSerializer().Encode(array)
+}
+
+// UnserializeOptions reads the options column of a batch. Options that
+// cannot be decoded, e.g. because of the closures PHP stored with them, are
+// searched for allowFailures only.
+func UnserializeOptions(serialized string) Options {
+	decoded, err := php_serialize.UnSerialize(serialized)
+	array, ok := decoded.(php_serialize.PhpArray)
+	if err != nil || !ok {
+		return Options{AllowFailures: strings.Contains(serialized, `s:13:"allowFailures";b:1;`)}
+	}
+
+	var options Options
+	if v, ok := array["allowFailures"]; ok {
+		options.AllowFailures = php_serialize.PhpValueBool(v)
+	}
+	if v, ok := array["queue"].(string); ok {
+		options.Queue = v
+	}
+	if v, ok := array["connection"].(string); ok {
+		options.Connection = v
+	}
+	return options
+}
@@ -0,0 +1,93 @@
+package batch
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pixelvide/laravel-go/pkg/queue"
+)
+
+// Job is a job of a batch
+type Job struct {
+	Name string                 // Laravel job class name
+	Args map[string]interface{} // Public properties of the job object
+}
+
+// PendingBatch is a batch being configured before it is dispatched, like the
+// PendingBatch returned by Bus::batch()
+type PendingBatch struct {
+	repository Repository
+	publisher  *queue.Publisher
+	jobs       []Job
+	name       string
+	options    Options
+}
+
+// New creates a batch of jobs dispatched with publisher and stored in repository
+func New(repository Repository, publisher *queue.Publisher, jobs ...Job) *PendingBatch {
+	return &PendingBatch{repository: repository, publisher: publisher, jobs: jobs}
+}
+
+// Name sets the name of the batch, which also selects the callbacks Go workers run
+func (p *PendingBatch) Name(name string) *PendingBatch {
+	p.name = name
+	return p
+}
+
+// AllowFailures keeps the batch going when a job fails instead of cancelling it
+func (p *PendingBatch) AllowFailures() *PendingBatch {
+	p.options.AllowFailures = true
+	return p
+}
+
+// OnQueue pushes the jobs of the batch onto the given queue
+func (p *PendingBatch) OnQueue(queueName string) *PendingBatch {
+	p.options.Queue = queueName
+	return p
+}
+
+// Dispatch stores the batch and pushes its jobs, each carrying the batch id
+// in its batchId property like the Batchable trait. The batch is deleted if
+// it cannot be stored completely.
+func (p *PendingBatch) Dispatch(ctx context.Context) (*Batch, error) {
+	if len(p.jobs) == 0 {
+		return nil, errors.New("batch: a batch needs at least one job")
+	}
+
+	batch, err := p.repository.Store(ctx, p.name, p.options)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.add(ctx, batch); err != nil {
+		_ = p.repository.Delete(ctx, batch.ID)
+		return nil, err
+	}
+
+	return p.repository.Find(ctx, batch.ID)
+}
+
+// add pushes the jobs onto the queue, like Batch::add
+func (p *PendingBatch) add(ctx context.Context, batch *Batch) error {
+	if err := p.repository.IncrementTotalJobs(ctx, batch.ID, len(p.jobs)); err != nil {
+		return err
+	}
+
+	var opts []queue.DispatchOption
+	if p.options.Queue != "" {
+		opts = append(opts, queue.OnQueue(p.options.Queue))
+	}
+
+	for _, job := range p.jobs {
+		args := make(map[string]interface{}, len(job.Args)+1)
+		for key, value := range job.Args {
+			args[key] = value
+		}
+		args["batchId"] = batch.ID
+
+		if err := p.publisher.Dispatch(ctx, job.Name, args, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package batch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Callback is run when a batch reaches a stage, like the closures passed to
+// then(), progress() and finally() of Bus::batch()
+type Callback func(ctx context.Context, batch *Batch) error
+
+// CatchCallback is run when the first job of a batch fails, like catch()
+type CatchCallback func(ctx context.Context, batch *Batch, err error) error
+
+// callbacks holds the callbacks of the batches with a given name
+type callbacks struct {
+	then     []Callback
+	catch    []CatchCallback
+	finally  []Callback
+	progress []Callback
+}
+
+var (
+	// registry maps batch names to their callbacks
+	registry = make(map[string]*callbacks)
+	mu       sync.RWMutex
+)
+
+func register(name string, fn func(c *callbacks)) {
+	mu.Lock()
+	defer mu.Unlock()
+	c, ok := registry[name]
+	if !ok {
+		c = &callbacks{}
+		registry[name] = c
+	}
+	fn(c)
+}
+
+// Then registers a callback run when every job of the batches with the given name has succeeded.
+// The closures PHP stored with a batch can only be run by PHP workers, so Go
+// workers run the callbacks registered for the batch's name instead.
+func Then(name string, cb Callback) {
+	register(name, func(c *callbacks) { c.then = append(c.then, cb) })
+}
+
+// Catch registers a callback run when the first job of the batches with the given name fails
+func Catch(name string, cb CatchCallback) {
+	register(name, func(c *callbacks) { c.catch = append(c.catch, cb) })
+}
+
+// Finally registers a callback run once every job of the batches with the given name has run
+func Finally(name string, cb Callback) {
+	register(name, func(c *callbacks) { c.finally = append(c.finally, cb) })
+}
+
+// Progress registers a callback run whenever a job of the batches with the given name completes
+func Progress(name string, cb Callback) {
+	register(name, func(c *callbacks) { c.progress = append(c.progress, cb) })
+}
+
+func callbacksFor(name string) callbacks {
+	mu.RLock()
+	defer mu.RUnlock()
+	if c, ok := registry[name]; ok {
+		return *c
+	}
+	return callbacks{}
+}
+
+// Recorder updates batches as their jobs complete, like Batch::recordSuccessfulJob
+// and Batch::recordFailedJob. The worker calls it for jobs with a batchId.
+type Recorder struct {
+	repository Repository
+}
+
+// NewRecorder creates a recorder updating batches in repository
+func NewRecorder(repository Repository) *Recorder {
+	return &Recorder{repository: repository}
+}
+
+// RecordSuccessfulJob records a job of the batch that completed successfully.
+// Jobs of batches that no longer exist are ignored.
+func (r *Recorder) RecordSuccessfulJob(ctx context.Context, batchID string, jobID string) error {
+	batch, err := r.repository.Find(ctx, batchID)
+	if err != nil || batch == nil {
+		return err
+	}
+
+	counts, err := r.repository.DecrementPendingJobs(ctx, batchID, jobID)
+	if err != nil {
+		return err
+	}
+	batch.PendingJobs, batch.FailedJobs = counts.PendingJobs, counts.FailedJobs
+
+	cbs := callbacksFor(batch.Name)
+	r.run(ctx, batch, cbs.progress)
+
+	if counts.PendingJobs == 0 {
+		if err := r.repository.MarkAsFinished(ctx, batchID); err != nil {
+			return err
+		}
+		batch.FinishedAt = time.Now()
+		r.run(ctx, batch, cbs.then)
+	}
+
+	if counts.AllJobsHaveRanExactlyOnce() {
+		r.run(ctx, batch, cbs.finally)
+	}
+	return nil
+}
+
+// RecordFailedJob records a job of the batch that failed. The first failure
+// cancels the batch unless it allows failures.
+func (r *Recorder) RecordFailedJob(ctx context.Context, batchID string, jobID string, jobErr error) error {
+	batch, err := r.repository.Find(ctx, batchID)
+	if err != nil || batch == nil {
+		return err
+	}
+
+	counts, err := r.repository.IncrementFailedJobs(ctx, batchID, jobID)
+	if err != nil {
+		return err
+	}
+	batch.PendingJobs, batch.FailedJobs = counts.PendingJobs, counts.FailedJobs
+
+	if counts.FailedJobs == 1 && !batch.Options.AllowFailures {
+		if err := r.repository.Cancel(ctx, batchID); err != nil {
+			return err
+		}
+		batch.CancelledAt = time.Now()
+		batch.FinishedAt = batch.CancelledAt
+	}
+
+	cbs := callbacksFor(batch.Name)
+	r.run(ctx, batch, cbs.progress)
+
+	if counts.FailedJobs == 1 {
+		for _, cb := range cbs.catch {
+			if err := cb(ctx, batch, jobErr); err != nil {
+				zerolog.Ctx(ctx).Error().Err(err).Str("batch_id", batch.ID).Msg("Batch callback failed")
+			}
+		}
+	}
+
+	if counts.AllJobsHaveRanExactlyOnce() {
+		r.run(ctx, batch, cbs.finally)
+	}
+	return nil
+}
+
+// run invokes callbacks, reporting their errors like Batch::invokeHandlerCallback
+func (r *Recorder) run(ctx context.Context, batch *Batch, cbs []Callback) {
+	for _, cb := range cbs {
+		if err := cb(ctx, batch); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Str("batch_id", batch.ID).Msg("Batch callback failed")
+		}
+	}
+}
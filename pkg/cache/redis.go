@@ -2,31 +2,115 @@ package cache
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// releaseLockScript deletes a lock only if it is still held by the owner,
+// like Illuminate\Cache\LuaScripts::releaseLock
+var releaseLockScript = redis.NewScript(`
+if redis.call("get",KEYS[1]) == ARGV[1] then
+    return redis.call("del",KEYS[1])
+else
+    return 0
+end
+`)
+
 type RedisStore struct {
 	client *redis.Client
+	prefix string
+
+	// owners holds the owner token of every lock acquired by this store
+	owners   map[string]string
+	ownersMu sync.Mutex
 }
 
+// NewRedisStore creates a new Redis cache store whose keys are not prefixed
 func NewRedisStore(client *redis.Client) *RedisStore {
-	return &RedisStore{client: client}
+	return NewPrefixedRedisStore(client, "")
+}
+
+// NewPrefixedRedisStore creates a new Redis cache store.
+// prefix is prepended to every key; Laravel uses REDIS_PREFIX followed by CACHE_PREFIX.
+func NewPrefixedRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix, owners: make(map[string]string)}
 }
 
 func (s *RedisStore) Get(ctx context.Context, key string) (string, error) {
-	return s.client.Get(ctx, key).Result()
+	return s.client.Get(ctx, s.prefix+key).Result()
 }
 
 func (s *RedisStore) Put(ctx context.Context, key string, value string, ttl time.Duration) error {
-	return s.client.Set(ctx, key, value, ttl).Err()
+	return s.client.Set(ctx, s.prefix+key, value, ttl).Err()
 }
 
 func (s *RedisStore) Forget(ctx context.Context, key string) error {
-	return s.client.Del(ctx, key).Err()
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
+
+func (s *RedisStore) Increment(ctx context.Context, key string, value int64) (int64, error) {
+	return s.client.IncrBy(ctx, s.prefix+key, value).Result()
+}
+
+// Add stores value unless key exists, reporting whether it was stored
+func (s *RedisStore) Add(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, s.prefix+key, value, ttl).Result()
 }
 
 func (s *RedisStore) Flush(ctx context.Context) error {
 	return s.client.FlushDB(ctx).Err()
 }
+
+// GetLock acquires the same lock as Cache::lock($name, $seconds)->get() in
+// Laravel, so Go and PHP processes exclude each other. A zero duration never expires.
+func (s *RedisStore) GetLock(ctx context.Context, name string, duration time.Duration) (bool, error) {
+	owner, err := randomOwner()
+	if err != nil {
+		return false, err
+	}
+
+	acquired, err := s.client.SetNX(ctx, s.prefix+name, owner, duration).Result()
+	if err != nil || !acquired {
+		return false, err
+	}
+
+	s.ownersMu.Lock()
+	s.owners[name] = owner
+	s.ownersMu.Unlock()
+	return true, nil
+}
+
+// ReleaseLock releases a lock acquired by this store, leaving locks held by others untouched
+func (s *RedisStore) ReleaseLock(ctx context.Context, name string) error {
+	s.ownersMu.Lock()
+	owner, ok := s.owners[name]
+	delete(s.owners, name)
+	s.ownersMu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return releaseLockScript.Run(ctx, s.client, []string{s.prefix + name}, owner).Err()
+}
+
+// ForceReleaseLock releases a lock regardless of its owner, like Lock::forceRelease
+func (s *RedisStore) ForceReleaseLock(ctx context.Context, name string) error {
+	s.ownersMu.Lock()
+	delete(s.owners, name)
+	s.ownersMu.Unlock()
+
+	return s.client.Del(ctx, s.prefix+name).Err()
+}
+
+// randomOwner returns a random lock owner token, like Str::random(20)
+func randomOwner() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
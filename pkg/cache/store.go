@@ -12,3 +12,26 @@ type Store interface {
 	Forget(ctx context.Context, key string) error
 	Flush(ctx context.Context) error
 }
+
+// LockStore is implemented by stores providing Laravel compatible cache
+// locks, like Cache::lock()
+type LockStore interface {
+	// GetLock acquires the lock for duration (0 never expires), returning false if it is held
+	GetLock(ctx context.Context, name string, duration time.Duration) (bool, error)
+	// ReleaseLock releases a lock acquired by this store
+	ReleaseLock(ctx context.Context, name string) error
+	// ForceReleaseLock releases a lock whoever holds it
+	ForceReleaseLock(ctx context.Context, name string) error
+}
+
+// Incrementer is implemented by stores that can atomically increment a
+// numeric value, like Laravel's Store::increment
+type Incrementer interface {
+	Increment(ctx context.Context, key string, value int64) (int64, error)
+}
+
+// Adder is implemented by stores that can atomically store a value only if
+// the key does not exist yet, like Laravel's Repository::add
+type Adder interface {
+	Add(ctx context.Context, key string, value string, ttl time.Duration) (bool, error)
+}
@@ -2,12 +2,14 @@ package config
 
 // Config holds the global application configuration
 type Config struct {
-	App      AppConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	Queue    QueueConfig
-	Cache    CacheConfig
-	Mail     MailConfig
+	App        AppConfig
+	Database   DatabaseConfig
+	Redis      RedisConfig
+	Queue      QueueConfig
+	SQS        SQSConfig
+	Beanstalkd BeanstalkdConfig
+	Cache      CacheConfig
+	Mail       MailConfig
 }
 
 // AppConfig maps to APP_* variables
@@ -15,6 +17,10 @@ type AppConfig struct {
 	Name string `env:"APP_NAME" envDefault:"Laravel"`
 	Env  string `env:"APP_ENV" envDefault:"production"`
 	Key  string `env:"APP_KEY"`
+
+	// Cipher and PreviousKeys configure the encrypter like Laravel's config/app.php
+	Cipher       string   `env:"APP_CIPHER" envDefault:"AES-256-CBC"`
+	PreviousKeys []string `env:"APP_PREVIOUS_KEYS" envSeparator:","`
 }
 
 // DatabaseConfig maps to DB_* variables
@@ -25,6 +31,10 @@ type DatabaseConfig struct {
 	Database   string `env:"DB_DATABASE" envDefault:"forge"`
 	Username   string `env:"DB_USERNAME" envDefault:"forge"`
 	Password   string `env:"DB_PASSWORD" envDefault:""`
+
+	// QueueTable and QueueRetryAfter mirror the "database" connection in Laravel's config/queue.php
+	QueueTable      string `env:"DB_QUEUE_TABLE" envDefault:"jobs"`
+	QueueRetryAfter int    `env:"DB_QUEUE_RETRY_AFTER" envDefault:"90"`
 }
 
 // RedisConfig maps to REDIS_* variables
@@ -34,27 +44,88 @@ type RedisConfig struct {
 	Password string `env:"REDIS_PASSWORD" envDefault:""`
 	DB       int    `env:"REDIS_DB" envDefault:"0"`
 	CacheDB  int    `env:"REDIS_CACHE_DB" envDefault:"1"`
+
+	// Prefix is prepended to every key, like Laravel's redis "prefix" option.
+	// When REDIS_PREFIX is not set, Load derives it from APP_NAME ("<app_name>_database_").
+	Prefix string `env:"REDIS_PREFIX"`
+
+	// QueueConnection and CacheConnection name the Laravel Redis connections
+	// used by the queue and the cache ("default" or "cache"), see Connection.
+	QueueConnection string `env:"REDIS_QUEUE_CONNECTION" envDefault:"default"`
+	CacheConnection string `env:"REDIS_CACHE_CONNECTION" envDefault:"cache"`
+
+	// QueueRetryAfter and QueueBlockFor mirror the "redis" connection in Laravel's config/queue.php
+	QueueRetryAfter int `env:"REDIS_QUEUE_RETRY_AFTER" envDefault:"90"`
+	QueueBlockFor   int `env:"REDIS_QUEUE_BLOCK_FOR" envDefault:"5"`
+}
+
+// Connection returns the settings of one of Laravel's named Redis connections.
+// The "cache" connection uses REDIS_CACHE_DB, any other connection REDIS_DB.
+func (c RedisConfig) Connection(name string) RedisConfig {
+	if name == "cache" {
+		c.DB = c.CacheDB
+	}
+	return c
+}
+
+// BeanstalkdConfig maps to BEANSTALKD_* variables, like the "beanstalkd"
+// connection in Laravel's config/queue.php
+type BeanstalkdConfig struct {
+	Host  string `env:"BEANSTALKD_QUEUE_HOST" envDefault:"localhost"`
+	Port  string `env:"BEANSTALKD_QUEUE_PORT" envDefault:"11300"`
+	Queue string `env:"BEANSTALKD_QUEUE" envDefault:"default"`
+
+	// RetryAfter is the time to run (TTR) jobs are pushed with: beanstalkd
+	// releases reserved jobs that are not deleted, released or touched in time
+	RetryAfter int `env:"BEANSTALKD_QUEUE_RETRY_AFTER" envDefault:"90"`
 }
 
 // QueueConfig maps to QUEUE_* variables
 type QueueConfig struct {
 	Connection string `env:"QUEUE_CONNECTION" envDefault:"sync"`
 	Queue      string `env:"QUEUE_QUEUE" envDefault:"default"`
+
+	// FailedDriver selects the failed job provider (queue.failed.driver):
+	// database-uuids, file, redis, dynamodb or null
+	FailedDriver string `env:"QUEUE_FAILED_DRIVER" envDefault:"database-uuids"`
+
+	// FailedTable is the table failed jobs are recorded in (queue.failed.table)
+	FailedTable string `env:"QUEUE_FAILED_TABLE" envDefault:"failed_jobs"`
+
+	// FailedPath is the JSON file used by the file driver
+	FailedPath string `env:"QUEUE_FAILED_PATH" envDefault:"storage/framework/cache/failed-jobs.json"`
+
+	// FailedRegion and FailedEndpoint configure the dynamodb driver. The
+	// endpoint may point at a local stand-in such as DynamoDB Local.
+	FailedRegion   string `env:"AWS_DEFAULT_REGION" envDefault:"us-east-1"`
+	FailedEndpoint string `env:"DYNAMODB_ENDPOINT"`
+
+	// BatchingDatabase is the connection job batches are stored on
+	// (queue.batching.database). When QUEUE_BATCHING_DATABASE is not set,
+	// Load uses DB_CONNECTION if that is set; empty disables job batches.
+	BatchingDatabase string `env:"QUEUE_BATCHING_DATABASE"`
+
+	// BatchingTable is the table job batches are stored in (queue.batching.table)
+	BatchingTable string `env:"QUEUE_BATCHING_TABLE" envDefault:"job_batches"`
 }
 
 // CacheConfig maps to CACHE_* variables
 type CacheConfig struct {
 	Store string `env:"CACHE_STORE" envDefault:"file"` // file, array, database, redis, memcached, dynamo
+
+	// Prefix is prepended to cache keys. When CACHE_PREFIX is not set, Load
+	// derives it from APP_NAME ("<app_name>_cache_").
+	Prefix string `env:"CACHE_PREFIX"`
 }
 
 // MailConfig maps to MAIL_* variables
 type MailConfig struct {
-	Mailer       string `env:"MAIL_MAILER" envDefault:"smtp"`
-	Host         string `env:"MAIL_HOST" envDefault:"mailhog"`
-	Port         string `env:"MAIL_PORT" envDefault:"1025"`
-	Username     string `env:"MAIL_USERNAME"`
-	Password     string `env:"MAIL_PASSWORD"`
-	Encryption   string `env:"MAIL_ENCRYPTION" envDefault:"tls"`
-	FromAddress  string `env:"MAIL_FROM_ADDRESS"`
-	FromName     string `env:"MAIL_FROM_NAME"`
+	Mailer      string `env:"MAIL_MAILER" envDefault:"smtp"`
+	Host        string `env:"MAIL_HOST" envDefault:"mailhog"`
+	Port        string `env:"MAIL_PORT" envDefault:"1025"`
+	Username    string `env:"MAIL_USERNAME"`
+	Password    string `env:"MAIL_PASSWORD"`
+	Encryption  string `env:"MAIL_ENCRYPTION" envDefault:"tls"`
+	FromAddress string `env:"MAIL_FROM_ADDRESS"`
+	FromName    string `env:"MAIL_FROM_NAME"`
 }
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"os"
+
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
 )
@@ -15,5 +17,21 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	// Laravel derives these prefixes from APP_NAME unless they are set explicitly.
+	// An explicitly empty value disables the prefix.
+	if _, ok := os.LookupEnv("REDIS_PREFIX"); !ok {
+		cfg.Redis.Prefix = slug(cfg.App.Name) + "_database_"
+	}
+	if _, ok := os.LookupEnv("CACHE_PREFIX"); !ok {
+		cfg.Cache.Prefix = slug(cfg.App.Name) + "_cache_"
+	}
+
+	// DB_CONNECTION has a default, so only an explicit one enables job batches
+	if _, ok := os.LookupEnv("QUEUE_BATCHING_DATABASE"); !ok {
+		if _, ok := os.LookupEnv("DB_CONNECTION"); ok {
+			cfg.Queue.BatchingDatabase = cfg.Database.Connection
+		}
+	}
+
 	return cfg, nil
 }
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_DerivesPrefixesFromAppName(t *testing.T) {
+	t.Setenv("APP_NAME", "My Shop")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, "my_shop_database_", cfg.Redis.Prefix)
+	assert.Equal(t, "my_shop_cache_", cfg.Cache.Prefix)
+}
+
+func TestLoad_ExplicitPrefixes(t *testing.T) {
+	t.Setenv("APP_NAME", "My Shop")
+	t.Setenv("REDIS_PREFIX", "")
+	t.Setenv("CACHE_PREFIX", "shop:")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, "", cfg.Redis.Prefix)
+	assert.Equal(t, "shop:", cfg.Cache.Prefix)
+}
+
+func TestLoad_BatchingDatabase(t *testing.T) {
+	unsetenv(t, "QUEUE_BATCHING_DATABASE")
+	unsetenv(t, "DB_CONNECTION")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "", cfg.Queue.BatchingDatabase)
+
+	t.Setenv("DB_CONNECTION", "pgsql")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "pgsql", cfg.Queue.BatchingDatabase)
+
+	t.Setenv("QUEUE_BATCHING_DATABASE", "mysql")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "mysql", cfg.Queue.BatchingDatabase)
+}
+
+// unsetenv unsets an environment variable for the duration of the test
+func unsetenv(t *testing.T, key string) {
+	t.Helper()
+	t.Setenv(key, "")
+	require.NoError(t, os.Unsetenv(key))
+}
+
+func TestSlug(t *testing.T) {
+	tests := map[string]string{
+		"Laravel":           "laravel",
+		"My Shop":           "my_shop",
+		"  my-shop  api ":   "my_shop_api",
+		"Shop.io":           "shopio",
+		"jobs@work":         "jobs_at_work",
+		"Already_snake__ok": "already_snake_ok",
+	}
+
+	for title, want := range tests {
+		assert.Equal(t, want, slug(title), title)
+	}
+}
+
+func TestRedisConfig_Connection(t *testing.T) {
+	cfg := RedisConfig{DB: 0, CacheDB: 1}
+
+	assert.Equal(t, 0, cfg.Connection("default").DB)
+	assert.Equal(t, 1, cfg.Connection("cache").DB)
+}
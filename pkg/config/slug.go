@@ -0,0 +1,33 @@
+package config
+
+import (
+	"strings"
+	"unicode"
+)
+
+// slug converts a title to a slug separated by underscores, following
+// Laravel's Str::slug($title, '_') which is used to derive key prefixes
+// from APP_NAME. Non-ASCII letters are kept rather than transliterated.
+func slug(title string) string {
+	// Convert dashes into the separator and "@" into "at"
+	title = strings.ReplaceAll(title, "-", "_")
+	title = strings.ReplaceAll(title, "@", "_at_")
+
+	var builder strings.Builder
+	pendingSeparator := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r == '_' || unicode.IsSpace(r):
+			// Collapse runs of separators and whitespace into a single separator
+			pendingSeparator = builder.Len() > 0
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if pendingSeparator {
+				builder.WriteByte('_')
+				pendingSeparator = false
+			}
+			builder.WriteRune(r)
+		}
+	}
+
+	return builder.String()
+}
@@ -2,15 +2,31 @@ package config
 
 import (
 	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 )
 
-// SQSConfig holds configuration for SQS connection
+// SQSConfig holds configuration for SQS connection, like the "sqs"
+// connection in Laravel's config/queue.php
 type SQSConfig struct {
-	Region    string
-	QueueUrl  string
-	Profile   string // Optional AWS profile
+	Region  string `env:"AWS_DEFAULT_REGION" envDefault:"us-east-1"`
+	Profile string // Optional AWS profile
+
+	// Deprecated: queue names are resolved with Prefix and Suffix, and full
+	// queue URLs may be used as queue names
+	QueueUrl string
+
+	// Prefix, Queue and Suffix build queue URLs like Laravel's SqsQueue:
+	// Prefix + "/" + name + Suffix. Without a prefix, names are looked up
+	// with GetQueueUrl.
+	Prefix string `env:"SQS_PREFIX"`
+	Queue  string `env:"SQS_QUEUE" envDefault:"default"`
+	Suffix string `env:"SQS_SUFFIX"`
+
+	// Endpoint points the client at an SQS compatible server such as ElasticMQ
+	Endpoint string `env:"SQS_ENDPOINT"`
 }
 
 // LoadSQSClient loads an SQS client from config
@@ -27,5 +43,9 @@ func LoadSQSClient(ctx context.Context, cfg SQSConfig) (*sqs.Client, error) {
 		return nil, err
 	}
 
-	return sqs.NewFromConfig(awsCfg), nil
+	return sqs.NewFromConfig(awsCfg, func(o *sqs.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	}), nil
 }
@@ -0,0 +1,243 @@
+package console
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/pixelvide/laravel-go/pkg/config"
+	"github.com/pixelvide/laravel-go/pkg/encryption"
+	"github.com/pixelvide/laravel-go/pkg/queue"
+	"github.com/pixelvide/laravel-go/pkg/root"
+	"github.com/spf13/cobra"
+
+	// Failed job providers selectable with QUEUE_FAILED_DRIVER
+	_ "github.com/pixelvide/laravel-go/pkg/driver/database"
+	_ "github.com/pixelvide/laravel-go/pkg/driver/dynamodb"
+	_ "github.com/pixelvide/laravel-go/pkg/driver/redis"
+)
+
+var (
+	failedQueue      string
+	failedConnection string
+	retryQueue       string
+	flushHours       int
+)
+
+var failedCmd = &cobra.Command{
+	Use:   "queue:failed",
+	Short: "List all of the failed queue jobs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider, err := failedJobProvider()
+		if err != nil {
+			return err
+		}
+
+		jobs, err := provider.All(cmd.Context(), queue.FailedJobFilter{Connection: failedConnection, Queue: failedQueue})
+		if err != nil {
+			return err
+		}
+
+		if len(jobs) == 0 {
+			cmd.Println("No failed jobs found.")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tUUID\tCONNECTION\tQUEUE\tCLASS\tFAILED AT")
+		for _, job := range jobs {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", job.ID, job.UUID, job.Connection, job.Queue, job.DisplayName(), job.FailedAt.Format("2006-01-02 15:04:05"))
+		}
+		return tw.Flush()
+	},
+}
+
+var retryCmd = &cobra.Command{
+	Use:   "queue:retry [id...|all]",
+	Short: "Retry failed queue jobs by id or uuid, \"all\" of them or those of a --queue",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && retryQueue == "" {
+			return fmt.Errorf("specify the ids of the jobs to retry, \"all\" or --queue")
+		}
+
+		provider, err := failedJobProvider()
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		jobs, err := jobsToRetry(ctx, provider, args)
+		if err != nil {
+			return err
+		}
+
+		resolver := newConnectionResolver()
+		encrypter := retryEncrypter(resolver.cfg)
+		for _, job := range jobs {
+			driver, err := resolver.driver(job.Connection)
+			if err != nil {
+				return fmt.Errorf("retrying failed job [%s]: %w", job.ID, err)
+			}
+
+			// Like RetryCommand::resetAttempts and refreshRetryUntil
+			payload, err := queue.WithAttempts(job.Payload, 0)
+			if err != nil {
+				return fmt.Errorf("retrying failed job [%s]: %w", job.ID, err)
+			}
+			if payload, err = queue.RefreshRetryUntil(payload, encrypter); err != nil {
+				return fmt.Errorf("retrying failed job [%s]: %w", job.ID, err)
+			}
+
+			if err := driver.Push(ctx, job.Queue, payload); err != nil {
+				return fmt.Errorf("retrying failed job [%s]: %w", job.ID, err)
+			}
+
+			if _, err := provider.Forget(ctx, job.ID); err != nil {
+				return err
+			}
+			cmd.Printf("The failed job [%s] has been pushed back onto the queue.\n", job.ID)
+		}
+		return nil
+	},
+}
+
+var forgetCmd = &cobra.Command{
+	Use:   "queue:forget id",
+	Short: "Delete a failed queue job",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider, err := failedJobProvider()
+		if err != nil {
+			return err
+		}
+
+		deleted, err := provider.Forget(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return fmt.Errorf("no failed job matches the given ID [%s]", args[0])
+		}
+
+		cmd.Println("Failed job deleted successfully.")
+		return nil
+	},
+}
+
+var flushCmd = &cobra.Command{
+	Use:   "queue:flush",
+	Short: "Flush all of the failed queue jobs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider, err := failedJobProvider()
+		if err != nil {
+			return err
+		}
+
+		if err := provider.Flush(cmd.Context(), flushHours); err != nil {
+			return err
+		}
+
+		if flushHours > 0 {
+			cmd.Printf("All jobs that failed more than %d hours ago have been deleted successfully.\n", flushHours)
+		} else {
+			cmd.Println("All failed jobs deleted successfully.")
+		}
+		return nil
+	},
+}
+
+// failedJobProvider returns the provider set with SetFailedJobProvider, or the
+// one selected by QUEUE_FAILED_DRIVER
+func failedJobProvider() (queue.FailedJobProvider, error) {
+	if globalFailedProvider != nil {
+		return globalFailedProvider, nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading configuration: %w", err)
+	}
+	return queue.NewFailedJobProvider(cfg)
+}
+
+// jobsToRetry resolves the command arguments ("all" or ids) and --queue to failed jobs
+func jobsToRetry(ctx context.Context, provider queue.FailedJobProvider, args []string) ([]queue.FailedJob, error) {
+	if retryQueue != "" || (len(args) == 1 && args[0] == "all") {
+		return provider.All(ctx, queue.FailedJobFilter{Queue: retryQueue})
+	}
+
+	jobs := make([]queue.FailedJob, 0, len(args))
+	for _, id := range args {
+		job, err := provider.Find(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job == nil {
+			return nil, fmt.Errorf("unable to find failed job with ID [%s]", id)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+// retryEncrypter returns the APP_KEY encrypter used to read the commands of
+// ShouldBeEncrypted jobs, or nil without a key
+func retryEncrypter(cfg *config.Config) queue.Encrypter {
+	if cfg == nil || cfg.App.Key == "" {
+		return nil
+	}
+	encrypter, err := encryption.NewFromConfig(cfg.App)
+	if err != nil {
+		return nil
+	}
+	return encrypter
+}
+
+// connectionResolver builds queue drivers for the connections failed jobs were recorded on
+type connectionResolver struct {
+	cfg     *config.Config
+	drivers map[string]queue.Driver
+	mu      sync.Mutex
+}
+
+func newConnectionResolver() *connectionResolver {
+	cfg, _ := config.Load()
+	return &connectionResolver{cfg: cfg, drivers: make(map[string]queue.Driver)}
+}
+
+func (r *connectionResolver) driver(connection string) (queue.Driver, error) {
+	// A driver set with SetDriver serves the default connection
+	if globalDriver != nil && (r.cfg == nil || connection == r.cfg.Queue.Connection) {
+		return globalDriver, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if driver, ok := r.drivers[connection]; ok {
+		return driver, nil
+	}
+	if r.cfg == nil {
+		return nil, fmt.Errorf("no configuration to connect to queue connection %q", connection)
+	}
+
+	cfg := *r.cfg
+	cfg.Queue.Connection = connection
+	driver, err := configureDriver(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	r.drivers[connection] = driver
+	return driver, nil
+}
+
+func init() {
+	failedCmd.Flags().StringVar(&failedQueue, "queue", "", "Only list jobs that failed on this queue")
+	failedCmd.Flags().StringVar(&failedConnection, "connection", "", "Only list jobs that failed on this connection")
+	retryCmd.Flags().StringVar(&retryQueue, "queue", "", "Retry all of the failed jobs of this queue")
+	flushCmd.Flags().IntVar(&flushHours, "hours", 0, "Only flush jobs that failed more than this many hours ago")
+
+	root.GetRoot().AddCommand(failedCmd, retryCmd, forgetCmd, flushCmd)
+}
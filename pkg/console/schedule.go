@@ -37,14 +37,10 @@ var scheduleCmd = &cobra.Command{
 		switch store {
 		case "redis":
 			if cfg != nil {
-				rCfg := config.RedisConfig{
-					Host:     cfg.Redis.Host,
-					Port:     cfg.Redis.Port,
-					Password: cfg.Redis.Password,
-					DB:       cfg.Redis.DB,
-				}
+				// Locks live in the cache store, like Laravel's scheduler mutexes
+				rCfg := cfg.Redis.Connection(cfg.Redis.CacheConnection)
 				rdb := redis.NewRedisDriver(rCfg).Client
-				lockProvider = schedule.NewRedisLockProvider(rdb)
+				lockProvider = schedule.NewPrefixedRedisLockProvider(rdb, cfg.Redis.Prefix+cfg.Cache.Prefix)
 			}
 		case "database":
 			if cfg != nil {
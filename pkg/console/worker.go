@@ -3,17 +3,23 @@ package console
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	awsconfig "github.com/aws/aws-sdk-go-v2/config"
-	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/pixelvide/laravel-go/pkg/batch"
+	"github.com/pixelvide/laravel-go/pkg/cache"
 	"github.com/pixelvide/laravel-go/pkg/config"
 	"github.com/pixelvide/laravel-go/pkg/database"
+	"github.com/pixelvide/laravel-go/pkg/driver/beanstalkd"
 	driverdatabase "github.com/pixelvide/laravel-go/pkg/driver/database"
+	"github.com/pixelvide/laravel-go/pkg/driver/memory"
 	"github.com/pixelvide/laravel-go/pkg/driver/redis"
 	driversqs "github.com/pixelvide/laravel-go/pkg/driver/sqs"
+	"github.com/pixelvide/laravel-go/pkg/driver/syncdriver"
+	"github.com/pixelvide/laravel-go/pkg/encryption"
 	"github.com/pixelvide/laravel-go/pkg/queue"
 	"github.com/pixelvide/laravel-go/pkg/root"
 	"github.com/pixelvide/laravel-go/pkg/telemetry"
@@ -25,6 +31,9 @@ import (
 var (
 	queueName   string
 	concurrency int
+	backoff     string
+	unhandled   string
+	heartbeat   int
 )
 
 var (
@@ -37,6 +46,24 @@ func SetDriver(driver queue.Driver) {
 	globalDriver = driver
 }
 
+// Driver returns the queue driver of the worker command: the one set with
+// SetDriver, or else the one configured by QUEUE_CONNECTION. With the memory
+// connection, dispatch onto it to run a publisher and workers in one process.
+func Driver() (queue.Driver, error) {
+	if globalDriver == nil {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, err
+		}
+		d, err := configureDriver(cfg)
+		if err != nil {
+			return nil, err
+		}
+		globalDriver = d
+	}
+	return globalDriver, nil
+}
+
 // SetFailedJobProvider sets the failed job provider for the worker command
 func SetFailedJobProvider(provider queue.FailedJobProvider) {
 	globalFailedProvider = provider
@@ -65,6 +92,15 @@ var workerCmd = &cobra.Command{
 				}
 				globalDriver = d
 			}
+			// Auto-configure the failed job provider (QUEUE_FAILED_DRIVER)
+			if globalFailedProvider == nil {
+				provider, err := queue.NewFailedJobProvider(cfg)
+				if err != nil {
+					log.Warn().Err(err).Str("driver", cfg.Queue.FailedDriver).Msg("Failed to configure failed job provider")
+				} else {
+					globalFailedProvider = provider
+				}
+			}
 		}
 
 		tp, err := telemetry.InitTracer("laravel-go-worker")
@@ -85,6 +121,57 @@ var workerCmd = &cobra.Command{
 
 		// Initialize Worker
 		w := worker.NewWorker(globalDriver, globalFailedProvider, queueName, concurrency, appName, tracer)
+		if cfg != nil {
+			w.Connection = cfg.Queue.Connection
+			w.Connections = newConnectionResolver().driver
+
+			// Decrypt the commands of ShouldBeEncrypted jobs with APP_KEY
+			if cfg.App.Key != "" {
+				encrypter, err := encryption.NewFromConfig(cfg.App)
+				if err != nil {
+					log.Warn().Err(err).Msg("Invalid APP_KEY, encrypted jobs cannot be decrypted")
+				} else {
+					w.Encrypter = encrypter
+				}
+			}
+
+			// Share maxExceptions counts and unique job locks with PHP through the cache store
+			if cfg.Cache.Store == "redis" {
+				client := redis.NewRedisDriver(cfg.Redis.Connection(cfg.Redis.CacheConnection)).Client
+				store := cache.NewPrefixedRedisStore(client, cfg.Redis.Prefix+cfg.Cache.Prefix)
+				w.Cache = store
+				w.Locks = store
+			}
+
+			// Keep job_batches up to date for jobs dispatched with Bus::batch()
+			repository, closeDB, err := batchRepository(cfg, globalDriver)
+			if err != nil {
+				log.Warn().Err(err).Msg("Failed to connect to the batch database, job batches will not be updated")
+			} else if repository != nil {
+				defer closeDB()
+				w.Batches = batch.NewRecorder(repository)
+			}
+		}
+
+		defaultBackoff, err := queue.ParseBackoff(backoff)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid --backoff value")
+		}
+		w.Backoff = defaultBackoff
+		w.Heartbeat = time.Duration(heartbeat) * time.Second
+
+		// Jobs pushed onto the sync connection, e.g. the next job of a chain,
+		// run right away through this worker. Its Pop blocks until shutdown.
+		if d, ok := globalDriver.(*syncdriver.SyncDriver); ok {
+			d.Worker = w
+		}
+
+		switch action := worker.UnhandledJobAction(unhandled); action {
+		case worker.FailUnhandled, worker.ReleaseUnhandled:
+			w.UnhandledJobs = action
+		default:
+			log.Fatal().Str("unhandled", unhandled).Msg("Invalid --unhandled value, expected \"fail\" or \"release\"")
+		}
 
 		// Run Worker with Graceful Shutdown
 		ctx, cancel := context.WithCancel(context.Background())
@@ -102,20 +189,42 @@ var workerCmd = &cobra.Command{
 		log.Info().Str("queue", queueName).Int("workers", concurrency).Msg("Starting worker pool...")
 
 		w.Run(ctx)
+
+		// Give back what the driver holds, e.g. messages SQS received ahead
+		if closer, ok := globalDriver.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.Warn().Err(err).Msg("Failed to close queue driver")
+			}
+		}
 		log.Info().Msg("Worker pool stopped.")
 	},
 }
 
+// batchRepository returns the job_batches repository on the
+// QUEUE_BATCHING_DATABASE connection, or nil when no batching database is
+// configured. The database driver's connection is reused when it is the same.
+func batchRepository(cfg *config.Config, driver queue.Driver) (*driverdatabase.DatabaseBatchRepository, func(), error) {
+	connection := cfg.Queue.BatchingDatabase
+	if d, ok := driver.(*driverdatabase.DatabaseDriver); ok && (connection == "" || connection == cfg.Database.Connection) {
+		return driverdatabase.NewDatabaseBatchRepository(d.DB(), cfg.Database.Connection, cfg.Queue.BatchingTable), func() {}, nil
+	}
+	if connection == "" {
+		return nil, nil, nil
+	}
+
+	dbConfig := cfg.Database
+	dbConfig.Connection = connection
+	db, err := database.NewFactory().Connect(dbConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return driverdatabase.NewDatabaseBatchRepository(db, connection, cfg.Queue.BatchingTable), func() { _ = db.Close() }, nil
+}
+
 func configureDriver(cfg *config.Config) (queue.Driver, error) {
 	switch cfg.Queue.Connection {
 	case "redis":
-		rCfg := config.RedisConfig{
-			Host:     cfg.Redis.Host,
-			Port:     cfg.Redis.Port,
-			Password: cfg.Redis.Password,
-			DB:       cfg.Redis.DB,
-		}
-		return redis.NewRedisDriver(rCfg), nil
+		return redis.NewRedisDriver(cfg.Redis.Connection(cfg.Redis.QueueConnection)), nil
 
 	case "database":
 		// Create DB Connection
@@ -127,25 +236,21 @@ func configureDriver(cfg *config.Config) (queue.Driver, error) {
 		return driverdatabase.NewDatabaseDriver(cfg.Database, db), nil
 
 	case "sqs":
-		ctx := context.Background()
-		awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+		client, err := config.LoadSQSClient(context.Background(), cfg.SQS)
 		if err != nil {
 			return nil, err
 		}
-		client := awssqs.NewFromConfig(awsCfg)
-		// We use the configured queue URL or name
-		// For now, we assume QUEUE_QUEUE in env is the URL or we resolve it
-		// This driver expects a URL.
-		queueUrl := cfg.Queue.Queue
-		if queueUrl == "default" {
-			// Try to find URL for default queue?
-			// Simplification: Assume user puts URL in QUEUE_QUEUE for SQS
-			log.Warn().Msg("Using 'default' as SQS queue URL. Ensure QUEUE_QUEUE is set to a valid URL in .env")
-		}
-		return driversqs.NewSQSDriver(client, queueUrl), nil
+		return driversqs.NewSQSDriverFromConfig(client, cfg.SQS), nil
+
+	case "beanstalkd":
+		return beanstalkd.NewBeanstalkdDriver(cfg.Beanstalkd), nil
 
 	case "sync":
-		return nil, fmt.Errorf("sync driver not yet implemented")
+		// Jobs run as they are dispatched, e.g. the next job of a chain
+		return syncdriver.NewSyncDriver(), nil
+
+	case "memory":
+		return memory.NewMemoryDriver(), nil
 
 	default:
 		return nil, fmt.Errorf("unsupported queue connection: %s", cfg.Queue.Connection)
@@ -153,8 +258,11 @@ func configureDriver(cfg *config.Config) (queue.Driver, error) {
 }
 
 func init() {
-	workerCmd.Flags().StringVar(&queueName, "queue", "default", "Name of the queue to process")
+	workerCmd.Flags().StringVar(&queueName, "queue", "default", "Names of the queues to process, in priority order (e.g. \"high,default,low\")")
 	workerCmd.Flags().IntVar(&concurrency, "workers", 5, "Number of concurrent workers")
+	workerCmd.Flags().StringVar(&unhandled, "unhandled", "fail", "What to do with jobs that have no handler or cannot be decoded: \"fail\" or \"release\"")
+	workerCmd.Flags().StringVar(&backoff, "backoff", "0", "Seconds to wait before retrying a job that failed, e.g. \"1,5,30\"")
+	workerCmd.Flags().IntVar(&heartbeat, "heartbeat", 0, "Seconds between extensions of the lease of running jobs, keeping jobs that outlast retry_after or the SQS visibility timeout from being redelivered (0 disables)")
 
 	root.GetRoot().AddCommand(workerCmd)
 }
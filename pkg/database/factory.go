@@ -24,7 +24,7 @@ func (f *Factory) Connect(cfg config.DatabaseConfig) (*sql.DB, error) {
 	var driverName string
 
 	switch cfg.Connection {
-	case "mysql":
+	case "mysql", "mariadb":
 		driverName = "mysql"
 		dsn = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&loc=Local",
 			cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.Database)
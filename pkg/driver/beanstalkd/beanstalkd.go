@@ -0,0 +1,293 @@
+package beanstalkd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pixelvide/laravel-go/pkg/config"
+	"github.com/pixelvide/laravel-go/pkg/queue"
+)
+
+// defaultPriority is the priority Laravel puts and releases jobs with (Pheanstalk::DEFAULT_PRIORITY)
+const defaultPriority = 1024
+
+// ttrMargin is added to the timeout of jobs that may run longer than retry_after
+const ttrMargin = 5 * time.Second
+
+// BeanstalkdDriver implements queue.Driver on beanstalkd tubes like Laravel's
+// BeanstalkdQueue: each queue is a tube, popped jobs are reserved, and
+// acknowledged jobs are deleted. Beanstalkd releases reserved jobs once their
+// time to run (TTR) has passed, or when the connection that reserved them closes.
+//
+// Beanstalkd only lets the connection that reserved a job delete, release,
+// bury or touch it, so each popped job keeps its connection until it is
+// acknowledged or released.
+type BeanstalkdDriver struct {
+	addr       string
+	tube       string
+	retryAfter time.Duration
+	blockFor   time.Duration
+
+	mu       sync.Mutex
+	idle     []*conn
+	reserved map[string]*conn
+}
+
+// NewBeanstalkdDriver creates a driver for the beanstalkd server of cfg.
+// Connections are opened as they are needed.
+func NewBeanstalkdDriver(cfg config.BeanstalkdConfig) *BeanstalkdDriver {
+	tube := cfg.Queue
+	if tube == "" {
+		tube = "default"
+	}
+
+	retryAfter := cfg.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = 90 // Laravel's default retry_after
+	}
+
+	port := cfg.Port
+	if port == "" {
+		port = "11300"
+	}
+
+	return &BeanstalkdDriver{
+		addr:       net.JoinHostPort(cfg.Host, port),
+		tube:       tube,
+		retryAfter: time.Duration(retryAfter) * time.Second,
+		blockFor:   time.Second,
+		reserved:   make(map[string]*conn),
+	}
+}
+
+// Pop reserves the next job of the queue's tube. It waits until a job is
+// available, reserving for a second at a time so ctx is honoured.
+// The job's attempts are the number of times beanstalkd reserved it.
+func (b *BeanstalkdDriver) Pop(ctx context.Context, queueName string) (*queue.Job, error) {
+	c, err := b.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.watchOnly(b.tubeFor(queueName)); err != nil {
+		b.discard(c)
+		return nil, err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			b.putBack(c)
+			return nil, err
+		}
+
+		id, body, err := c.reserve(b.blockFor)
+		if err != nil {
+			b.discard(c)
+			return nil, err
+		}
+		if id == "" {
+			continue
+		}
+
+		// BeanstalkdJob::attempts counts the reserves of the job
+		attempts, err := c.reserves(id)
+		if err != nil {
+			b.discard(c)
+			return nil, err
+		}
+
+		b.mu.Lock()
+		b.reserved[id] = c
+		b.mu.Unlock()
+
+		return &queue.Job{ID: id, Queue: queueName, Body: body, Attempts: attempts}, nil
+	}
+}
+
+// Push puts a job onto the queue's tube
+func (b *BeanstalkdDriver) Push(ctx context.Context, queueName string, body []byte) error {
+	return b.Later(ctx, queueName, body, 0)
+}
+
+// Later puts a job onto the queue's tube that is ready once delay has elapsed.
+// Its TTR is retry_after, or a few seconds more than the job's timeout when
+// that is longer, so the job is not released while the worker may run it.
+func (b *BeanstalkdDriver) Later(ctx context.Context, queueName string, body []byte, delay time.Duration) error {
+	c, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.use(b.tubeFor(queueName)); err != nil {
+		b.discard(c)
+		return err
+	}
+	if _, err := c.put(body, defaultPriority, delay, b.timeToRun(body)); err != nil {
+		b.discardOnNetError(c, err)
+		return err
+	}
+	b.putBack(c)
+	return nil
+}
+
+// Ack deletes the job (BeanstalkdJob::delete)
+func (b *BeanstalkdDriver) Ack(ctx context.Context, job *queue.Job) error {
+	return b.finish(ctx, job, "DELETED", "delete %s", job.ID)
+}
+
+// Release puts the reserved job back into its tube, ready after delay, like
+// BeanstalkdJob::release. Beanstalkd keeps the original body, so changes to
+// job.Body are not persisted.
+func (b *BeanstalkdDriver) Release(ctx context.Context, job *queue.Job, delay time.Duration) error {
+	return b.finish(ctx, job, "RELEASED", "release %s %d %d", job.ID, defaultPriority, seconds(delay))
+}
+
+// Bury moves the reserved job to its tube's buried list, where it stays until
+// kicked, like BeanstalkdJob::bury. Failed jobs are deleted as in Laravel;
+// bury keeps a job for inspection with beanstalkd tools instead.
+func (b *BeanstalkdDriver) Bury(ctx context.Context, job *queue.Job) error {
+	return b.finish(ctx, job, "BURIED", "bury %s %d", job.ID, defaultPriority)
+}
+
+// ExtendLease touches the reserved job, which gives it its full TTR again.
+// Beanstalkd does not take a duration, so d only decides how often the
+// worker should touch the job.
+func (b *BeanstalkdDriver) ExtendLease(ctx context.Context, job *queue.Job, d time.Duration) error {
+	b.mu.Lock()
+	c, ok := b.reserved[job.ID]
+	b.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("beanstalkd: job %s is not reserved by this worker", job.ID)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := c.expect("TOUCHED", 0, nil, "touch %s", job.ID)
+	if b.discardOnNetError(c, err) {
+		// Closing the connection released the job
+		b.mu.Lock()
+		delete(b.reserved, job.ID)
+		b.mu.Unlock()
+	}
+	return err
+}
+
+// Close closes the idle connections. Jobs still reserved keep their
+// connection until they are acknowledged or released.
+func (b *BeanstalkdDriver) Close() error {
+	b.mu.Lock()
+	idle := b.idle
+	b.idle = nil
+	b.mu.Unlock()
+
+	var errs []error
+	for _, c := range idle {
+		errs = append(errs, c.close())
+	}
+	return errors.Join(errs...)
+}
+
+// finish runs a command that ends the reservation of a job on the connection
+// that reserved it. Jobs that are no longer reserved, e.g. whose TTR has
+// passed, are deleted or buried through any connection.
+func (b *BeanstalkdDriver) finish(ctx context.Context, job *queue.Job, status string, format string, args ...any) error {
+	b.mu.Lock()
+	c, ok := b.reserved[job.ID]
+	delete(b.reserved, job.ID)
+	b.mu.Unlock()
+
+	if !ok {
+		var err error
+		if c, err = b.acquire(ctx); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := c.expect(status, 0, nil, format, args...)
+	if errors.Is(err, errNotFound) {
+		err = fmt.Errorf("beanstalkd: job %s not found or not reserved by this worker: %w", job.ID, err)
+	}
+	if !b.discardOnNetError(c, err) {
+		b.putBack(c)
+	}
+	return err
+}
+
+// timeToRun returns the TTR of a job payload
+func (b *BeanstalkdDriver) timeToRun(body []byte) time.Duration {
+	var payload queue.LaravelJob
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Timeout == nil {
+		return b.retryAfter
+	}
+	return max(b.retryAfter, time.Duration(*payload.Timeout)*time.Second+ttrMargin)
+}
+
+// tubeFor returns the tube of a queue, the configured queue when empty
+func (b *BeanstalkdDriver) tubeFor(queueName string) string {
+	if queueName == "" {
+		return b.tube
+	}
+	return queueName
+}
+
+// acquire returns an idle connection, or dials a new one
+func (b *BeanstalkdDriver) acquire(ctx context.Context) (*conn, error) {
+	b.mu.Lock()
+	if n := len(b.idle); n > 0 {
+		c := b.idle[n-1]
+		b.idle = b.idle[:n-1]
+		b.mu.Unlock()
+		return c, nil
+	}
+	b.mu.Unlock()
+
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", b.addr)
+	if err != nil {
+		return nil, err
+	}
+	return newConn(netConn), nil
+}
+
+// putBack makes a connection available to other commands
+func (b *BeanstalkdDriver) putBack(c *conn) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.idle = append(b.idle, c)
+}
+
+// discard closes a connection whose state is unknown after an error
+func (b *BeanstalkdDriver) discard(c *conn) {
+	_ = c.close()
+}
+
+// discardOnNetError discards the connection unless err is nil or a response
+// of the server, reporting whether it did
+func (b *BeanstalkdDriver) discardOnNetError(c *conn, err error) bool {
+	if err == nil || isServerError(err) {
+		return false
+	}
+	b.discard(c)
+	return true
+}
+
+// isServerError reports whether err is a response of the server
+func isServerError(err error) bool {
+	var response *responseError
+	return errors.As(err, &response)
+}
@@ -0,0 +1,354 @@
+package beanstalkd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pixelvide/laravel-go/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJob is a job held by fakeBeanstalkd
+type fakeJob struct {
+	id       int
+	tube     string
+	body     []byte
+	ttr      time.Duration
+	state    string // ready, delayed, reserved or buried
+	readyAt  time.Time
+	deadline time.Time
+	owner    *fakeClient
+	reserves int
+}
+
+// fakeBeanstalkd implements the part of the beanstalkd protocol the driver uses
+type fakeBeanstalkd struct {
+	mu       sync.Mutex
+	listener net.Listener
+	jobs     map[int]*fakeJob
+	nextID   int
+}
+
+// fakeClient is the state of one connection to fakeBeanstalkd
+type fakeClient struct {
+	using    string
+	watching map[string]bool
+}
+
+func newFakeBeanstalkd(t *testing.T) *fakeBeanstalkd {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	f := &fakeBeanstalkd{listener: listener, jobs: make(map[int]*fakeJob)}
+	go func() {
+		for {
+			netConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(netConn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeBeanstalkd) config() config.BeanstalkdConfig {
+	host, port, _ := net.SplitHostPort(f.listener.Addr().String())
+	return config.BeanstalkdConfig{Host: host, Port: port, Queue: "default", RetryAfter: 90}
+}
+
+func (f *fakeBeanstalkd) serve(netConn net.Conn) {
+	client := &fakeClient{using: "default", watching: map[string]bool{"default": true}}
+	reader := bufio.NewReader(netConn)
+	defer func() {
+		_ = netConn.Close()
+
+		// Jobs reserved by a closed connection are released
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for _, job := range f.jobs {
+			if job.owner == client {
+				job.state, job.owner = "ready", nil
+			}
+		}
+	}()
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			return
+		}
+
+		var body []byte
+		if words[0] == "put" {
+			size, _ := strconv.Atoi(words[4])
+			body = make([]byte, size+2)
+			if _, err := io.ReadFull(reader, body); err != nil {
+				return
+			}
+			body = body[:size]
+		}
+
+		if _, err := netConn.Write([]byte(f.handle(client, words, body) + "\r\n")); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeBeanstalkd) handle(client *fakeClient, words []string, body []byte) string {
+	if words[0] == "reserve-with-timeout" {
+		timeout, _ := strconv.Atoi(words[1])
+		deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+		for {
+			if response, ok := f.reserve(client); ok {
+				return response
+			}
+			if time.Now().After(deadline) {
+				return "TIMED_OUT"
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	arg := func(i int) int {
+		n, _ := strconv.Atoi(words[i])
+		return n
+	}
+	// reservedJob returns the job reserved by this client
+	reservedJob := func() *fakeJob {
+		if job := f.jobs[arg(1)]; job != nil && job.state == "reserved" && job.owner == client {
+			return job
+		}
+		return nil
+	}
+
+	switch words[0] {
+	case "use":
+		client.using = words[1]
+		return "USING " + words[1]
+	case "watch":
+		client.watching[words[1]] = true
+		return fmt.Sprintf("WATCHING %d", len(client.watching))
+	case "ignore":
+		delete(client.watching, words[1])
+		return fmt.Sprintf("WATCHING %d", len(client.watching))
+	case "put":
+		f.nextID++
+		f.jobs[f.nextID] = &fakeJob{
+			id:      f.nextID,
+			tube:    client.using,
+			body:    body,
+			ttr:     time.Duration(arg(3)) * time.Second,
+			state:   "delayed",
+			readyAt: time.Now().Add(time.Duration(arg(2)) * time.Second),
+		}
+		return fmt.Sprintf("INSERTED %d", f.nextID)
+	case "stats-job":
+		job := f.jobs[arg(1)]
+		if job == nil {
+			return "NOT_FOUND"
+		}
+		stats := fmt.Sprintf("---\nid: %d\ntube: %s\nstate: %s\nreserves: %d\n", job.id, job.tube, job.state, job.reserves)
+		return fmt.Sprintf("OK %d\r\n%s", len(stats), stats)
+	case "delete":
+		job := f.jobs[arg(1)]
+		if job == nil || (job.state == "reserved" && job.owner != client) {
+			return "NOT_FOUND"
+		}
+		delete(f.jobs, job.id)
+		return "DELETED"
+	case "release":
+		job := reservedJob()
+		if job == nil {
+			return "NOT_FOUND"
+		}
+		job.state, job.owner = "delayed", nil
+		job.readyAt = time.Now().Add(time.Duration(arg(3)) * time.Second)
+		return "RELEASED"
+	case "bury":
+		job := reservedJob()
+		if job == nil {
+			return "NOT_FOUND"
+		}
+		job.state, job.owner = "buried", nil
+		return "BURIED"
+	case "touch":
+		job := reservedJob()
+		if job == nil {
+			return "NOT_FOUND"
+		}
+		job.deadline = time.Now().Add(job.ttr)
+		return "TOUCHED"
+	}
+	return "UNKNOWN_COMMAND"
+}
+
+// reserve reserves the oldest ready job of the client's watched tubes
+func (f *fakeBeanstalkd) reserve(client *fakeClient) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var next *fakeJob
+	for _, job := range f.jobs {
+		ready := job.state == "ready" ||
+			(job.state == "delayed" && !job.readyAt.After(time.Now())) ||
+			(job.state == "reserved" && job.deadline.Before(time.Now()))
+		if ready && client.watching[job.tube] && (next == nil || job.id < next.id) {
+			next = job
+		}
+	}
+	if next == nil {
+		return "", false
+	}
+
+	next.state, next.owner = "reserved", client
+	next.deadline = time.Now().Add(next.ttr)
+	next.reserves++
+	return fmt.Sprintf("RESERVED %d %d\r\n%s", next.id, len(next.body), next.body), true
+}
+
+func (f *fakeBeanstalkd) job(id string) *fakeJob {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n, _ := strconv.Atoi(id)
+	if job := f.jobs[n]; job != nil {
+		copied := *job
+		return &copied
+	}
+	return nil
+}
+
+func newTestDriver(t *testing.T) (*BeanstalkdDriver, *fakeBeanstalkd) {
+	t.Helper()
+
+	fake := newFakeBeanstalkd(t)
+	driver := NewBeanstalkdDriver(fake.config())
+	t.Cleanup(func() { _ = driver.Close() })
+	return driver, fake
+}
+
+func TestBeanstalkdDriver_ReservesAndDeletesJobs(t *testing.T) {
+	driver, fake := newTestDriver(t)
+	ctx := context.Background()
+
+	require.NoError(t, driver.Push(ctx, "emails", []byte(`{"uuid":"a"}`)))
+
+	// Each queue is a tube
+	popCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err := driver.Pop(popCtx, "default")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	job, err := driver.Pop(ctx, "emails")
+	require.NoError(t, err)
+	assert.Equal(t, `{"uuid":"a"}`, string(job.Body))
+	assert.Equal(t, "emails", job.Queue)
+	assert.Equal(t, 1, job.Attempts)
+
+	stored := fake.job(job.ID)
+	require.NotNil(t, stored)
+	assert.Equal(t, "emails", stored.tube)
+	assert.Equal(t, "reserved", stored.state)
+
+	require.NoError(t, driver.Ack(ctx, job))
+	assert.Nil(t, fake.job(job.ID))
+}
+
+func TestBeanstalkdDriver_ReleaseAndBury(t *testing.T) {
+	driver, fake := newTestDriver(t)
+	ctx := context.Background()
+
+	require.NoError(t, driver.Push(ctx, "default", []byte(`{"uuid":"a"}`)))
+
+	job, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+	require.NoError(t, driver.Release(ctx, job, 0))
+
+	// The attempts count the reserves of the job
+	job, err = driver.Pop(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, 2, job.Attempts)
+
+	require.NoError(t, driver.Release(ctx, job, time.Minute))
+	released := fake.job(job.ID)
+	assert.Equal(t, "delayed", released.state)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), released.readyAt, 2*time.Second)
+
+	require.NoError(t, driver.Later(ctx, "default", []byte(`{"uuid":"b"}`), 0))
+	job, err = driver.Pop(ctx, "default")
+	require.NoError(t, err)
+	require.NoError(t, driver.Bury(ctx, job))
+	assert.Equal(t, "buried", fake.job(job.ID).state)
+
+	// Buried jobs are no longer reserved by this worker
+	assert.ErrorContains(t, driver.Release(ctx, job, 0), "not found")
+}
+
+func TestBeanstalkdDriver_TimeToRun(t *testing.T) {
+	driver, fake := newTestDriver(t)
+	ctx := context.Background()
+
+	require.NoError(t, driver.Push(ctx, "default", []byte(`{"uuid":"a"}`)))
+	require.NoError(t, driver.Push(ctx, "default", []byte(`{"uuid":"b","timeout":30}`)))
+	require.NoError(t, driver.Push(ctx, "default", []byte(`{"uuid":"c","timeout":300}`)))
+
+	// Jobs may run for retry_after, or their timeout when it is longer
+	assert.Equal(t, 90*time.Second, fake.job("1").ttr)
+	assert.Equal(t, 90*time.Second, fake.job("2").ttr)
+	assert.Equal(t, 305*time.Second, fake.job("3").ttr)
+
+	job, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+
+	reservedUntil := fake.job(job.ID).deadline
+	time.Sleep(1100 * time.Millisecond)
+	require.NoError(t, driver.ExtendLease(ctx, job, time.Minute))
+	assert.True(t, fake.job(job.ID).deadline.After(reservedUntil))
+}
+
+func TestBeanstalkdDriver_ConcurrentReservations(t *testing.T) {
+	driver, fake := newTestDriver(t)
+	ctx := context.Background()
+
+	for i := range 5 {
+		require.NoError(t, driver.Push(ctx, "default", []byte(fmt.Sprintf(`{"uuid":"%d"}`, i))))
+	}
+
+	// Each reserved job keeps its own connection until it is acknowledged
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job, err := driver.Pop(ctx, "default")
+			if assert.NoError(t, err) {
+				assert.NoError(t, driver.Ack(ctx, job))
+			}
+		}()
+	}
+	wg.Wait()
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	assert.Empty(t, fake.jobs)
+}
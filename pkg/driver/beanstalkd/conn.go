@@ -0,0 +1,202 @@
+package beanstalkd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ioTimeout bounds each command, on top of the time the server may block
+const ioTimeout = 10 * time.Second
+
+// errNotFound is returned by commands on jobs that do not exist, or are not
+// reserved by the connection
+var errNotFound = &responseError{response: "NOT_FOUND"}
+
+// responseError is an error response of the server. Unlike network errors,
+// it leaves the connection usable.
+type responseError struct {
+	response string
+}
+
+func (e *responseError) Error() string {
+	return "beanstalkd: " + e.response
+}
+
+// conn is a connection speaking the beanstalkd protocol. The tubes it uses
+// and watches are tracked to skip redundant use and watch commands.
+type conn struct {
+	mu       sync.Mutex
+	netConn  net.Conn
+	reader   *bufio.Reader
+	using    string
+	watching string
+}
+
+func newConn(netConn net.Conn) *conn {
+	return &conn{
+		netConn:  netConn,
+		reader:   bufio.NewReader(netConn),
+		using:    "default",
+		watching: "default",
+	}
+}
+
+// command sends a command line, with body as its data if not nil, and
+// returns the words of the response line. block is how long the server may
+// take to reply, e.g. the timeout of reserve-with-timeout.
+func (c *conn) command(block time.Duration, body []byte, format string, args ...any) ([]string, error) {
+	if err := c.netConn.SetDeadline(time.Now().Add(block + ioTimeout)); err != nil {
+		return nil, err
+	}
+
+	var request bytes.Buffer
+	fmt.Fprintf(&request, format+"\r\n", args...)
+	if body != nil {
+		request.Write(body)
+		request.WriteString("\r\n")
+	}
+	if _, err := c.netConn.Write(request.Bytes()); err != nil {
+		return nil, err
+	}
+
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return nil, errors.New("beanstalkd: empty response")
+	}
+
+	switch words[0] {
+	case "OUT_OF_MEMORY", "INTERNAL_ERROR", "BAD_FORMAT", "UNKNOWN_COMMAND", "EXPECTED_CRLF", "JOB_TOO_BIG", "DRAINING":
+		return nil, &responseError{response: words[0]}
+	case "NOT_FOUND":
+		return nil, errNotFound
+	}
+	return words, nil
+}
+
+// data reads the body following a response such as RESERVED or OK
+func (c *conn) data(size string) ([]byte, error) {
+	n, err := strconv.Atoi(size)
+	if err != nil {
+		return nil, fmt.Errorf("beanstalkd: invalid data size %q", size)
+	}
+	body := make([]byte, n+2)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		return nil, err
+	}
+	return body[:n], nil
+}
+
+// use selects the tube put adds jobs to
+func (c *conn) use(tube string) error {
+	if c.using == tube {
+		return nil
+	}
+	if _, err := c.expect("USING", 0, nil, "use %s", tube); err != nil {
+		return err
+	}
+	c.using = tube
+	return nil
+}
+
+// watchOnly makes reserve take jobs from tube alone, like Pheanstalk's watchOnly
+func (c *conn) watchOnly(tube string) error {
+	if c.watching == tube {
+		return nil
+	}
+	if _, err := c.expect("WATCHING", 0, nil, "watch %s", tube); err != nil {
+		return err
+	}
+	if _, err := c.expect("WATCHING", 0, nil, "ignore %s", c.watching); err != nil {
+		return err
+	}
+	c.watching = tube
+	return nil
+}
+
+// put adds a job to the used tube and returns its id
+func (c *conn) put(body []byte, priority uint32, delay, ttr time.Duration) (string, error) {
+	words, err := c.command(0, body, "put %d %d %d %d", priority, seconds(delay), seconds(ttr), len(body))
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case words[0] == "INSERTED" && len(words) == 2:
+		return words[1], nil
+	case words[0] == "BURIED":
+		return "", &responseError{response: "the server is out of memory, the job was buried"}
+	}
+	return "", &responseError{response: "unexpected response to put: " + strings.Join(words, " ")}
+}
+
+// reserve waits up to timeout for a job of the watched tubes, returning an
+// empty id when none became available
+func (c *conn) reserve(timeout time.Duration) (string, []byte, error) {
+	words, err := c.command(timeout, nil, "reserve-with-timeout %d", seconds(timeout))
+	if err != nil {
+		return "", nil, err
+	}
+	switch {
+	case words[0] == "RESERVED" && len(words) == 3:
+		body, err := c.data(words[2])
+		return words[1], body, err
+	case words[0] == "TIMED_OUT", words[0] == "DEADLINE_SOON":
+		return "", nil, nil
+	}
+	return "", nil, fmt.Errorf("beanstalkd: unexpected response to reserve: %s", strings.Join(words, " "))
+}
+
+// reserves returns how many times the job has been reserved, from stats-job
+func (c *conn) reserves(id string) (int, error) {
+	words, err := c.expect("OK", 0, nil, "stats-job %s", id)
+	if err != nil {
+		return 0, err
+	}
+	if len(words) != 2 {
+		return 0, errors.New("beanstalkd: invalid stats-job response")
+	}
+	stats, err := c.data(words[1])
+	if err != nil {
+		return 0, err
+	}
+
+	// The stats are a YAML dictionary of "key: value" lines
+	for _, line := range strings.Split(string(stats), "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "reserves:"); ok {
+			return strconv.Atoi(strings.TrimSpace(value))
+		}
+	}
+	return 0, errors.New("beanstalkd: stats-job has no reserves")
+}
+
+// expect runs a command whose response must start with status
+func (c *conn) expect(status string, block time.Duration, body []byte, format string, args ...any) ([]string, error) {
+	words, err := c.command(block, body, format, args...)
+	if err != nil {
+		return nil, err
+	}
+	if words[0] != status {
+		return nil, &responseError{response: fmt.Sprintf("expected %s, got %s", status, strings.Join(words, " "))}
+	}
+	return words, nil
+}
+
+func (c *conn) close() error {
+	return c.netConn.Close()
+}
+
+// seconds rounds a duration up to whole seconds, as the protocol uses
+func seconds(d time.Duration) int64 {
+	return int64(max((d+time.Second-1)/time.Second, 0))
+}
@@ -0,0 +1,212 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pixelvide/laravel-go/pkg/batch"
+)
+
+// DatabaseBatchRepository implements batch.Repository with Laravel's
+// job_batches table, like DatabaseBatchRepository in PHP
+type DatabaseBatchRepository struct {
+	db      *sql.DB
+	table   string
+	dialect dialect
+}
+
+// NewDatabaseBatchRepository creates a new repository for the given Laravel
+// connection name (DB_CONNECTION)
+func NewDatabaseBatchRepository(db *sql.DB, connection string, tableName string) *DatabaseBatchRepository {
+	if tableName == "" {
+		tableName = "job_batches"
+	}
+	return &DatabaseBatchRepository{
+		db:      db,
+		table:   tableName,
+		dialect: dialectFor(connection),
+	}
+}
+
+const batchColumns = `id, name, total_jobs, pending_jobs, failed_jobs, failed_job_ids, options, created_at, cancelled_at, finished_at`
+
+// Find returns a batch by id, or nil if it does not exist
+func (r *DatabaseBatchRepository) Find(ctx context.Context, id string) (*batch.Batch, error) {
+	query := r.dialect.rebind(`SELECT ` + batchColumns + ` FROM ` + r.table + ` WHERE id = ?`)
+
+	b, err := r.scanBatch(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return b, err
+}
+
+// Store creates an empty batch with a time ordered id, like Str::orderedUuid
+func (r *DatabaseBatchRepository) Store(ctx context.Context, name string, options batch.Options) (*batch.Batch, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	serialized, err := batch.SerializeOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if r.dialect == dialectPostgres {
+		// Postgres cannot store the null bytes of serialized objects in text columns
+		serialized = base64.StdEncoding.EncodeToString([]byte(serialized))
+	}
+
+	query := r.dialect.rebind(`
+		INSERT INTO ` + r.table + ` (id, name, total_jobs, pending_jobs, failed_jobs, failed_job_ids, options, created_at, cancelled_at, finished_at)
+		VALUES (?, ?, 0, 0, 0, '[]', ?, ?, NULL, NULL)`)
+
+	if _, err := r.db.ExecContext(ctx, query, id.String(), name, serialized, time.Now().Unix()); err != nil {
+		return nil, err
+	}
+	return r.Find(ctx, id.String())
+}
+
+// IncrementTotalJobs adds jobs to a batch
+func (r *DatabaseBatchRepository) IncrementTotalJobs(ctx context.Context, id string, amount int) error {
+	query := r.dialect.rebind(`UPDATE ` + r.table + ` SET total_jobs = total_jobs + ?, pending_jobs = pending_jobs + ?, finished_at = NULL WHERE id = ?`)
+	_, err := r.db.ExecContext(ctx, query, amount, amount, id)
+	return err
+}
+
+// DecrementPendingJobs records a job that completed successfully, removing it
+// from the failed jobs in case an earlier attempt failed
+func (r *DatabaseBatchRepository) DecrementPendingJobs(ctx context.Context, id string, jobID string) (batch.UpdatedBatchJobCounts, error) {
+	return r.updateAtomicValues(ctx, id, func(pending, failed int, failedIDs []string) (int, int, []string) {
+		return pending - 1, failed, slices.DeleteFunc(failedIDs, func(failedID string) bool { return failedID == jobID })
+	})
+}
+
+// IncrementFailedJobs records a job that failed
+func (r *DatabaseBatchRepository) IncrementFailedJobs(ctx context.Context, id string, jobID string) (batch.UpdatedBatchJobCounts, error) {
+	return r.updateAtomicValues(ctx, id, func(pending, failed int, failedIDs []string) (int, int, []string) {
+		if !slices.Contains(failedIDs, jobID) {
+			failedIDs = append(failedIDs, jobID)
+		}
+		return pending, failed + 1, failedIDs
+	})
+}
+
+// MarkAsFinished sets the time the batch finished
+func (r *DatabaseBatchRepository) MarkAsFinished(ctx context.Context, id string) error {
+	query := r.dialect.rebind(`UPDATE ` + r.table + ` SET finished_at = ? WHERE id = ?`)
+	_, err := r.db.ExecContext(ctx, query, time.Now().Unix(), id)
+	return err
+}
+
+// Cancel marks the batch as cancelled and finished
+func (r *DatabaseBatchRepository) Cancel(ctx context.Context, id string) error {
+	now := time.Now().Unix()
+	query := r.dialect.rebind(`UPDATE ` + r.table + ` SET cancelled_at = ?, finished_at = ? WHERE id = ?`)
+	_, err := r.db.ExecContext(ctx, query, now, now, id)
+	return err
+}
+
+// Delete removes a batch
+func (r *DatabaseBatchRepository) Delete(ctx context.Context, id string) error {
+	query := r.dialect.rebind(`DELETE FROM ` + r.table + ` WHERE id = ?`)
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// updateAtomicValues updates the job counts of a batch within a transaction
+// holding its row lock, like DatabaseBatchRepository::updateAtomicValues
+func (r *DatabaseBatchRepository) updateAtomicValues(ctx context.Context, id string, update func(pending, failed int, failedIDs []string) (int, int, []string)) (batch.UpdatedBatchJobCounts, error) {
+	var counts batch.UpdatedBatchJobCounts
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return counts, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	lock := " FOR UPDATE"
+	if r.dialect == dialectSQLite {
+		lock = ""
+	}
+	query := r.dialect.rebind(`SELECT pending_jobs, failed_jobs, failed_job_ids FROM ` + r.table + ` WHERE id = ?` + lock)
+
+	var pending, failed int
+	var rawFailedIDs sql.NullString
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&pending, &failed, &rawFailedIDs); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return counts, nil
+		}
+		return counts, err
+	}
+
+	pending, failed, failedIDs := update(pending, failed, decodeFailedJobIDs(rawFailedIDs.String))
+	encoded, err := json.Marshal(failedIDs)
+	if err != nil {
+		return counts, err
+	}
+
+	updateQuery := r.dialect.rebind(`UPDATE ` + r.table + ` SET pending_jobs = ?, failed_jobs = ?, failed_job_ids = ? WHERE id = ?`)
+	if _, err := tx.ExecContext(ctx, updateQuery, pending, failed, string(encoded), id); err != nil {
+		return counts, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return counts, err
+	}
+	return batch.UpdatedBatchJobCounts{PendingJobs: pending, FailedJobs: failed}, nil
+}
+
+func (r *DatabaseBatchRepository) scanBatch(row interface{ Scan(...any) error }) (*batch.Batch, error) {
+	var b batch.Batch
+	var failedIDs, options sql.NullString
+	var createdAt int64
+	var cancelledAt, finishedAt sql.NullInt64
+
+	err := row.Scan(&b.ID, &b.Name, &b.TotalJobs, &b.PendingJobs, &b.FailedJobs, &failedIDs, &options, &createdAt, &cancelledAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	b.FailedJobIDs = decodeFailedJobIDs(failedIDs.String)
+	b.Options = batch.UnserializeOptions(r.unserializeOptions(options.String))
+	b.CreatedAt = time.Unix(createdAt, 0)
+	if cancelledAt.Valid {
+		b.CancelledAt = time.Unix(cancelledAt.Int64, 0)
+	}
+	if finishedAt.Valid {
+		b.FinishedAt = time.Unix(finishedAt.Int64, 0)
+	}
+	return &b, nil
+}
+
+// unserializeOptions decodes the base64 encoding Laravel applies on Postgres
+func (r *DatabaseBatchRepository) unserializeOptions(serialized string) string {
+	if r.dialect != dialectPostgres || strings.ContainsAny(serialized, ":;") {
+		return serialized
+	}
+	decoded, err := base64.StdEncoding.DecodeString(serialized)
+	if err != nil {
+		return serialized
+	}
+	return string(decoded)
+}
+
+// decodeFailedJobIDs reads the failed_job_ids JSON column
+func decodeFailedJobIDs(raw string) []string {
+	ids := []string{}
+	_ = json.Unmarshal([]byte(raw), &ids)
+	if ids == nil {
+		ids = []string{}
+	}
+	return ids
+}
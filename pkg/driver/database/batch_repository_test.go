@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pixelvide/laravel-go/pkg/batch"
+)
+
+var batchColumnNames = []string{"id", "name", "total_jobs", "pending_jobs", "failed_jobs", "failed_job_ids", "options", "created_at", "cancelled_at", "finished_at"}
+
+func TestBatchRepository_Find(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewDatabaseBatchRepository(db, "mysql", "")
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT id, name, total_jobs, pending_jobs, failed_jobs, failed_job_ids, options, created_at, cancelled_at, finished_at FROM job_batches WHERE id = \?`).
+		WithArgs("batch-1").
+		WillReturnRows(sqlmock.NewRows(batchColumnNames).
+			AddRow("batch-1", "import", 3, 1, 1, `["job-2"]`, `a:1:{s:13:"allowFailures";b:1;}`, createdAt.Unix(), nil, nil))
+
+	b, err := repo.Find(context.Background(), "batch-1")
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+
+	if b.Name != "import" || b.TotalJobs != 3 || b.PendingJobs != 1 || b.FailedJobs != 1 {
+		t.Errorf("unexpected batch counts: %+v", b)
+	}
+	if len(b.FailedJobIDs) != 1 || b.FailedJobIDs[0] != "job-2" {
+		t.Errorf("expected failed job ids [job-2], got %v", b.FailedJobIDs)
+	}
+	if !b.Options.AllowFailures {
+		t.Error("expected the batch to allow failures")
+	}
+	if !b.CreatedAt.Equal(createdAt) || b.Cancelled() || b.Finished() {
+		t.Errorf("unexpected batch times: %+v", b)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBatchRepository_IncrementFailedJobs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewDatabaseBatchRepository(db, "pgsql", "")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT pending_jobs, failed_jobs, failed_job_ids FROM job_batches WHERE id = \$1 FOR UPDATE`).
+		WithArgs("batch-1").
+		WillReturnRows(sqlmock.NewRows([]string{"pending_jobs", "failed_jobs", "failed_job_ids"}).AddRow(2, 1, `["job-1"]`))
+	mock.ExpectExec(`UPDATE job_batches SET pending_jobs = \$1, failed_jobs = \$2, failed_job_ids = \$3 WHERE id = \$4`).
+		WithArgs(2, 2, `["job-1","job-2"]`, "batch-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	counts, err := repo.IncrementFailedJobs(context.Background(), "batch-1", "job-2")
+	if err != nil {
+		t.Fatalf("IncrementFailedJobs failed: %v", err)
+	}
+	if counts != (batch.UpdatedBatchJobCounts{PendingJobs: 2, FailedJobs: 2}) {
+		t.Errorf("unexpected counts: %+v", counts)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBatchRepository_DecrementPendingJobsForgetsRetriedFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewDatabaseBatchRepository(db, "mysql", "")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT pending_jobs, failed_jobs, failed_job_ids FROM job_batches WHERE id = \? FOR UPDATE`).
+		WithArgs("batch-1").
+		WillReturnRows(sqlmock.NewRows([]string{"pending_jobs", "failed_jobs", "failed_job_ids"}).AddRow(1, 1, `["job-1"]`))
+	mock.ExpectExec(`UPDATE job_batches SET pending_jobs = \?, failed_jobs = \?, failed_job_ids = \? WHERE id = \?`).
+		WithArgs(0, 1, `[]`, "batch-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	counts, err := repo.DecrementPendingJobs(context.Background(), "batch-1", "job-1")
+	if err != nil {
+		t.Fatalf("DecrementPendingJobs failed: %v", err)
+	}
+	if counts.PendingJobs != 0 || counts.FailedJobs != 1 {
+		t.Errorf("unexpected counts: %+v", counts)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBatchRepository_SQLiteOmitsRowLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewDatabaseBatchRepository(db, "sqlite", "")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT pending_jobs, failed_jobs, failed_job_ids FROM job_batches WHERE id = \?$`).
+		WithArgs("batch-1").
+		WillReturnRows(sqlmock.NewRows([]string{"pending_jobs", "failed_jobs", "failed_job_ids"}).AddRow(2, 0, `[]`))
+	mock.ExpectExec(`UPDATE job_batches SET pending_jobs = \?, failed_jobs = \?, failed_job_ids = \? WHERE id = \?`).
+		WithArgs(1, 0, `[]`, "batch-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if _, err := repo.DecrementPendingJobs(context.Background(), "batch-1", "job-1"); err != nil {
+		t.Fatalf("DecrementPendingJobs failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBatchRepository_StoreEncodesOptionsOnPostgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewDatabaseBatchRepository(db, "pgsql", "")
+	options := base64.StdEncoding.EncodeToString([]byte(`a:1:{s:13:"allowFailures";b:1;}`))
+
+	mock.ExpectExec(`INSERT INTO job_batches \(id, name, total_jobs, pending_jobs, failed_jobs, failed_job_ids, options, created_at, cancelled_at, finished_at\)`).
+		WithArgs(sqlmock.AnyArg(), "import", options, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT .* FROM job_batches WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows(batchColumnNames).
+			AddRow("batch-1", "import", 0, 0, 0, `[]`, options, time.Now().Unix(), nil, nil))
+
+	b, err := repo.Store(context.Background(), "import", batch.Options{AllowFailures: true})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if !b.Options.AllowFailures {
+		t.Error("expected the stored options to be decoded")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,61 +12,96 @@ import (
 
 	"github.com/pixelvide/laravel-go/pkg/config"
 	"github.com/pixelvide/laravel-go/pkg/queue"
+	"github.com/rs/zerolog"
 )
 
-// DatabaseDriver implements queue.Driver for SQL databases
+// DatabaseDriver implements queue.Driver for SQL databases.
+// It follows Laravel's DatabaseQueue: popped jobs are reserved rather than
+// deleted, and only removed from the table once acknowledged.
 type DatabaseDriver struct {
-	db     *sql.DB
-	table  string
-	driver string
-	mu     sync.RWMutex
+	db         *sql.DB
+	table      string
+	dialect    dialect
+	retryAfter time.Duration
+
+	lockMu     sync.Mutex
+	lockKnown  bool
+	lockClause string
 }
 
-// NewDatabaseDriver creates a new database driver
+// NewDatabaseDriver creates a new database driver.
+// The SQL dialect is derived from cfg.Connection (Laravel's DB_CONNECTION).
 func NewDatabaseDriver(cfg config.DatabaseConfig, db *sql.DB) *DatabaseDriver {
-	tableName := "jobs" // Default
-	// cfg doesn't have Table currently, so hardcoding or using default
+	tableName := cfg.QueueTable
+	if tableName == "" {
+		tableName = "jobs"
+	}
 
-	// Check connection type to determine driver hint
-	driverHint := cfg.Connection
+	retryAfter := cfg.QueueRetryAfter
+	if retryAfter <= 0 {
+		retryAfter = 90 // Laravel's default retry_after
+	}
 
 	return &DatabaseDriver{
-		db:     db,
-		table:  tableName,
-		driver: driverHint,
+		db:         db,
+		table:      tableName,
+		dialect:    dialectFor(cfg.Connection),
+		retryAfter: time.Duration(retryAfter) * time.Second,
 	}
 }
 
+// DB returns the connection the driver runs on
+func (d *DatabaseDriver) DB() *sql.DB {
+	return d.db
+}
+
 func (d *DatabaseDriver) rebind(query string) string {
-	d.mu.RLock()
-	driver := d.driver
-	d.mu.RUnlock()
+	return d.dialect.rebind(query)
+}
 
-	if driver != "postgres" && driver != "pq" && driver != "pgsql" {
-		return query
+// lockForPopping returns the row locking clause used when claiming a job,
+// mirroring DatabaseQueue::getLockForPopping. SKIP LOCKED lets concurrent
+// workers claim different rows instead of queueing up behind the head row.
+// The server version is looked up until that succeeds once.
+func (d *DatabaseDriver) lockForPopping(ctx context.Context) string {
+	switch d.dialect {
+	case dialectPostgres:
+		return "FOR UPDATE SKIP LOCKED"
+	case dialectSQLite:
+		// SQLite has no row locks; the write transaction serializes claims
+		return ""
 	}
 
-	// Replace ? with $1, $2, etc.
-	parts := strings.Split(query, "?")
-	if len(parts) == 1 {
-		return query
+	d.lockMu.Lock()
+	defer d.lockMu.Unlock()
+	if d.lockKnown {
+		return d.lockClause
 	}
 
-	var builder strings.Builder
-	for i, part := range parts {
-		builder.WriteString(part)
-		if i < len(parts)-1 {
-			builder.WriteString("$" + strconv.Itoa(i+1))
-		}
+	var version string
+	if err := d.db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Msg("Could not determine the database server version, falling back to FOR UPDATE")
+		return "FOR UPDATE"
 	}
-	return builder.String()
+
+	d.lockClause = "FOR UPDATE"
+	if mysqlSupportsSkipLocked(version) {
+		d.lockClause = "FOR UPDATE SKIP LOCKED"
+	}
+	d.lockKnown = true
+	return d.lockClause
 }
 
-// Pop retrieves a job from the database
-// Note: This is a simplified implementation. A production-ready version
-// should handle "reserved" state, row locking (FOR UPDATE SKIP LOCKED),
-// and specific SQL dialects (Postgres vs MySQL).
+// Pop retrieves a job from the database and marks it as reserved.
+// A reserved job becomes available again once retry_after has elapsed
+// without it being acknowledged, e.g. because the worker crashed.
 func (d *DatabaseDriver) Pop(ctx context.Context, queueName string) (*queue.Job, error) {
+	return d.PopQueues(ctx, []string{queueName})
+}
+
+// PopQueues retrieves and reserves a job from the first of queueNames that has
+// one available, using a single `queue IN (...)` query ordered by priority.
+func (d *DatabaseDriver) PopQueues(ctx context.Context, queueNames []string) (*queue.Job, error) {
 	// Simple polling loop since SQL doesn't block like Redis
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
@@ -78,7 +112,7 @@ func (d *DatabaseDriver) Pop(ctx context.Context, queueName string) (*queue.Job,
 			return nil, ctx.Err()
 		case <-ticker.C:
 			// Attempt to pop a job
-			job, err := d.popJob(ctx, queueName)
+			job, err := d.popFrom(ctx, queueNames)
 			if err != nil {
 				if errors.Is(err, sql.ErrNoRows) {
 					continue
@@ -90,7 +124,9 @@ func (d *DatabaseDriver) Pop(ctx context.Context, queueName string) (*queue.Job,
 	}
 }
 
-func (d *DatabaseDriver) popJob(ctx context.Context, queueName string) (*queue.Job, error) {
+func (d *DatabaseDriver) popFrom(ctx context.Context, queueNames []string) (*queue.Job, error) {
+	lock := d.lockForPopping(ctx)
+
 	// Start transaction
 	tx, err := d.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -100,60 +136,69 @@ func (d *DatabaseDriver) popJob(ctx context.Context, queueName string) (*queue.J
 		_ = tx.Rollback()
 	}()
 
+	// Restrict to the requested queues, preferring them in the given order
+	queueFilter := "queue = ?"
+	ordering := "id ASC"
+	if len(queueNames) > 1 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(queueNames)), ", ")
+		queueFilter = "queue IN (" + placeholders + ")"
+
+		var priority strings.Builder
+		priority.WriteString("CASE queue")
+		for i := range queueNames {
+			fmt.Fprintf(&priority, " WHEN ? THEN %d", i)
+		}
+		priority.WriteString(" END, id ASC")
+		ordering = priority.String()
+	}
+
 	// Find available job
 	// Laravel jobs table usually has: id, queue, payload, attempts, reserved_at, available_at
+	// A job is available if it has not been reserved yet and is due, or if
+	// its reservation has expired (DatabaseQueue::isReservedButExpired).
 	query := fmt.Sprintf(`
-		SELECT id, payload
+		SELECT id, queue, payload, attempts
 		FROM %s
-		WHERE queue = ?
-		AND (reserved_at IS NULL OR reserved_at <= ?)
-		AND available_at <= ?
-		ORDER BY id ASC
-		LIMIT 1 FOR UPDATE`, d.table) // Note: FOR UPDATE blocks if not using SKIP LOCKED (Postgres feature)
+		WHERE %s
+		AND ((reserved_at IS NULL AND available_at <= ?) OR (reserved_at <= ?))
+		ORDER BY %s
+		LIMIT 1 %s`, d.table, queueFilter, ordering, lock)
 
 	query = d.rebind(query)
 
-	// For compatibility/simplicity, we assume standard SQL.
-	// In high concurrency, this might lock.
-	// We use current timestamp for checks
 	now := time.Now().Unix()
+	expiration := now - int64(d.retryAfter/time.Second)
+
+	args := make([]any, 0, 2*len(queueNames)+2)
+	for _, name := range queueNames {
+		args = append(args, name)
+	}
+	args = append(args, now, expiration)
+	if len(queueNames) > 1 {
+		for _, name := range queueNames {
+			args = append(args, name)
+		}
+	}
 
 	var id int64
+	var queueName string
 	var payload []byte
+	var attempts int
 
-	err = tx.QueryRowContext(ctx, query, queueName, now, now).Scan(&id, &payload)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&id, &queueName, &payload, &attempts)
 	if err != nil {
 		if !errors.Is(err, sql.ErrNoRows) {
-			// Auto-detect PostgreSQL driver if not configured
-			if strings.Contains(err.Error(), "pq:") || strings.Contains(err.Error(), "syntax error at or near \"AND\"") {
-				d.mu.RLock()
-				currentDriver := d.driver
-				d.mu.RUnlock()
-
-				if currentDriver != "postgres" && currentDriver != "pq" && currentDriver != "pgsql" {
-					log.Printf("[DatabaseDriver] Detected PostgreSQL error: %v. Switching driver mode to 'postgres'.", err)
-					d.mu.Lock()
-					d.driver = "postgres"
-					d.mu.Unlock()
-
-					// Rollback current transaction and retry
-					_ = tx.Rollback()
-					return d.popJob(ctx, queueName)
-				}
-			}
-			log.Printf("[DatabaseDriver] Error popping job (Driver=%s): %v. Query: %s", d.driver, err, query)
+			zerolog.Ctx(ctx).Error().Err(err).Str("dialect", string(d.dialect)).Str("query", query).Msg("Error popping job")
 		}
 		return nil, err
 	}
 
-	// Delete job (Queue worker style: pop means remove)
-	// Laravel usually marks as reserved, then deletes on Ack.
-	// Since our interface is Pop-Consume-Done, we can delete here OR mark reserved.
-	// To match the Redis implementation (which pops/removes), we delete it.
-	// NOTE: If worker crashes, job is lost.
-	// Improvement: Mark reserved, delete on completion. But interface is simple Pop.
-	deleteQuery := d.rebind(fmt.Sprintf("DELETE FROM %s WHERE id = ?", d.table))
-	_, err = tx.ExecContext(ctx, deleteQuery, id)
+	// Mark the job as reserved (DatabaseQueue::markJobAsReserved).
+	// The row stays in the table until Ack deletes it, so a crashed worker
+	// does not lose the job: it is picked up again after retry_after.
+	attempts++
+	reserveQuery := d.rebind(fmt.Sprintf("UPDATE %s SET reserved_at = ?, attempts = ? WHERE id = ?", d.table))
+	_, err = tx.ExecContext(ctx, reserveQuery, now, attempts, id)
 	if err != nil {
 		return nil, err
 	}
@@ -163,42 +208,72 @@ func (d *DatabaseDriver) popJob(ctx context.Context, queueName string) (*queue.J
 	}
 
 	return &queue.Job{
-		ID:   fmt.Sprintf("%d", id),
-		Body: payload,
+		ID:       fmt.Sprintf("%d", id),
+		Queue:    queueName,
+		Body:     payload,
+		Attempts: attempts,
 	}, nil
 }
 
 // Push adds a job to the database
 func (d *DatabaseDriver) Push(ctx context.Context, queueName string, body []byte) error {
+	return d.Later(ctx, queueName, body, 0)
+}
+
+// Later adds a job to the database that becomes available after delay
+func (d *DatabaseDriver) Later(ctx context.Context, queueName string, body []byte, delay time.Duration) error {
 	query := fmt.Sprintf(`
 		INSERT INTO %s (queue, payload, attempts, available_at, created_at)
 		VALUES (?, ?, 0, ?, ?)`, d.table)
 
 	query = d.rebind(query)
 
-	now := time.Now().Unix()
-	_, err := d.db.ExecContext(ctx, query, queueName, body, now, now)
+	now := time.Now()
+	_, err := d.db.ExecContext(ctx, query, queueName, body, now.Add(delay).Unix(), now.Unix())
 	return err
 }
 
-// Ack deletes the job from the database
+// Ack deletes the reserved job from the database
 func (d *DatabaseDriver) Ack(ctx context.Context, job *queue.Job) error {
-	// Job was already deleted in Pop, so this is a no-op in the current implementation.
-	// If Pop used "mark reserved" strategy, this would delete it.
-	return nil
+	id, err := strconv.ParseInt(job.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid database job id %q: %w", job.ID, err)
+	}
+
+	query := d.rebind(fmt.Sprintf("DELETE FROM %s WHERE id = ?", d.table))
+	_, err = d.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// Release makes a reserved job available again after delay.
+// Laravel deletes and re-inserts the row; clearing the reservation in place
+// is equivalent and keeps the attempts column intact.
+func (d *DatabaseDriver) Release(ctx context.Context, job *queue.Job, delay time.Duration) error {
+	id, err := strconv.ParseInt(job.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid database job id %q: %w", job.ID, err)
+	}
+
+	query := d.rebind(fmt.Sprintf("UPDATE %s SET reserved_at = NULL, available_at = ?, payload = ? WHERE id = ?", d.table))
+
+	availableAt := time.Now().Add(delay).Unix()
+	_, err = d.db.ExecContext(ctx, query, availableAt, job.Body, id)
+	return err
 }
 
-// Fail moves a job to the failed_jobs table
-func (d *DatabaseDriver) Fail(ctx context.Context, queueName string, body []byte, err error) error {
-	// Laravel failed_jobs: uuid, connection, queue, payload, exception, failed_at
-	query := `
-		INSERT INTO failed_jobs (connection, queue, payload, exception, failed_at)
-		VALUES (?, ?, ?, ?, ?)`
+// ExtendLease moves the job's reserved_at forward, so the reservation only
+// expires once lease has passed rather than retry_after after it was popped.
+// A lease shorter than retry_after extends the reservation by retry_after,
+// so it never expires sooner than a fresh reservation would.
+func (d *DatabaseDriver) ExtendLease(ctx context.Context, job *queue.Job, lease time.Duration) error {
+	id, err := strconv.ParseInt(job.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid database job id %q: %w", job.ID, err)
+	}
 
-	query = d.rebind(query)
+	query := d.rebind(fmt.Sprintf("UPDATE %s SET reserved_at = ? WHERE id = ? AND reserved_at IS NOT NULL", d.table))
 
-	now := time.Now() // failed_at is usually timestamp
-	// We use "database" as connection name
-	_, dbErr := d.db.ExecContext(ctx, query, "database", queueName, body, err.Error(), now)
-	return dbErr
+	reservedAt := time.Now().Add(max(lease, d.retryAfter) - d.retryAfter).Unix()
+	_, err = d.db.ExecContext(ctx, query, reservedAt, id)
+	return err
 }
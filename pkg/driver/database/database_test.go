@@ -2,11 +2,13 @@ package database
 
 import (
 	"context"
+	"database/sql/driver"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/pixelvide/laravel-go/pkg/config"
+	"github.com/pixelvide/laravel-go/pkg/queue"
 )
 
 func TestPop_PostgresSyntax(t *testing.T) {
@@ -27,14 +29,14 @@ func TestPop_PostgresSyntax(t *testing.T) {
 	// Original query structure:
 	// SELECT ... FROM jobs WHERE queue = ? AND ... reserved_at <= ? AND available_at <= ? ...
 
-	query := `SELECT id, payload FROM jobs WHERE queue = \$1 AND \(reserved_at IS NULL OR reserved_at <= \$2\) AND available_at <= \$3 ORDER BY id ASC LIMIT 1 FOR UPDATE`
+	query := `SELECT id, queue, payload, attempts FROM jobs WHERE queue = \$1 AND \(\(reserved_at IS NULL AND available_at <= \$2\) OR \(reserved_at <= \$3\)\) ORDER BY id ASC LIMIT 1 FOR UPDATE SKIP LOCKED`
 
 	mock.ExpectQuery(query).
 		WithArgs("default", sqlmock.AnyArg(), sqlmock.AnyArg()).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "payload"}).AddRow(1, []byte("{}")))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "queue", "payload", "attempts"}).AddRow(1, "default", []byte("{}"), 0))
 
-	// Delete query: DELETE FROM jobs WHERE id = $1
-	mock.ExpectExec("DELETE FROM jobs WHERE id = \\$1").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	// Reserve query: UPDATE jobs SET reserved_at = $1, attempts = $2 WHERE id = $3
+	mock.ExpectExec("UPDATE jobs SET reserved_at = \\$1, attempts = \\$2 WHERE id = \\$3").WithArgs(sqlmock.AnyArg(), 1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -61,17 +63,20 @@ func TestPop_MySQLSyntax(t *testing.T) {
 	cfg := config.DatabaseConfig{Connection: "mysql"}
 	driver := NewDatabaseDriver(cfg, db)
 
+	// MySQL 8 supports SKIP LOCKED
+	mock.ExpectQuery(`SELECT VERSION\(\)`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("8.0.36"))
+
 	// Expectation
 	mock.ExpectBegin()
 
 	// We expect the query with ? because rebind should skip
-	query := `SELECT id, payload FROM jobs WHERE queue = \? AND \(reserved_at IS NULL OR reserved_at <= \?\) AND available_at <= \? ORDER BY id ASC LIMIT 1 FOR UPDATE`
+	query := `SELECT id, queue, payload, attempts FROM jobs WHERE queue = \? AND \(\(reserved_at IS NULL AND available_at <= \?\) OR \(reserved_at <= \?\)\) ORDER BY id ASC LIMIT 1 FOR UPDATE SKIP LOCKED`
 
 	mock.ExpectQuery(query).
 		WithArgs("default", sqlmock.AnyArg(), sqlmock.AnyArg()).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "payload"}).AddRow(1, []byte("{}")))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "queue", "payload", "attempts"}).AddRow(1, "default", []byte("{}"), 0))
 
-	mock.ExpectExec("DELETE FROM jobs WHERE id = \\?").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE jobs SET reserved_at = \\?, attempts = \\? WHERE id = \\?").WithArgs(sqlmock.AnyArg(), 1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -86,3 +91,184 @@ func TestPop_MySQLSyntax(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestPop_ReclaimsExpiredReservation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	cfg := config.DatabaseConfig{Connection: "mysql", QueueRetryAfter: 60}
+	driver := NewDatabaseDriver(cfg, db)
+
+	now := time.Now().Unix()
+
+	mock.ExpectQuery(`SELECT VERSION\(\)`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("8.0.36"))
+	mock.ExpectBegin()
+
+	// The third argument is the reservation expiry: now - retry_after
+	query := `SELECT id, queue, payload, attempts FROM jobs WHERE queue = \? AND \(\(reserved_at IS NULL AND available_at <= \?\) OR \(reserved_at <= \?\)\) ORDER BY id ASC LIMIT 1 FOR UPDATE SKIP LOCKED`
+	mock.ExpectQuery(query).
+		WithArgs("default", sqlmock.AnyArg(), expiryArg{want: now - 60}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "queue", "payload", "attempts"}).AddRow(7, "default", []byte("{}"), 2))
+
+	// A job reclaimed from an expired reservation keeps counting attempts
+	mock.ExpectExec("UPDATE jobs SET reserved_at = \\?, attempts = \\? WHERE id = \\?").WithArgs(sqlmock.AnyArg(), 3, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	job, err := driver.Pop(context.Background(), "default")
+	if err != nil {
+		t.Fatalf("Pop failed: %v", err)
+	}
+	if job.ID != "7" {
+		t.Errorf("Expected job ID 7, got %s", job.ID)
+	}
+	if job.Attempts != 3 {
+		t.Errorf("Expected job attempts 3, got %d", job.Attempts)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAck_DeletesReservedJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	cfg := config.DatabaseConfig{Connection: "pgsql"}
+	driver := NewDatabaseDriver(cfg, db)
+
+	mock.ExpectExec("DELETE FROM jobs WHERE id = \\$1").WithArgs(42).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := driver.Ack(context.Background(), &queue.Job{ID: "42"}); err != nil {
+		t.Errorf("Ack failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+// expiryArg matches a unix timestamp allowing for a second of clock drift
+type expiryArg struct {
+	want int64
+}
+
+func (e expiryArg) Match(v driver.Value) bool {
+	got, ok := v.(int64)
+	return ok && got >= e.want && got <= e.want+1
+}
+
+func TestRelease_MakesJobAvailableAfterDelay(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	cfg := config.DatabaseConfig{Connection: "mysql"}
+	driver := NewDatabaseDriver(cfg, db)
+
+	availableAt := time.Now().Add(30 * time.Second).Unix()
+	mock.ExpectExec("UPDATE jobs SET reserved_at = NULL, available_at = \\?, payload = \\? WHERE id = \\?").
+		WithArgs(expiryArg{want: availableAt}, []byte("{}"), 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := driver.Release(context.Background(), &queue.Job{ID: "5", Body: []byte("{}")}, 30*time.Second); err != nil {
+		t.Errorf("Release failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestExtendLease_MovesReservationForward(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	cfg := config.DatabaseConfig{Connection: "mysql", QueueRetryAfter: 90}
+	driver := NewDatabaseDriver(cfg, db)
+
+	// The reservation expires retry_after after reserved_at, so 5 minutes from now
+	reservedAt := time.Now().Add(5*time.Minute - 90*time.Second).Unix()
+	mock.ExpectExec("UPDATE jobs SET reserved_at = \\? WHERE id = \\? AND reserved_at IS NOT NULL").
+		WithArgs(expiryArg{want: reservedAt}, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := driver.ExtendLease(context.Background(), &queue.Job{ID: "5"}, 5*time.Minute); err != nil {
+		t.Errorf("ExtendLease failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestExtendLease_NeverShortensReservation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	cfg := config.DatabaseConfig{Connection: "mysql", QueueRetryAfter: 90}
+	driver := NewDatabaseDriver(cfg, db)
+
+	// A lease shorter than retry_after reserves the job for retry_after from now
+	mock.ExpectExec("UPDATE jobs SET reserved_at = \\? WHERE id = \\? AND reserved_at IS NOT NULL").
+		WithArgs(expiryArg{want: time.Now().Unix()}, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := driver.ExtendLease(context.Background(), &queue.Job{ID: "5"}, 30*time.Second); err != nil {
+		t.Errorf("ExtendLease failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPopQueues_OrdersByPriority(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	cfg := config.DatabaseConfig{Connection: "pgsql"}
+	driver := NewDatabaseDriver(cfg, db)
+
+	mock.ExpectBegin()
+
+	query := `SELECT id, queue, payload, attempts FROM jobs WHERE queue IN \(\$1, \$2, \$3\) AND \(\(reserved_at IS NULL AND available_at <= \$4\) OR \(reserved_at <= \$5\)\) ORDER BY CASE queue WHEN \$6 THEN 0 WHEN \$7 THEN 1 WHEN \$8 THEN 2 END, id ASC LIMIT 1 FOR UPDATE SKIP LOCKED`
+	mock.ExpectQuery(query).
+		WithArgs("high", "default", "low", sqlmock.AnyArg(), sqlmock.AnyArg(), "high", "default", "low").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "queue", "payload", "attempts"}).AddRow(3, "default", []byte("{}"), 0))
+
+	mock.ExpectExec("UPDATE jobs SET reserved_at = \\$1, attempts = \\$2 WHERE id = \\$3").WithArgs(sqlmock.AnyArg(), 1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	job, err := driver.PopQueues(ctx, []string{"high", "default", "low"})
+	if err != nil {
+		t.Fatalf("PopQueues failed: %v", err)
+	}
+	if job.Queue != "default" {
+		t.Errorf("Expected job from the default queue, got %s", job.Queue)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// dialect identifies the SQL flavour behind a Laravel DB_CONNECTION value
+type dialect string
+
+const (
+	dialectMySQL    dialect = "mysql"
+	dialectPostgres dialect = "pgsql"
+	dialectSQLite   dialect = "sqlite"
+)
+
+// dialectFor maps a Laravel connection name (DB_CONNECTION) to its dialect.
+// Unknown connections are treated as MySQL, Laravel's default.
+func dialectFor(connection string) dialect {
+	switch strings.ToLower(connection) {
+	case "pgsql", "postgres", "postgresql", "pq":
+		return dialectPostgres
+	case "sqlite", "sqlite3":
+		return dialectSQLite
+	default:
+		return dialectMySQL
+	}
+}
+
+// rebind converts ? placeholders into the dialect's bind syntax
+func (d dialect) rebind(query string) string {
+	if d != dialectPostgres {
+		return query
+	}
+
+	// Replace ? with $1, $2, etc.
+	parts := strings.Split(query, "?")
+	if len(parts) == 1 {
+		return query
+	}
+
+	var builder strings.Builder
+	for i, part := range parts {
+		builder.WriteString(part)
+		if i < len(parts)-1 {
+			builder.WriteString("$" + strconv.Itoa(i+1))
+		}
+	}
+	return builder.String()
+}
+
+// identifierPattern matches a table or column name, optionally qualified by a schema
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// quoteIdentifier checks a table or column name and quotes each of its parts,
+// with backticks for MySQL and double quotes otherwise
+func (d dialect) quoteIdentifier(name string) (string, error) {
+	if !identifierPattern.MatchString(name) {
+		return "", fmt.Errorf("invalid SQL identifier %q", name)
+	}
+
+	quote := `"`
+	if d == dialectMySQL {
+		quote = "`"
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quote + part + quote
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// mysqlSupportsSkipLocked reports whether a MySQL or MariaDB server supports
+// SKIP LOCKED, using the same thresholds as DatabaseQueue::getLockForPopping:
+// MySQL 8.0.1 and MariaDB 10.6.0.
+func mysqlSupportsSkipLocked(version string) bool {
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		// Older MariaDB releases report "5.5.5-10.6.12-MariaDB" for replication compatibility
+		version = strings.TrimPrefix(version, "5.5.5-")
+		return compareVersion(version, []int{10, 6, 0}) >= 0
+	}
+	return compareVersion(version, []int{8, 0, 1}) >= 0
+}
+
+// compareVersion compares the leading dotted number of version (e.g. "8.0.36-log") with want
+func compareVersion(version string, want []int) int {
+	if i := strings.IndexFunc(version, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); i >= 0 {
+		version = version[:i]
+	}
+
+	parts := strings.Split(version, ".")
+	for i, w := range want {
+		got := 0
+		if i < len(parts) {
+			got, _ = strconv.Atoi(parts[i])
+		}
+		if got != w {
+			if got < w {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
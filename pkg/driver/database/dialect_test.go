@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pixelvide/laravel-go/pkg/config"
+)
+
+func TestPop_LegacyMySQLFallsBackToForUpdate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	cfg := config.DatabaseConfig{Connection: "mysql"}
+	driver := NewDatabaseDriver(cfg, db)
+
+	// MySQL 5.7 has no SKIP LOCKED, so the plain lock is used.
+	// The version is only looked up once.
+	mock.ExpectQuery(`SELECT VERSION\(\)`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("5.7.44-log"))
+
+	query := `SELECT id, queue, payload, attempts FROM jobs WHERE queue = \? AND \(\(reserved_at IS NULL AND available_at <= \?\) OR \(reserved_at <= \?\)\) ORDER BY id ASC LIMIT 1 FOR UPDATE$`
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectQuery(query).
+			WithArgs("default", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "queue", "payload", "attempts"}).AddRow(1, "default", []byte("{}"), 0))
+		mock.ExpectExec("UPDATE jobs SET reserved_at = \\?, attempts = \\? WHERE id = \\?").WithArgs(sqlmock.AnyArg(), 1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		if _, err := driver.Pop(ctx, "default"); err != nil {
+			t.Fatalf("Pop failed: %v", err)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPop_SQLiteOmitsLockClause(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	cfg := config.DatabaseConfig{Connection: "sqlite"}
+	driver := NewDatabaseDriver(cfg, db)
+
+	mock.ExpectBegin()
+	query := `SELECT id, queue, payload, attempts FROM jobs WHERE queue = \? AND \(\(reserved_at IS NULL AND available_at <= \?\) OR \(reserved_at <= \?\)\) ORDER BY id ASC LIMIT 1$`
+	mock.ExpectQuery(query).
+		WithArgs("default", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "queue", "payload", "attempts"}).AddRow(1, "default", []byte("{}"), 0))
+	mock.ExpectExec("UPDATE jobs SET reserved_at = \\?, attempts = \\? WHERE id = \\?").WithArgs(sqlmock.AnyArg(), 1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if _, err := driver.Pop(ctx, "default"); err != nil {
+		t.Fatalf("Pop failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPop_RetriesFailedVersionLookup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	driver := NewDatabaseDriver(config.DatabaseConfig{Connection: "mysql"}, db)
+
+	// A failed lookup falls back to FOR UPDATE without being remembered
+	mock.ExpectQuery(`SELECT VERSION\(\)`).WillReturnError(errors.New("connection refused"))
+	mock.ExpectQuery(`SELECT VERSION\(\)`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("8.0.36"))
+
+	for _, lock := range []string{"FOR UPDATE", "FOR UPDATE SKIP LOCKED", "FOR UPDATE SKIP LOCKED"} {
+		if got := driver.lockForPopping(context.Background()); got != lock {
+			t.Errorf("lockForPopping() = %q, want %q", got, lock)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDialectFor(t *testing.T) {
+	tests := map[string]dialect{
+		"mysql":    dialectMySQL,
+		"mariadb":  dialectMySQL,
+		"pgsql":    dialectPostgres,
+		"postgres": dialectPostgres,
+		"sqlite":   dialectSQLite,
+		"":         dialectMySQL,
+	}
+
+	for connection, want := range tests {
+		if got := dialectFor(connection); got != want {
+			t.Errorf("dialectFor(%q) = %s, want %s", connection, got, want)
+		}
+	}
+}
+
+func TestMySQLSupportsSkipLocked(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"8.0.36", true},
+		{"8.0.1", true},
+		{"8.0.0", false},
+		{"5.7.44-log", false},
+		{"10.6.12-MariaDB", true},
+		{"5.5.5-10.6.12-MariaDB-1:10.6.12+maria~ubu2004", true},
+		{"10.5.21-MariaDB", false},
+	}
+
+	for _, tt := range tests {
+		if got := mysqlSupportsSkipLocked(tt.version); got != tt.want {
+			t.Errorf("mysqlSupportsSkipLocked(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
@@ -3,33 +3,151 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strconv"
 	"time"
+
+	"github.com/pixelvide/laravel-go/pkg/config"
+	dbconn "github.com/pixelvide/laravel-go/pkg/database"
+	"github.com/pixelvide/laravel-go/pkg/queue"
 )
 
-// DatabaseFailedJobProvider implements queue.FailedJobProvider using a SQL database
+func init() {
+	queue.RegisterFailedJobProvider("database-uuids", func(cfg *config.Config) (queue.FailedJobProvider, error) {
+		db, err := dbconn.NewFactory().Connect(cfg.Database)
+		if err != nil {
+			return nil, err
+		}
+		return NewDatabaseFailedJobProviderForConnection(db, cfg.Database.Connection, cfg.Queue.FailedTable), nil
+	})
+}
+
+// DatabaseFailedJobProvider implements queue.FailedJobProvider using a SQL database.
+// Records match Laravel's DatabaseUuidFailedJobProvider, so failed jobs can be
+// inspected and retried with `php artisan queue:failed` and `queue:retry`.
 type DatabaseFailedJobProvider struct {
-	db    *sql.DB
-	table string
+	db      *sql.DB
+	table   string
+	dialect dialect
 }
 
-// NewDatabaseFailedJobProvider creates a new provider
+// NewDatabaseFailedJobProvider creates a new provider for a MySQL database
 func NewDatabaseFailedJobProvider(db *sql.DB, tableName string) *DatabaseFailedJobProvider {
+	return NewDatabaseFailedJobProviderForConnection(db, "mysql", tableName)
+}
+
+// NewDatabaseFailedJobProviderForConnection creates a new provider for the
+// given Laravel connection name (DB_CONNECTION)
+func NewDatabaseFailedJobProviderForConnection(db *sql.DB, connection string, tableName string) *DatabaseFailedJobProvider {
 	if tableName == "" {
 		tableName = "failed_jobs"
 	}
 	return &DatabaseFailedJobProvider{
-		db:    db,
-		table: tableName,
+		db:      db,
+		table:   tableName,
+		dialect: dialectFor(connection),
 	}
 }
 
 // Log records a failed job to the database
-func (p *DatabaseFailedJobProvider) Log(ctx context.Context, connection string, queue string, payload []byte, exception string) error {
-	query := `
-		INSERT INTO ` + p.table + ` (connection, queue, payload, exception, failed_at)
-		VALUES (?, ?, ?, ?, ?)`
+func (p *DatabaseFailedJobProvider) Log(ctx context.Context, connection string, queueName string, payload []byte, exception string) error {
+	query := p.dialect.rebind(`
+		INSERT INTO ` + p.table + ` (uuid, connection, queue, payload, exception, failed_at)
+		VALUES (?, ?, ?, ?, ?, ?)`)
 
 	now := time.Now()
-	_, err := p.db.ExecContext(ctx, query, connection, queue, payload, exception, now)
+	_, err := p.db.ExecContext(ctx, query, queue.FailedJobUUID(payload), connection, queueName, payload, exception, now)
+	return err
+}
+
+// All returns the failed jobs matching the filter, most recent first
+func (p *DatabaseFailedJobProvider) All(ctx context.Context, filter queue.FailedJobFilter) ([]queue.FailedJob, error) {
+	query := `SELECT id, uuid, connection, queue, payload, exception, failed_at FROM ` + p.table + ` WHERE 1 = 1`
+	var args []any
+	if filter.Connection != "" {
+		query += ` AND connection = ?`
+		args = append(args, filter.Connection)
+	}
+	if filter.Queue != "" {
+		query += ` AND queue = ?`
+		args = append(args, filter.Queue)
+	}
+	query += ` ORDER BY id DESC`
+
+	rows, err := p.db.QueryContext(ctx, p.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []queue.FailedJob
+	for rows.Next() {
+		job, err := scanFailedJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// Find returns a failed job by id or uuid, or nil if it does not exist
+func (p *DatabaseFailedJobProvider) Find(ctx context.Context, id string) (*queue.FailedJob, error) {
+	column, arg := failedJobKey(id)
+	query := p.dialect.rebind(`SELECT id, uuid, connection, queue, payload, exception, failed_at FROM ` + p.table + ` WHERE ` + column + ` = ?`)
+
+	job, err := scanFailedJob(p.db.QueryRowContext(ctx, query, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return job, err
+}
+
+// Forget deletes a failed job by id or uuid, reporting whether it existed
+func (p *DatabaseFailedJobProvider) Forget(ctx context.Context, id string) (bool, error) {
+	column, arg := failedJobKey(id)
+	query := p.dialect.rebind(`DELETE FROM ` + p.table + ` WHERE ` + column + ` = ?`)
+
+	result, err := p.db.ExecContext(ctx, query, arg)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// Flush deletes failed jobs older than the given number of hours, or all of them if hours is 0
+func (p *DatabaseFailedJobProvider) Flush(ctx context.Context, hours int) error {
+	if hours <= 0 {
+		_, err := p.db.ExecContext(ctx, `DELETE FROM `+p.table)
+		return err
+	}
+
+	query := p.dialect.rebind(`DELETE FROM ` + p.table + ` WHERE failed_at <= ?`)
+	_, err := p.db.ExecContext(ctx, query, time.Now().Add(-time.Duration(hours)*time.Hour))
 	return err
 }
+
+// failedJobKey picks the column identifying a failed job: numeric ids are
+// matched against the id column, anything else against the uuid
+func failedJobKey(id string) (string, any) {
+	if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+		return "id", n
+	}
+	return "uuid", id
+}
+
+// scanFailedJob reads a failed_jobs row from sql.Row or sql.Rows
+func scanFailedJob(row interface{ Scan(dest ...any) error }) (*queue.FailedJob, error) {
+	var (
+		id      int64
+		jobUUID sql.NullString
+		job     queue.FailedJob
+	)
+	if err := row.Scan(&id, &jobUUID, &job.Connection, &job.Queue, &job.Payload, &job.Exception, &job.FailedAt); err != nil {
+		return nil, err
+	}
+	job.ID = strconv.FormatInt(id, 10)
+	job.UUID = jobUUID.String
+	return &job, nil
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pixelvide/laravel-go/pkg/queue"
+)
+
+func TestFailedJobProvider_LogStoresPayloadUUID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	provider := NewDatabaseFailedJobProvider(db, "")
+	payload := []byte(`{"uuid":"9a5d1e2c-7c2f-4b8e-9d3a-1f2e3d4c5b6a","displayName":"App\\Jobs\\Test"}`)
+
+	mock.ExpectExec(`INSERT INTO failed_jobs \(uuid, connection, queue, payload, exception, failed_at\)`).
+		WithArgs("9a5d1e2c-7c2f-4b8e-9d3a-1f2e3d4c5b6a", "database", "default", payload, "boom", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := provider.Log(context.Background(), "database", "default", payload, "boom"); err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+var failedJobColumns = []string{"id", "uuid", "connection", "queue", "payload", "exception", "failed_at"}
+
+func TestFailedJobProvider_AllFiltersAndRebinds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	provider := NewDatabaseFailedJobProviderForConnection(db, "pgsql", "")
+	failedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT id, uuid, connection, queue, payload, exception, failed_at FROM failed_jobs WHERE 1 = 1 AND connection = \$1 AND queue = \$2 ORDER BY id DESC`).
+		WithArgs("redis", "emails").
+		WillReturnRows(sqlmock.NewRows(failedJobColumns).
+			AddRow(2, "uuid-2", "redis", "emails", []byte(`{"displayName":"App\\Jobs\\SendMail"}`), "boom", failedAt))
+
+	jobs, err := provider.All(context.Background(), queue.FailedJobFilter{Connection: "redis", Queue: "emails"})
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+
+	if len(jobs) != 1 {
+		t.Fatalf("expected 1 failed job, got %d", len(jobs))
+	}
+	if jobs[0].ID != "2" || jobs[0].UUID != "uuid-2" || !jobs[0].FailedAt.Equal(failedAt) {
+		t.Errorf("unexpected failed job: %+v", jobs[0])
+	}
+	if got := jobs[0].DisplayName(); got != `App\Jobs\SendMail` {
+		t.Errorf("expected display name App\\Jobs\\SendMail, got %s", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFailedJobProvider_FindByIDOrUUID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	provider := NewDatabaseFailedJobProviderForConnection(db, "mysql", "")
+
+	mock.ExpectQuery(`FROM failed_jobs WHERE id = \?`).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows(failedJobColumns).AddRow(7, "uuid-7", "redis", "default", []byte(`{}`), "boom", time.Now()))
+	mock.ExpectQuery(`FROM failed_jobs WHERE uuid = \?`).
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows(failedJobColumns))
+
+	job, err := provider.Find(context.Background(), "7")
+	if err != nil || job == nil || job.UUID != "uuid-7" {
+		t.Errorf("expected to find job 7, got %+v (%v)", job, err)
+	}
+
+	job, err = provider.Find(context.Background(), "missing")
+	if err != nil || job != nil {
+		t.Errorf("expected no job for an unknown uuid, got %+v (%v)", job, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFailedJobProvider_ForgetAndFlush(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	provider := NewDatabaseFailedJobProviderForConnection(db, "mysql", "")
+	ctx := context.Background()
+
+	mock.ExpectExec(`DELETE FROM failed_jobs WHERE uuid = \?`).WithArgs("uuid-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM failed_jobs WHERE failed_at <= \?`).WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`DELETE FROM failed_jobs$`).WillReturnResult(sqlmock.NewResult(0, 5))
+
+	deleted, err := provider.Forget(ctx, "uuid-1")
+	if err != nil || !deleted {
+		t.Errorf("expected job to be forgotten, got %v (%v)", deleted, err)
+	}
+	if err := provider.Flush(ctx, 48); err != nil {
+		t.Errorf("Flush with hours failed: %v", err)
+	}
+	if err := provider.Flush(ctx, 0); err != nil {
+		t.Errorf("Flush failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
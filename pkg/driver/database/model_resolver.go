@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pixelvide/laravel-go/pkg/queue"
+)
+
+// DatabaseModelResolver implements queue.ModelResolver by selecting the rows
+// of models from their tables
+type DatabaseModelResolver struct {
+	db         *sql.DB
+	connection string
+	dialect    dialect
+	models     map[string]modelTable
+}
+
+type modelTable struct {
+	table      string
+	primaryKey string
+}
+
+// NewDatabaseModelResolver creates a new resolver for the given Laravel
+// connection name (DB_CONNECTION)
+func NewDatabaseModelResolver(db *sql.DB, connection string) *DatabaseModelResolver {
+	return &DatabaseModelResolver{
+		db:         db,
+		connection: connection,
+		dialect:    dialectFor(connection),
+		models:     make(map[string]modelTable),
+	}
+}
+
+// Model registers the table and primary key of a model class. Only registered
+// classes are resolved, as the class name comes from the job payload.
+// The primary key defaults to "id". Register models before resolving any.
+func (r *DatabaseModelResolver) Model(class string, table string, primaryKey string) *DatabaseModelResolver {
+	if primaryKey == "" {
+		primaryKey = "id"
+	}
+	r.models[class] = modelTable{table: table, primaryKey: primaryKey}
+	return r
+}
+
+// ResolveModels selects the rows of the referenced models, in the order of the identifier's ids
+func (r *DatabaseModelResolver) ResolveModels(ctx context.Context, model queue.ModelIdentifier) ([]map[string]any, error) {
+	ids := model.IDs()
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	table, ok := r.models[model.Class]
+	if !ok {
+		return nil, fmt.Errorf("model class %q is not registered with the resolver", model.Class)
+	}
+	if model.Connection != nil && *model.Connection != "" && *model.Connection != r.connection {
+		return nil, fmt.Errorf("model %q belongs to connection %q, the resolver uses %q", model.Class, *model.Connection, r.connection)
+	}
+
+	from, err := r.dialect.quoteIdentifier(table.table)
+	if err != nil {
+		return nil, err
+	}
+	primaryKey, err := r.dialect.quoteIdentifier(table.primaryKey)
+	if err != nil {
+		return nil, err
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	query := r.dialect.rebind(`SELECT * FROM ` + from + ` WHERE ` + primaryKey + ` IN (` + placeholders + `)`)
+
+	rows, err := r.db.QueryContext(ctx, query, ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]map[string]any, len(ids))
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			row[column] = values[i]
+		}
+		byKey[keyString(row[table.primaryKey])] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	models := make([]map[string]any, 0, len(byKey))
+	for _, id := range ids {
+		if row, ok := byKey[keyString(id)]; ok {
+			models = append(models, row)
+		}
+	}
+	return models, nil
+}
+
+// keyString formats a primary key for comparison, as drivers scan integer
+// keys as int64 and string keys as []byte
+func keyString(key any) string {
+	if b, ok := key.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(key)
+}
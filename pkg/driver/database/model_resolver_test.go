@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pixelvide/laravel-go/pkg/queue"
+)
+
+func TestModelResolver_ResolveModels(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	resolver := NewDatabaseModelResolver(db, "pgsql").Model("App\\Models\\PodcastEpisode", "podcast_episodes", "")
+
+	mock.ExpectQuery(`SELECT \* FROM "podcast_episodes" WHERE "id" IN \(\$1, \$2, \$3\)`).
+		WithArgs(3, 9, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).
+			AddRow(int64(1), []byte("First")).
+			AddRow(int64(3), []byte("Third")))
+
+	rows, err := resolver.ResolveModels(context.Background(), queue.NewCollectionIdentifier("App\\Models\\PodcastEpisode", []any{3, 9, 1}))
+	if err != nil {
+		t.Fatalf("ResolveModels failed: %v", err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	if string(rows[0]["title"].([]byte)) != "Third" || string(rows[1]["title"].([]byte)) != "First" {
+		t.Errorf("expected rows in the order of the ids, got %v", rows)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestModelResolver_ConfiguredModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	resolver := NewDatabaseModelResolver(db, "mysql").Model("App\\Models\\Podcast", "shows", "uuid")
+
+	mock.ExpectQuery("SELECT \\* FROM `shows` WHERE `uuid` IN \\(\\?\\)").
+		WithArgs("abc").
+		WillReturnRows(sqlmock.NewRows([]string{"uuid", "title"}))
+
+	rows, err := resolver.ResolveModels(context.Background(), queue.NewModelIdentifier("App\\Models\\Podcast", "abc"))
+	if err != nil {
+		t.Fatalf("ResolveModels failed: %v", err)
+	}
+	if len(rows) != 0 {
+		t.Errorf("expected no rows, got %v", rows)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestModelResolver_RejectsUnresolvableModels(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	resolver := NewDatabaseModelResolver(db, "mysql").
+		Model("App\\Models\\Podcast", "podcasts", "id").
+		Model("App\\Models\\Episode", "episodes; DROP TABLE users", "id")
+
+	other := "pgsql"
+	wrongConnection := queue.NewModelIdentifier("App\\Models\\Podcast", 1)
+	wrongConnection.Connection = &other
+
+	tests := map[string]queue.ModelIdentifier{
+		"unregistered class": queue.NewModelIdentifier("App\\Models\\User", 1),
+		"other connection":   wrongConnection,
+		"invalid table":      queue.NewModelIdentifier("App\\Models\\Episode", 1),
+	}
+
+	for name, model := range tests {
+		if _, err := resolver.ResolveModels(context.Background(), model); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expected no queries: %s", err)
+	}
+}
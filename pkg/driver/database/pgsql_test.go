@@ -24,14 +24,14 @@ func TestPop_PgSQLConnection(t *testing.T) {
 	mock.ExpectBegin()
 
 	// We expect the query with $1, $2, $3 because rebind should have replaced ?
-	query := `SELECT id, payload FROM jobs WHERE queue = \$1 AND \(reserved_at IS NULL OR reserved_at <= \$2\) AND available_at <= \$3 ORDER BY id ASC LIMIT 1 FOR UPDATE`
+	query := `SELECT id, queue, payload, attempts FROM jobs WHERE queue = \$1 AND \(\(reserved_at IS NULL AND available_at <= \$2\) OR \(reserved_at <= \$3\)\) ORDER BY id ASC LIMIT 1 FOR UPDATE SKIP LOCKED`
 
 	mock.ExpectQuery(query).
 		WithArgs("default", sqlmock.AnyArg(), sqlmock.AnyArg()).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "payload"}).AddRow(1, []byte("{}")))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "queue", "payload", "attempts"}).AddRow(1, "default", []byte("{}"), 0))
 
-	// Delete query: DELETE FROM jobs WHERE id = $1
-	mock.ExpectExec("DELETE FROM jobs WHERE id = \\$1").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	// Reserve query: UPDATE jobs SET reserved_at = $1, attempts = $2 WHERE id = $3
+	mock.ExpectExec("UPDATE jobs SET reserved_at = \\$1, attempts = \\$2 WHERE id = \\$3").WithArgs(sqlmock.AnyArg(), 1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
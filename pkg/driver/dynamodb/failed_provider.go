@@ -0,0 +1,174 @@
+// Package dynamodb stores failed jobs in DynamoDB like Laravel's DynamoDbFailedJobProvider.
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	awsdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/pixelvide/laravel-go/pkg/config"
+	"github.com/pixelvide/laravel-go/pkg/queue"
+)
+
+// expiresAfter is how long Laravel keeps failed jobs, through a TTL on expires_at
+const expiresAfter = 3 * 24 * time.Hour
+
+// ErrFlushUnsupported is returned by Flush, as DynamoDB relies on TTL to expire failed jobs
+var ErrFlushUnsupported = errors.New("DynamoDb failed job storage may not be flushed. Please use DynamoDb's TTL features on your expires_at attribute")
+
+func init() {
+	queue.RegisterFailedJobProvider("dynamodb", func(cfg *config.Config) (queue.FailedJobProvider, error) {
+		awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(cfg.Queue.FailedRegion))
+		if err != nil {
+			return nil, err
+		}
+
+		client := awsdynamodb.NewFromConfig(awsCfg, func(o *awsdynamodb.Options) {
+			if cfg.Queue.FailedEndpoint != "" {
+				o.BaseEndpoint = aws.String(cfg.Queue.FailedEndpoint)
+			}
+		})
+		return NewDynamoDBFailedJobProvider(client, cfg.App.Name, cfg.Queue.FailedTable), nil
+	})
+}
+
+// DynamoDBFailedJobProvider implements queue.FailedJobProvider on a DynamoDB
+// table keyed by application (partition key) and uuid (sort key), the layout
+// used by Laravel's DynamoDbFailedJobProvider.
+type DynamoDBFailedJobProvider struct {
+	client      *awsdynamodb.Client
+	application string
+	table       string
+}
+
+// NewDynamoDBFailedJobProvider creates a provider for the given application name (APP_NAME)
+func NewDynamoDBFailedJobProvider(client *awsdynamodb.Client, application string, table string) *DynamoDBFailedJobProvider {
+	if table == "" {
+		table = "failed_jobs"
+	}
+	return &DynamoDBFailedJobProvider{client: client, application: application, table: table}
+}
+
+// Log records a failed job, expiring it after three days
+func (p *DynamoDBFailedJobProvider) Log(ctx context.Context, connection string, queueName string, payload []byte, exception string) error {
+	id := queue.FailedJobUUID(payload)
+
+	failedAt := time.Now()
+	_, err := p.client.PutItem(ctx, &awsdynamodb.PutItemInput{
+		TableName: aws.String(p.table),
+		Item: map[string]types.AttributeValue{
+			"application": &types.AttributeValueMemberS{Value: p.application},
+			"uuid":        &types.AttributeValueMemberS{Value: id},
+			"connection":  &types.AttributeValueMemberS{Value: connection},
+			"queue":       &types.AttributeValueMemberS{Value: queueName},
+			"payload":     &types.AttributeValueMemberS{Value: string(payload)},
+			"exception":   &types.AttributeValueMemberS{Value: exception},
+			"failed_at":   &types.AttributeValueMemberN{Value: strconv.FormatInt(failedAt.Unix(), 10)},
+			"expires_at":  &types.AttributeValueMemberN{Value: strconv.FormatInt(failedAt.Add(expiresAfter).Unix(), 10)},
+		},
+	})
+	return err
+}
+
+// All returns the application's failed jobs matching the filter, most recent first
+func (p *DynamoDBFailedJobProvider) All(ctx context.Context, filter queue.FailedJobFilter) ([]queue.FailedJob, error) {
+	paginator := awsdynamodb.NewQueryPaginator(p.client, &awsdynamodb.QueryInput{
+		TableName:              aws.String(p.table),
+		KeyConditionExpression: aws.String("application = :application"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":application": &types.AttributeValueMemberS{Value: p.application},
+		},
+		ScanIndexForward: aws.Bool(false),
+	})
+
+	var jobs []queue.FailedJob
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			if job := failedJobFromItem(item); filter.Matches(job) {
+				jobs = append(jobs, job)
+			}
+		}
+	}
+
+	// The sort key is the uuid, so order by failure time here
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].FailedAt.After(jobs[j].FailedAt) })
+	return jobs, nil
+}
+
+// Find returns a failed job by uuid, or nil if it does not exist
+func (p *DynamoDBFailedJobProvider) Find(ctx context.Context, id string) (*queue.FailedJob, error) {
+	out, err := p.client.GetItem(ctx, &awsdynamodb.GetItemInput{
+		TableName: aws.String(p.table),
+		Key:       p.key(id),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Item) == 0 {
+		return nil, nil
+	}
+
+	job := failedJobFromItem(out.Item)
+	return &job, nil
+}
+
+// Forget deletes a failed job by uuid, reporting whether it existed
+func (p *DynamoDBFailedJobProvider) Forget(ctx context.Context, id string) (bool, error) {
+	out, err := p.client.DeleteItem(ctx, &awsdynamodb.DeleteItemInput{
+		TableName:    aws.String(p.table),
+		Key:          p.key(id),
+		ReturnValues: types.ReturnValueAllOld,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(out.Attributes) > 0, nil
+}
+
+// Flush is not supported; configure a TTL on the expires_at attribute instead
+func (p *DynamoDBFailedJobProvider) Flush(ctx context.Context, hours int) error {
+	return ErrFlushUnsupported
+}
+
+func (p *DynamoDBFailedJobProvider) key(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"application": &types.AttributeValueMemberS{Value: p.application},
+		"uuid":        &types.AttributeValueMemberS{Value: id},
+	}
+}
+
+func failedJobFromItem(item map[string]types.AttributeValue) queue.FailedJob {
+	str := func(name string) string {
+		if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+			return v.Value
+		}
+		return ""
+	}
+
+	var failedAt time.Time
+	if v, ok := item["failed_at"].(*types.AttributeValueMemberN); ok {
+		if ts, err := strconv.ParseInt(v.Value, 10, 64); err == nil {
+			failedAt = time.Unix(ts, 0)
+		}
+	}
+
+	return queue.FailedJob{
+		ID:         str("uuid"),
+		UUID:       str("uuid"),
+		Connection: str("connection"),
+		Queue:      str("queue"),
+		Payload:    []byte(str("payload")),
+		Exception:  str("exception"),
+		FailedAt:   failedAt,
+	}
+}
@@ -0,0 +1,148 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	awsdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/pixelvide/laravel-go/pkg/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// attributes is an item in DynamoDB's JSON wire format, e.g. {"uuid": {"S": "..."}}
+type attributes map[string]map[string]string
+
+// fakeDynamoDB is a minimal stand-in for the DynamoDB JSON API, enough for the failed job provider
+type fakeDynamoDB struct {
+	mu     sync.Mutex
+	tables map[string]map[string]attributes
+}
+
+func (f *fakeDynamoDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var req struct {
+		TableName                 string
+		Item                      attributes
+		Key                       attributes
+		ExpressionAttributeValues attributes
+		ReturnValues              string
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	table := f.tables[req.TableName]
+	if table == nil {
+		table = make(map[string]attributes)
+		f.tables[req.TableName] = table
+	}
+	key := func(item attributes) string { return item["application"]["S"] + "/" + item["uuid"]["S"] }
+
+	var resp any
+	switch strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810.") {
+	case "PutItem":
+		table[key(req.Item)] = req.Item
+		resp = map[string]any{}
+	case "GetItem":
+		resp = map[string]any{"Item": table[key(req.Key)]}
+	case "DeleteItem":
+		old := table[key(req.Key)]
+		delete(table, key(req.Key))
+		resp = map[string]any{"Attributes": old}
+	case "Query":
+		items := []attributes{}
+		for _, item := range table {
+			if item["application"]["S"] == req.ExpressionAttributeValues[":application"]["S"] {
+				items = append(items, item)
+			}
+		}
+		resp = map[string]any{"Items": items, "Count": len(items)}
+	default:
+		http.Error(w, "unsupported operation", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func newTestProvider(t *testing.T, application string) (*DynamoDBFailedJobProvider, *fakeDynamoDB) {
+	t.Helper()
+
+	fake := &fakeDynamoDB{tables: make(map[string]map[string]attributes)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	client := awsdynamodb.New(awsdynamodb.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})
+	return NewDynamoDBFailedJobProvider(client, application, ""), fake
+}
+
+func TestDynamoDBFailedJobProvider_Log(t *testing.T) {
+	provider, fake := newTestProvider(t, "Laravel")
+
+	require.NoError(t, provider.Log(context.Background(), "sqs", "default", []byte(`{"uuid":"abc"}`), "boom"))
+
+	item := fake.tables["failed_jobs"]["Laravel/abc"]
+	require.NotNil(t, item)
+	assert.Equal(t, "sqs", item["connection"]["S"])
+	assert.Equal(t, `{"uuid":"abc"}`, item["payload"]["S"])
+	assert.Equal(t, "boom", item["exception"]["S"])
+	assert.NotEmpty(t, item["failed_at"]["N"])
+	assert.NotEmpty(t, item["expires_at"]["N"])
+}
+
+func TestDynamoDBFailedJobProvider_AllFindForget(t *testing.T) {
+	provider, _ := newTestProvider(t, "Laravel")
+	other := NewDynamoDBFailedJobProvider(provider.client, "Other", "")
+	ctx := context.Background()
+
+	require.NoError(t, provider.Log(ctx, "sqs", "default", []byte(`{"uuid":"a"}`), "boom"))
+	require.NoError(t, provider.Log(ctx, "sqs", "emails", []byte(`{"uuid":"b"}`), "bang"))
+	require.NoError(t, other.Log(ctx, "sqs", "default", []byte(`{"uuid":"c"}`), "boom"))
+
+	// Only the application's jobs are listed
+	jobs, err := provider.All(ctx, queue.FailedJobFilter{})
+	require.NoError(t, err)
+	assert.Len(t, jobs, 2)
+
+	jobs, err = provider.All(ctx, queue.FailedJobFilter{Queue: "emails"})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "b", jobs[0].ID)
+	assert.WithinDuration(t, time.Now(), jobs[0].FailedAt, 2*time.Second)
+
+	job, err := provider.Find(ctx, "a")
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, "default", job.Queue)
+
+	job, err = provider.Find(ctx, "missing")
+	require.NoError(t, err)
+	assert.Nil(t, job)
+
+	deleted, err := provider.Forget(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = provider.Forget(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	assert.ErrorIs(t, provider.Flush(ctx, 0), ErrFlushUnsupported)
+}
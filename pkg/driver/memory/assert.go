@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"github.com/pixelvide/laravel-go/pkg/queue"
+)
+
+// TestingT is the part of *testing.T the assertions use
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Pushed returns the jobs pushed with displayName, e.g. "App\\Jobs\\ProcessPodcast",
+// in the order they were pushed. Their Payload and UnserializedData are set,
+// so GetArg and Decode work as in a handler.
+func (d *MemoryDriver) Pushed(displayName string) []*queue.Job {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var jobs []*queue.Job
+	for _, job := range d.pushed {
+		if job.Payload != nil && job.Payload.DisplayName == displayName {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+// AssertPushed checks that a job with displayName was pushed, like Queue::assertPushed
+func (d *MemoryDriver) AssertPushed(t TestingT, displayName string) bool {
+	t.Helper()
+	if len(d.Pushed(displayName)) == 0 {
+		t.Errorf("The expected [%s] job was not pushed.", displayName)
+		return false
+	}
+	return true
+}
+
+// AssertPushedTimes checks that a job with displayName was pushed exactly times times
+func (d *MemoryDriver) AssertPushedTimes(t TestingT, displayName string, times int) bool {
+	t.Helper()
+	if count := len(d.Pushed(displayName)); count != times {
+		t.Errorf("The expected [%s] job was pushed %d times instead of %d times.", displayName, count, times)
+		return false
+	}
+	return true
+}
+
+// AssertPushedOn checks that a job with displayName was pushed onto queueName,
+// like Queue::assertPushedOn
+func (d *MemoryDriver) AssertPushedOn(t TestingT, queueName string, displayName string) bool {
+	t.Helper()
+	for _, job := range d.Pushed(displayName) {
+		if job.Queue == queueName {
+			return true
+		}
+	}
+	t.Errorf("The expected [%s] job was not pushed on the [%s] queue.", displayName, queueName)
+	return false
+}
+
+// AssertNotPushed checks that no job with displayName was pushed
+func (d *MemoryDriver) AssertNotPushed(t TestingT, displayName string) bool {
+	t.Helper()
+	if count := len(d.Pushed(displayName)); count > 0 {
+		t.Errorf("The unexpected [%s] job was pushed %d times.", displayName, count)
+		return false
+	}
+	return true
+}
+
+// AssertNothingPushed checks that no jobs were pushed, like Queue::assertNothingPushed
+func (d *MemoryDriver) AssertNothingPushed(t TestingT) bool {
+	t.Helper()
+
+	d.mu.Lock()
+	count := len(d.pushed)
+	d.mu.Unlock()
+
+	if count > 0 {
+		t.Errorf("%d unexpected jobs were pushed.", count)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pixelvide/laravel-go/pkg/queue"
+)
+
+// MemoryDriver implements queue.Driver in memory, for tests and for running a
+// publisher and workers in one process during development. Like the other
+// drivers, popped jobs are reserved until they are acknowledged or released,
+// and become available again once RetryAfter has passed.
+//
+// Every pushed job is also recorded, so tests can assert on what was
+// dispatched like with Laravel's Queue::fake().
+type MemoryDriver struct {
+	RetryAfter time.Duration // How long jobs stay reserved; until acknowledged when 0
+
+	mu      sync.Mutex
+	queues  map[string][]*entry
+	pushed  []*queue.Job
+	nextID  int
+	changed chan struct{} // closed and replaced when a job is added or released
+}
+
+// entry is a job held by the driver
+type entry struct {
+	id          string
+	body        []byte
+	attempts    int
+	availableAt time.Time
+	reserved    bool
+	expiresAt   time.Time
+}
+
+// NewMemoryDriver creates an empty in memory driver
+func NewMemoryDriver() *MemoryDriver {
+	return &MemoryDriver{
+		RetryAfter: 90 * time.Second, // Laravel's default retry_after
+		queues:     make(map[string][]*entry),
+		changed:    make(chan struct{}),
+	}
+}
+
+// Pop reserves the next available job of the queue, waiting until there is one
+func (d *MemoryDriver) Pop(ctx context.Context, queueName string) (*queue.Job, error) {
+	return d.PopQueues(ctx, []string{queueName})
+}
+
+// PopQueues reserves the next available job of the first queue that has one,
+// waiting until there is one
+func (d *MemoryDriver) PopQueues(ctx context.Context, queueNames []string) (*queue.Job, error) {
+	for {
+		d.mu.Lock()
+		now := time.Now()
+		job, wakeAt := d.reserve(queueNames, now)
+		changed := d.changed
+		d.mu.Unlock()
+
+		if job != nil {
+			return job, nil
+		}
+		if err := wait(ctx, changed, wakeAt, now); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// reserve reserves the first available job of queueNames. Without one, it
+// returns when the next delayed job or reservation becomes available.
+func (d *MemoryDriver) reserve(queueNames []string, now time.Time) (*queue.Job, time.Time) {
+	var wakeAt time.Time
+	for _, name := range queueNames {
+		for _, e := range d.queues[name] {
+			if e.reserved && d.RetryAfter <= 0 {
+				continue
+			}
+
+			availableAt := e.availableAt
+			if e.reserved {
+				availableAt = e.expiresAt
+			}
+			if availableAt.After(now) {
+				if wakeAt.IsZero() || availableAt.Before(wakeAt) {
+					wakeAt = availableAt
+				}
+				continue
+			}
+
+			e.attempts++
+			e.reserved = true
+			e.expiresAt = now.Add(d.RetryAfter)
+			return &queue.Job{ID: e.id, Queue: name, Body: bytes.Clone(e.body), Attempts: e.attempts}, time.Time{}
+		}
+	}
+	return nil, wakeAt
+}
+
+// wait blocks until changed is closed, wakeAt is reached or ctx is done
+func wait(ctx context.Context, changed <-chan struct{}, wakeAt time.Time, now time.Time) error {
+	var timeout <-chan time.Time
+	if !wakeAt.IsZero() {
+		timer := time.NewTimer(wakeAt.Sub(now))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-changed:
+	case <-timeout:
+	}
+	return nil
+}
+
+// Push adds a job to the queue
+func (d *MemoryDriver) Push(ctx context.Context, queueName string, body []byte) error {
+	return d.Later(ctx, queueName, body, 0)
+}
+
+// Later adds a job to the queue that becomes available once delay has elapsed
+func (d *MemoryDriver) Later(ctx context.Context, queueName string, body []byte, delay time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.nextID++
+	d.queues[queueName] = append(d.queues[queueName], &entry{
+		id:          strconv.Itoa(d.nextID),
+		body:        bytes.Clone(body),
+		availableAt: time.Now().Add(delay),
+	})
+	d.pushed = append(d.pushed, pushedJob(queueName, body))
+	d.notify()
+	return nil
+}
+
+// Ack removes the job from its queue
+func (d *MemoryDriver) Ack(ctx context.Context, job *queue.Job) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.queues[job.Queue] = slices.DeleteFunc(d.queues[job.Queue], func(e *entry) bool {
+		return e.id == job.ID
+	})
+	return nil
+}
+
+// Release makes a reserved job available again after delay, with job.Body as its payload
+func (d *MemoryDriver) Release(ctx context.Context, job *queue.Job, delay time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e := d.find(job); e != nil {
+		e.reserved = false
+		e.body = bytes.Clone(job.Body)
+		e.availableAt = time.Now().Add(delay)
+		d.notify()
+	}
+	return nil
+}
+
+// ExtendLease keeps the reserved job from becoming available again until lease has passed
+func (d *MemoryDriver) ExtendLease(ctx context.Context, job *queue.Job, lease time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e := d.find(job); e != nil && e.reserved {
+		e.expiresAt = time.Now().Add(lease)
+	}
+	return nil
+}
+
+// Size returns the number of jobs on the queue that have not been
+// acknowledged, including delayed and reserved ones
+func (d *MemoryDriver) Size(queueName string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.queues[queueName])
+}
+
+func (d *MemoryDriver) find(job *queue.Job) *entry {
+	for _, e := range d.queues[job.Queue] {
+		if e.id == job.ID {
+			return e
+		}
+	}
+	return nil
+}
+
+// notify wakes up waiting pops. It must be called with mu held.
+func (d *MemoryDriver) notify() {
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+// pushedJob records a pushed payload, parsed like the worker does
+func pushedJob(queueName string, body []byte) *queue.Job {
+	job := &queue.Job{Queue: queueName, Body: bytes.Clone(body)}
+
+	var payload queue.LaravelJob
+	if err := json.Unmarshal(body, &payload); err == nil {
+		job.ID = payload.UUID
+		job.Payload = &payload
+		job.UnserializedData, _ = queue.UnserializeCommand(payload.Data)
+	}
+	return job
+}
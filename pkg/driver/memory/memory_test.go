@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pixelvide/laravel-go/pkg/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingT records the failures of assertions that are expected to fail
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMemoryDriver_ReservesUntilAcknowledged(t *testing.T) {
+	driver := NewMemoryDriver()
+	ctx := context.Background()
+
+	require.NoError(t, driver.Push(ctx, "default", []byte(`{"uuid":"a"}`)))
+
+	job, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, `{"uuid":"a"}`, string(job.Body))
+	assert.Equal(t, "default", job.Queue)
+	assert.Equal(t, 1, job.Attempts)
+
+	// The reserved job is not popped again
+	popCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = driver.Pop(popCtx, "default")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, driver.Size("default"))
+
+	require.NoError(t, driver.Ack(ctx, job))
+	assert.Equal(t, 0, driver.Size("default"))
+}
+
+func TestMemoryDriver_ExpiredReservationsAreRedelivered(t *testing.T) {
+	driver := NewMemoryDriver()
+	driver.RetryAfter = 50 * time.Millisecond
+	ctx := context.Background()
+
+	require.NoError(t, driver.Push(ctx, "default", []byte(`{"uuid":"a"}`)))
+	first, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+
+	// Extending the lease delays the redelivery
+	require.NoError(t, driver.ExtendLease(ctx, first, 150*time.Millisecond))
+	start := time.Now()
+
+	second, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	assert.Equal(t, first.ID, second.ID)
+	assert.Equal(t, 2, second.Attempts)
+}
+
+func TestMemoryDriver_DelayedAndReleasedJobs(t *testing.T) {
+	driver := NewMemoryDriver()
+	ctx := context.Background()
+
+	start := time.Now()
+	require.NoError(t, driver.Later(ctx, "default", []byte(`{"uuid":"a","attempts":0}`), 100*time.Millisecond))
+
+	job, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	// Released jobs keep their new payload
+	job.Body = []byte(`{"uuid":"a","attempts":1}`)
+	require.NoError(t, driver.Release(ctx, job, 0))
+
+	job, err = driver.Pop(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, `{"uuid":"a","attempts":1}`, string(job.Body))
+	assert.Equal(t, 2, job.Attempts)
+}
+
+func TestMemoryDriver_PopQueuesWaitsForPush(t *testing.T) {
+	driver := NewMemoryDriver()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, driver.Push(ctx, "low", []byte(`{"uuid":"low"}`)))
+	require.NoError(t, driver.Push(ctx, "high", []byte(`{"uuid":"high"}`)))
+
+	job, err := driver.PopQueues(ctx, []string{"high", "low"})
+	require.NoError(t, err)
+	assert.Equal(t, "high", job.Queue)
+
+	job, err = driver.PopQueues(ctx, []string{"high", "low"})
+	require.NoError(t, err)
+	assert.Equal(t, "low", job.Queue)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = driver.Push(ctx, "high", []byte(`{"uuid":"later"}`))
+	}()
+
+	job, err = driver.PopQueues(ctx, []string{"high", "low"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"uuid":"later"}`, string(job.Body))
+}
+
+func TestMemoryDriver_Assertions(t *testing.T) {
+	driver := NewMemoryDriver()
+	publisher := queue.NewPublisher(driver)
+	ctx := context.Background()
+
+	driver.AssertNothingPushed(t)
+
+	require.NoError(t, publisher.Dispatch(ctx, "App\\Jobs\\ProcessPodcast", map[string]any{"podcastId": 1}))
+	require.NoError(t, publisher.Dispatch(ctx, "App\\Jobs\\ProcessPodcast", map[string]any{"podcastId": 2}, queue.OnQueue("podcasts")))
+
+	driver.AssertPushed(t, "App\\Jobs\\ProcessPodcast")
+	driver.AssertPushedTimes(t, "App\\Jobs\\ProcessPodcast", 2)
+	driver.AssertPushedOn(t, "podcasts", "App\\Jobs\\ProcessPodcast")
+	driver.AssertNotPushed(t, "App\\Jobs\\SendEmail")
+
+	pushed := driver.Pushed("App\\Jobs\\ProcessPodcast")
+	require.Len(t, pushed, 2)
+	assert.EqualValues(t, 2, pushed[1].GetArg("podcastId"))
+
+	failing := &recordingT{}
+	assert.False(t, driver.AssertPushed(failing, "App\\Jobs\\SendEmail"))
+	assert.False(t, driver.AssertPushedTimes(failing, "App\\Jobs\\ProcessPodcast", 1))
+	assert.False(t, driver.AssertPushedOn(failing, "emails", "App\\Jobs\\ProcessPodcast"))
+	assert.False(t, driver.AssertNotPushed(failing, "App\\Jobs\\ProcessPodcast"))
+	assert.False(t, driver.AssertNothingPushed(failing))
+	assert.Equal(t, []string{
+		"The expected [App\\Jobs\\SendEmail] job was not pushed.",
+		"The expected [App\\Jobs\\ProcessPodcast] job was pushed 2 times instead of 1 times.",
+		"The expected [App\\Jobs\\ProcessPodcast] job was not pushed on the [emails] queue.",
+		"The unexpected [App\\Jobs\\ProcessPodcast] job was pushed 2 times.",
+		"2 unexpected jobs were pushed.",
+	}, failing.errors)
+}
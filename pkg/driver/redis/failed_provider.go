@@ -0,0 +1,134 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pixelvide/laravel-go/pkg/config"
+	"github.com/pixelvide/laravel-go/pkg/queue"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func init() {
+	queue.RegisterFailedJobProvider("redis", func(cfg *config.Config) (queue.FailedJobProvider, error) {
+		client := NewRedisDriver(cfg.Redis.Connection(cfg.Redis.QueueConnection)).Client
+		return NewRedisFailedJobProvider(client, cfg.Redis.Prefix+cfg.Queue.FailedTable), nil
+	})
+}
+
+// RedisFailedJobProvider implements queue.FailedJobProvider with a Redis list,
+// newest job first. Entries use the JSON format of Laravel's FileFailedJobProvider.
+type RedisFailedJobProvider struct {
+	client *goredis.Client
+	key    string
+}
+
+// NewRedisFailedJobProvider creates a provider storing failed jobs in the list at key
+func NewRedisFailedJobProvider(client *goredis.Client, key string) *RedisFailedJobProvider {
+	return &RedisFailedJobProvider{client: client, key: key}
+}
+
+// Log prepends the failed job to the list
+func (p *RedisFailedJobProvider) Log(ctx context.Context, connection string, queueName string, payload []byte, exception string) error {
+	entry, err := json.Marshal(queue.NewFailedJobEntry(connection, queueName, payload, exception))
+	if err != nil {
+		return err
+	}
+
+	return p.client.LPush(ctx, p.key, entry).Err()
+}
+
+// All returns the failed jobs matching the filter, most recent first
+func (p *RedisFailedJobProvider) All(ctx context.Context, filter queue.FailedJobFilter) ([]queue.FailedJob, error) {
+	entries, err := p.entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []queue.FailedJob
+	for _, entry := range entries {
+		if job := entry.job.ToFailedJob(); filter.Matches(job) {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// Find returns a failed job by uuid, or nil if it does not exist
+func (p *RedisFailedJobProvider) Find(ctx context.Context, id string) (*queue.FailedJob, error) {
+	entries, err := p.entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.job.ID == id {
+			job := entry.job.ToFailedJob()
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+// Forget deletes a failed job by uuid, reporting whether it existed
+func (p *RedisFailedJobProvider) Forget(ctx context.Context, id string) (bool, error) {
+	removed, err := p.remove(ctx, func(job queue.FailedJobEntry) bool { return job.ID == id })
+	return removed > 0, err
+}
+
+// Flush deletes failed jobs older than the given number of hours, or all of them if hours is 0
+func (p *RedisFailedJobProvider) Flush(ctx context.Context, hours int) error {
+	if hours <= 0 {
+		return p.client.Del(ctx, p.key).Err()
+	}
+
+	cutoff := time.Now().Add(-time.Duration(hours) * time.Hour).Unix()
+	_, err := p.remove(ctx, func(job queue.FailedJobEntry) bool { return job.FailedAtTimestamp <= cutoff })
+	return err
+}
+
+type redisFailedEntry struct {
+	raw string
+	job queue.FailedJobEntry
+}
+
+// entries reads the whole list, skipping entries that cannot be decoded
+func (p *RedisFailedJobProvider) entries(ctx context.Context) ([]redisFailedEntry, error) {
+	values, err := p.client.LRange(ctx, p.key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]redisFailedEntry, 0, len(values))
+	for _, raw := range values {
+		var job queue.FailedJobEntry
+		if err := json.Unmarshal([]byte(raw), &job); err != nil {
+			continue
+		}
+		entries = append(entries, redisFailedEntry{raw: raw, job: job})
+	}
+	return entries, nil
+}
+
+// remove deletes the entries matching fn by value, so entries pushed
+// concurrently are left untouched
+func (p *RedisFailedJobProvider) remove(ctx context.Context, fn func(queue.FailedJobEntry) bool) (int64, error) {
+	entries, err := p.entries(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	for _, entry := range entries {
+		if !fn(entry.job) {
+			continue
+		}
+		n, err := p.client.LRem(ctx, p.key, 1, entry.raw).Result()
+		if err != nil {
+			return removed, err
+		}
+		removed += n
+	}
+	return removed, nil
+}
@@ -0,0 +1,53 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pixelvide/laravel-go/pkg/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisFailedJobProvider(t *testing.T) {
+	driver, mr := newTestDriver(t)
+	provider := NewRedisFailedJobProvider(driver.Client, "laravel_database_failed_jobs")
+	ctx := context.Background()
+
+	require.NoError(t, provider.Log(ctx, "redis", "default", []byte(`{"uuid":"first"}`), "boom"))
+	require.NoError(t, provider.Log(ctx, "redis", "emails", []byte(`{"uuid":"second"}`), "bang"))
+
+	stored, err := mr.List("laravel_database_failed_jobs")
+	require.NoError(t, err)
+	require.Len(t, stored, 2)
+	var newest map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stored[0]), &newest))
+	assert.Equal(t, "second", newest["id"])
+
+	jobs, err := provider.All(ctx, queue.FailedJobFilter{Queue: "default"})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "first", jobs[0].UUID)
+	assert.WithinDuration(t, time.Now(), jobs[0].FailedAt, 2*time.Second)
+
+	job, err := provider.Find(ctx, "second")
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, "bang", job.Exception)
+
+	deleted, err := provider.Forget(ctx, "second")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = provider.Forget(ctx, "second")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	require.NoError(t, provider.Flush(ctx, 1))
+	assert.True(t, mr.Exists("laravel_database_failed_jobs"))
+
+	require.NoError(t, provider.Flush(ctx, 0))
+	assert.False(t, mr.Exists("laravel_database_failed_jobs"))
+}
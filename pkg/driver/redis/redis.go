@@ -2,15 +2,33 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/pixelvide/laravel-go/pkg/config"
 	"github.com/pixelvide/laravel-go/pkg/queue"
 	goredis "github.com/redis/go-redis/v9"
 )
 
+// RedisDriver implements queue.Driver on top of the same Redis structures as
+// Laravel's RedisQueue, so Go workers and `php artisan queue:work` can consume
+// the same queues. Every key is prefixed with the configured REDIS_PREFIX:
+//
+//	queues:{name}          list of available jobs
+//	queues:{name}:notify   list with one entry per available job, used for blocking pops
+//	queues:{name}:reserved sorted set of jobs being processed, scored by reservation expiry
+//	queues:{name}:delayed  sorted set of delayed or released jobs, scored by availability
+//
+// A popped job stays in the reserved set until it is acknowledged, so jobs of
+// a crashed worker become available again once retry_after has passed.
 type RedisDriver struct {
-	Client *goredis.Client
+	Client     *goredis.Client
+	prefix     string
+	retryAfter time.Duration
+	blockFor   time.Duration
 }
 
 // NewRedisDriver creates a new Redis driver instance
@@ -20,58 +38,176 @@ func NewRedisDriver(cfg config.RedisConfig) *RedisDriver {
 		Password: cfg.Password,
 		DB:       cfg.DB,
 	})
-	return &RedisDriver{Client: rdb}
+
+	retryAfter := cfg.QueueRetryAfter
+	if retryAfter <= 0 {
+		retryAfter = 90 // Laravel's default retry_after
+	}
+
+	blockFor := cfg.QueueBlockFor
+	if blockFor <= 0 {
+		blockFor = 5
+	}
+
+	return &RedisDriver{
+		Client:     rdb,
+		prefix:     cfg.Prefix,
+		retryAfter: time.Duration(retryAfter) * time.Second,
+		blockFor:   time.Duration(blockFor) * time.Second,
+	}
+}
+
+// queueKey returns the Redis key of a queue, like RedisQueue::getQueue
+// combined with the connection prefix
+func (r *RedisDriver) queueKey(queueName string) string {
+	return r.prefix + "queues:" + queueName
 }
 
-// Pop blocks until a job is available and returns it
+// Pop blocks until a job is available and returns it.
+// The job's ID holds the reserved payload, which identifies it in the reserved set.
 func (r *RedisDriver) Pop(ctx context.Context, queueName string) (*queue.Job, error) {
-	// BLPOP returns [key, value]
-	// We use a timeout of 0 to block indefinitely until a job is available,
-	// but we respect the context deadline if set.
-
-	// Since BLPOP with 0 timeout blocks forever, we might want to use a small timeout loop
-	// to allow context cancellation checks, or rely on the client respecting context.
-	// go-redis BLPOP respects context.
-
-	// Laravel queue names in redis usually have a prefix.
-	// If the user passes "default", the key is likely "queues:default".
-	// But we will assume the config passes the full key or we handle it here.
-	// Standard Laravel uses "queues:{queueName}".
-	// Let's assume the user configures the full key or handles the prefix logic outside.
-	// For now, we use queueName as is.
-
-	result, err := r.Client.BLPop(ctx, 0, queueName).Result()
+	return r.PopQueues(ctx, []string{queueName})
+}
+
+// PopQueues blocks until one of the queues has a job, always taking it from
+// the first queue in queueNames that has one.
+func (r *RedisDriver) PopQueues(ctx context.Context, queueNames []string) (*queue.Job, error) {
+	notifyKeys := make([]string, len(queueNames))
+	for i, name := range queueNames {
+		notifyKeys[i] = r.queueKey(name) + ":notify"
+	}
+
+	for {
+		for _, name := range queueNames {
+			if err := r.migrate(ctx, r.queueKey(name)); err != nil {
+				return nil, err
+			}
+		}
+
+		job, err := r.popFirst(ctx, queueNames)
+		if err != nil || job != nil {
+			return job, err
+		}
+
+		// Nothing available: block on the notify lists, then scan the queues
+		// again in priority order. go-redis BLPOP respects the context.
+		err = r.Client.BLPop(ctx, r.blockFor, notifyKeys...).Err()
+		if err != nil && !errors.Is(err, goredis.Nil) {
+			return nil, err
+		}
+		if err == nil {
+			job, err := r.popFirst(ctx, queueNames)
+			if err != nil || job != nil {
+				return job, err
+			}
+		}
+	}
+}
+
+// popFirst reserves a job from the first non-empty queue, or returns nil
+func (r *RedisDriver) popFirst(ctx context.Context, queueNames []string) (*queue.Job, error) {
+	for _, name := range queueNames {
+		job, reserved, err := r.retrieveNextJob(ctx, r.queueKey(name))
+		if err != nil {
+			return nil, err
+		}
+		if job != "" {
+			return &queue.Job{
+				ID:       reserved,
+				Queue:    name,
+				Body:     []byte(job),
+				Attempts: reservedAttempts(reserved),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// reservedAttempts reads the attempts the pop script recorded in the
+// reserved payload, like RedisJob::attempts
+func reservedAttempts(reserved string) int {
+	var payload struct {
+		Attempts int `json:"attempts"`
+	}
+	if err := json.Unmarshal([]byte(reserved), &payload); err != nil {
+		return 0
+	}
+	return payload.Attempts
+}
+
+// migrate moves due delayed jobs and expired reservations back onto the queue
+func (r *RedisDriver) migrate(ctx context.Context, key string) error {
+	if err := r.migrateExpiredJobs(ctx, key+":delayed", key); err != nil {
+		return err
+	}
+	return r.migrateExpiredJobs(ctx, key+":reserved", key)
+}
+
+func (r *RedisDriver) migrateExpiredJobs(ctx context.Context, from, to string) error {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	err := migrateExpiredJobsScript.Run(ctx, r.Client, []string{from, to, to + ":notify"}, now, -1).Err()
+	if errors.Is(err, goredis.Nil) {
+		return nil
+	}
+	return err
+}
+
+// retrieveNextJob pops the next job into the reserved set, returning both the
+// original and the reserved payload. Both are empty if the queue is empty.
+func (r *RedisDriver) retrieveNextJob(ctx context.Context, key string) (string, string, error) {
+	expiresAt := strconv.FormatInt(time.Now().Add(r.retryAfter).Unix(), 10)
+
+	result, err := popScript.Run(ctx, r.Client, []string{key, key + ":reserved", key + ":notify"}, expiresAt).Slice()
 	if err != nil {
-		return nil, err
+		if errors.Is(err, goredis.Nil) {
+			return "", "", nil
+		}
+		return "", "", err
 	}
 
-	// result[0] is the key (queueName), result[1] is the value (payload)
 	if len(result) < 2 {
-		return nil, context.DeadlineExceeded // Should not happen with successful BLPop
+		return "", "", nil
 	}
 
-	return &queue.Job{
-		ID:   "", // Redis lists don't have explicit IDs unless inside the body
-		Body: []byte(result[1]),
-	}, nil
+	job, _ := result[0].(string)
+	reserved, _ := result[1].(string)
+	return job, reserved, nil
 }
 
-// Push adds a job to the queue
+// Push adds a job to the queue and notifies blocked workers
 func (r *RedisDriver) Push(ctx context.Context, queueName string, body []byte) error {
-	return r.Client.RPush(ctx, queueName, body).Err()
+	key := r.queueKey(queueName)
+	err := pushScript.Run(ctx, r.Client, []string{key, key + ":notify"}, body).Err()
+	if errors.Is(err, goredis.Nil) {
+		return nil
+	}
+	return err
 }
 
-// Ack is a no-op for Redis driver as BLPOP removes the item from the list
+// Later adds a job to the delayed set, scored by the time it becomes available (RedisQueue::laterRaw)
+func (r *RedisDriver) Later(ctx context.Context, queueName string, body []byte, delay time.Duration) error {
+	availableAt := float64(time.Now().Add(delay).Unix())
+	return r.Client.ZAdd(ctx, r.queueKey(queueName)+":delayed", goredis.Z{Score: availableAt, Member: body}).Err()
+}
+
+// Ack removes the job from the reserved set (RedisQueue::deleteReserved)
 func (r *RedisDriver) Ack(ctx context.Context, job *queue.Job) error {
-	return nil
+	return r.Client.ZRem(ctx, r.queueKey(job.Queue)+":reserved", job.ID).Err()
+}
+
+// Release moves the reserved job onto the delayed set, scored by the time it
+// becomes available. Like Laravel, the reserved payload (with its
+// incremented attempts) is what gets released.
+func (r *RedisDriver) Release(ctx context.Context, job *queue.Job, delay time.Duration) error {
+	key := r.queueKey(job.Queue)
+	availableAt := strconv.FormatInt(time.Now().Add(delay).Unix(), 10)
+	return releaseScript.Run(ctx, r.Client, []string{key + ":delayed", key + ":reserved"}, job.ID, availableAt).Err()
 }
 
-// Fail pushes the job to a failed jobs list.
-// In a real Laravel setup, this is usually a database table (failed_jobs).
-// Since we are using Redis here, we will push to a "failed" list.
-func (r *RedisDriver) Fail(ctx context.Context, queueName string, body []byte, err error) error {
-	// TODO: Wrap body in a failed job structure with exception details?
-	// For now, simply move to a failed list.
-	failedQueue := queueName + ":failed"
-	return r.Client.RPush(ctx, failedQueue, body).Err()
+// ExtendLease pushes the score of the reserved job forward, so it is only
+// migrated back onto the queue once d, or retry_after if longer, has passed.
+// Jobs no longer in the reserved set are not added back.
+func (r *RedisDriver) ExtendLease(ctx context.Context, job *queue.Job, d time.Duration) error {
+	expiresAt := float64(time.Now().Add(max(d, r.retryAfter)).Unix())
+	return r.Client.ZAddXX(ctx, r.queueKey(job.Queue)+":reserved", goredis.Z{Score: expiresAt, Member: job.ID}).Err()
 }
@@ -0,0 +1,236 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/pixelvide/laravel-go/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDriver(t *testing.T) (*RedisDriver, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	driver := NewRedisDriver(config.RedisConfig{
+		Host:          mr.Host(),
+		Port:          mr.Port(),
+		QueueBlockFor: 1,
+	})
+	t.Cleanup(func() { _ = driver.Client.Close() })
+
+	return driver, mr
+}
+
+func TestRedisDriver_PopReservesJob(t *testing.T) {
+	driver, mr := newTestDriver(t)
+	ctx := context.Background()
+
+	body := `{"uuid":"abc","displayName":"App\\Jobs\\Test","attempts":0}`
+	require.NoError(t, driver.Push(ctx, "default", []byte(body)))
+
+	job, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+
+	assert.Equal(t, body, string(job.Body))
+	assert.Equal(t, "default", job.Queue)
+	assert.Equal(t, 1, job.Attempts)
+
+	// The job moved to the reserved set with its attempts incremented
+	reserved, err := mr.ZMembers("queues:default:reserved")
+	require.NoError(t, err)
+	require.Len(t, reserved, 1)
+	assert.Equal(t, job.ID, reserved[0])
+
+	var reservedPayload map[string]any
+	require.NoError(t, json.Unmarshal([]byte(job.ID), &reservedPayload))
+	assert.EqualValues(t, 1, reservedPayload["attempts"])
+
+	// The notification was consumed along with the job
+	assert.False(t, mr.Exists("queues:default:notify"))
+
+	// Acknowledging removes the reservation
+	require.NoError(t, driver.Ack(ctx, job))
+	assert.False(t, mr.Exists("queues:default:reserved"))
+}
+
+func TestRedisDriver_PopsJobsPushedByLaravel(t *testing.T) {
+	driver, mr := newTestDriver(t)
+
+	// RedisQueue::push uses the same list, and a PHP worker may have drained the notify list
+	body := `{"uuid":"php","displayName":"App\\Jobs\\Test","attempts":0}`
+	_, err := mr.RPush("queues:default", body)
+	require.NoError(t, err)
+
+	job, err := driver.Pop(context.Background(), "default")
+	require.NoError(t, err)
+	assert.Equal(t, body, string(job.Body))
+}
+
+func TestRedisDriver_ReleaseDelaysReservedJob(t *testing.T) {
+	driver, mr := newTestDriver(t)
+	ctx := context.Background()
+
+	require.NoError(t, driver.Push(ctx, "default", []byte(`{"uuid":"abc","attempts":0}`)))
+
+	job, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+
+	require.NoError(t, driver.Release(ctx, job, time.Minute))
+
+	assert.False(t, mr.Exists("queues:default:reserved"))
+	delayed, err := mr.ZMembers("queues:default:delayed")
+	require.NoError(t, err)
+	assert.Equal(t, []string{job.ID}, delayed)
+
+	score, err := mr.ZScore("queues:default:delayed", job.ID)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(time.Minute).Unix(), score, 1)
+}
+
+func TestRedisDriver_ExtendLeaseBumpsReservation(t *testing.T) {
+	driver, mr := newTestDriver(t)
+	ctx := context.Background()
+
+	require.NoError(t, driver.Push(ctx, "default", []byte(`{"uuid":"abc","attempts":0}`)))
+
+	job, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+
+	require.NoError(t, driver.ExtendLease(ctx, job, 10*time.Minute))
+
+	score, err := mr.ZScore("queues:default:reserved", job.ID)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(10*time.Minute).Unix(), score, 1)
+
+	// An acknowledged job is not reserved again
+	require.NoError(t, driver.Ack(ctx, job))
+	require.NoError(t, driver.ExtendLease(ctx, job, 10*time.Minute))
+	assert.False(t, mr.Exists("queues:default:reserved"))
+}
+
+func TestRedisDriver_ExtendLeaseNeverShortensReservation(t *testing.T) {
+	driver, mr := newTestDriver(t)
+	ctx := context.Background()
+
+	require.NoError(t, driver.Push(ctx, "default", []byte(`{"uuid":"abc","attempts":0}`)))
+
+	job, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+
+	// A lease shorter than retry_after keeps the job reserved for retry_after
+	require.NoError(t, driver.ExtendLease(ctx, job, 30*time.Second))
+
+	score, err := mr.ZScore("queues:default:reserved", job.ID)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(90*time.Second).Unix(), score, 1)
+}
+
+func TestRedisDriver_LaterAddsDelayedJob(t *testing.T) {
+	driver, mr := newTestDriver(t)
+	ctx := context.Background()
+
+	body := `{"uuid":"abc","attempts":0}`
+	require.NoError(t, driver.Later(ctx, "default", []byte(body), time.Minute))
+
+	assert.False(t, mr.Exists("queues:default"))
+	score, err := mr.ZScore("queues:default:delayed", body)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(time.Minute).Unix(), score, 1)
+}
+
+func TestRedisDriver_PopMigratesDueJobs(t *testing.T) {
+	driver, mr := newTestDriver(t)
+
+	// A released job that is due, and a reservation whose worker died
+	past := float64(time.Now().Add(-time.Second).Unix())
+	_, err := mr.ZAdd("queues:default:delayed", past, `{"uuid":"delayed","attempts":1}`)
+	require.NoError(t, err)
+	_, err = mr.ZAdd("queues:default:reserved", past, `{"uuid":"expired","attempts":1}`)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	first, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+	second, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"uuid":"delayed","attempts":1}`, string(first.Body))
+	assert.JSONEq(t, `{"uuid":"expired","attempts":1}`, string(second.Body))
+	assert.Equal(t, 2, first.Attempts)
+	assert.Equal(t, 2, second.Attempts)
+	assert.False(t, mr.Exists("queues:default:delayed"))
+}
+
+func TestRedisDriver_PopBlocksUntilContextDone(t *testing.T) {
+	driver, _ := newTestDriver(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	job, err := driver.Pop(ctx, "default")
+	assert.Nil(t, job)
+	assert.Error(t, err)
+}
+
+func TestRedisDriver_UsesPrefix(t *testing.T) {
+	mr := miniredis.RunT(t)
+	driver := NewRedisDriver(config.RedisConfig{
+		Host:   mr.Host(),
+		Port:   mr.Port(),
+		Prefix: "laravel_database_",
+	})
+	defer driver.Client.Close()
+
+	ctx := context.Background()
+	require.NoError(t, driver.Push(ctx, "default", []byte(`{"uuid":"abc","attempts":0}`)))
+
+	assert.True(t, mr.Exists("laravel_database_queues:default"))
+	assert.True(t, mr.Exists("laravel_database_queues:default:notify"))
+
+	job, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+	assert.True(t, mr.Exists("laravel_database_queues:default:reserved"))
+
+	require.NoError(t, driver.Ack(ctx, job))
+	assert.False(t, mr.Exists("laravel_database_queues:default:reserved"))
+}
+
+func TestRedisDriver_PopQueuesPrefersEarlierQueues(t *testing.T) {
+	driver, _ := newTestDriver(t)
+	ctx := context.Background()
+
+	require.NoError(t, driver.Push(ctx, "low", []byte(`{"uuid":"low","attempts":0}`)))
+	require.NoError(t, driver.Push(ctx, "high", []byte(`{"uuid":"high","attempts":0}`)))
+
+	queues := []string{"high", "default", "low"}
+
+	first, err := driver.PopQueues(ctx, queues)
+	require.NoError(t, err)
+	assert.Equal(t, "high", first.Queue)
+
+	second, err := driver.PopQueues(ctx, queues)
+	require.NoError(t, err)
+	assert.Equal(t, "low", second.Queue)
+}
+
+func TestRedisDriver_PopQueuesWakesOnLowerPriorityPush(t *testing.T) {
+	driver, _ := newTestDriver(t)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = driver.Push(context.Background(), "low", []byte(`{"uuid":"low","attempts":0}`))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	job, err := driver.PopQueues(ctx, []string{"high", "low"})
+	require.NoError(t, err)
+	assert.Equal(t, "low", job.Queue)
+}
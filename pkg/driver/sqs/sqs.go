@@ -2,88 +2,735 @@ package sqs
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/pixelvide/laravel-go/pkg/config"
 	"github.com/pixelvide/laravel-go/pkg/queue"
 )
 
+// maxVisibilityTimeout is the longest visibility timeout SQS accepts (12 hours)
+const maxVisibilityTimeout = 43200
+
+// maxDelaySeconds is the longest message delay SQS accepts (15 minutes)
+const maxDelaySeconds = 900
+
+// maxBatchSize is the most messages SQS receives or deletes in one request
+const maxBatchSize = 10
+
+// availableAtAttribute is the message attribute holding when a job delayed
+// beyond what SQS supports becomes available, as a Unix timestamp
+const availableAtAttribute = "available_at"
+
+// MessageIDFunc derives the message group or deduplication id of a job pushed
+// onto a FIFO queue from its payload
+type MessageIDFunc func(body []byte) string
+
+// SQSDriver is a queue driver for Amazon SQS. One driver serves every queue
+// of the connection; queue names are resolved to URLs like Laravel's SqsQueue.
 type SQSDriver struct {
-	client   *sqs.Client
-	queueUrl string
+	client       *sqs.Client
+	prefix       string
+	suffix       string
+	defaultQueue string
+
+	// ReceiveBatchSize is how many messages a receive fetches, at most 10.
+	// Messages are handed to the next Pop calls, so larger batches save
+	// requests but keep messages waiting while their visibility timeout runs.
+	ReceiveBatchSize int32
+
+	// WaitTimeSeconds is how long a receive waits for messages (long polling)
+	WaitTimeSeconds int32
+
+	// MessageGroupID and DeduplicationID set the message group and
+	// deduplication ids of jobs pushed onto FIFO queues (names ending in
+	// ".fifo"). They default to DefaultMessageGroupID and DefaultDeduplicationID.
+	MessageGroupID  MessageIDFunc
+	DeduplicationID MessageIDFunc
+
+	mu       sync.Mutex
+	urls     map[string]string
+	buffers  map[string]*messageBuffer
+	deletes  map[string]*deleteBatcher
+	received chan struct{}  // Closed and replaced whenever a receive finishes
+	receives sync.WaitGroup // Receives running in the background
+}
+
+// NewSQSDriver creates a new SQS driver whose default queue is queueURL
+func NewSQSDriver(client *sqs.Client, queueURL string) *SQSDriver {
+	return NewSQSDriverFromConfig(client, config.SQSConfig{Queue: queueURL})
 }
 
-// NewSQSDriver creates a new SQS driver
-func NewSQSDriver(client *sqs.Client, queueUrl string) *SQSDriver {
+// NewSQSDriverFromConfig creates a new SQS driver for the queues of cfg
+func NewSQSDriverFromConfig(client *sqs.Client, cfg config.SQSConfig) *SQSDriver {
 	return &SQSDriver{
-		client:   client,
-		queueUrl: queueUrl,
+		client:           client,
+		prefix:           cfg.Prefix,
+		suffix:           cfg.Suffix,
+		defaultQueue:     cfg.Queue,
+		ReceiveBatchSize: maxBatchSize,
+		WaitTimeSeconds:  20,
+		MessageGroupID:   DefaultMessageGroupID,
+		DeduplicationID:  DefaultDeduplicationID,
+		urls:             make(map[string]string),
+		buffers:          make(map[string]*messageBuffer),
+		deletes:          make(map[string]*deleteBatcher),
+		received:         make(chan struct{}),
 	}
 }
 
-// Pop retrieves a job from SQS
-func (s *SQSDriver) Pop(ctx context.Context, queueName string) (*queue.Job, error) {
-	// Note: queueName argument is ignored if queueUrl is hardcoded in driver,
-	// or we could treat queueName as the Queue URL if dynamic.
-	// For now, using the configured queueUrl.
-
-	input := &sqs.ReceiveMessageInput{
-		QueueUrl:            aws.String(s.queueUrl),
-		MaxNumberOfMessages: 1,
-		WaitTimeSeconds:     20, // Long polling
-		AttributeNames: []types.QueueAttributeName{
-			types.QueueAttributeNameAll,
-		},
+// QueueURL resolves a queue name to its URL, like SqsQueue::getQueue. URLs are
+// used as is. Other names get the suffix and are appended to the prefix, or
+// looked up with GetQueueUrl when there is no prefix. Lookups are cached.
+func (s *SQSDriver) QueueURL(ctx context.Context, queueName string) (string, error) {
+	if queueName == "" {
+		queueName = s.defaultQueue
+	}
+	if isURL(queueName) {
+		return queueName, nil
 	}
 
-	resp, err := s.client.ReceiveMessage(ctx, input)
+	s.mu.Lock()
+	cached, ok := s.urls[queueName]
+	s.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	name := suffixQueue(queueName, s.suffix)
+	var queueURL string
+	if s.prefix != "" {
+		queueURL = strings.TrimRight(s.prefix, "/") + "/" + name
+	} else {
+		resp, err := s.client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(name)})
+		if err != nil {
+			return "", fmt.Errorf("sqs: cannot resolve the URL of queue %q: %w", name, err)
+		}
+		queueURL = aws.ToString(resp.QueueUrl)
+	}
+
+	s.mu.Lock()
+	s.urls[queueName] = queueURL
+	s.mu.Unlock()
+	return queueURL, nil
+}
+
+// Pop retrieves a job from SQS. Messages are received in batches shared by
+// the goroutines popping the same queue.
+func (s *SQSDriver) Pop(ctx context.Context, queueName string) (*queue.Job, error) {
+	queueURL, err := s.QueueURL(ctx, queueName)
 	if err != nil {
 		return nil, err
 	}
+	buffer := s.buffer(queueURL)
 
-	if len(resp.Messages) == 0 {
-		// No messages, return nil (or error depending on expected behavior, usually nil or timeout)
+	if msg, ok := buffer.next(); ok {
+		return s.toJob(msg, queueName), nil
+	}
+
+	// Only one goroutine receives at a time, the others wait for its batch
+	select {
+	case buffer.receiving <- struct{}{}:
+	case <-ctx.Done():
+		return nil, s.giveUp(ctx, queueURL)
+	}
+	defer func() { <-buffer.receiving }()
+
+	if msg, ok := buffer.next(); ok {
+		return s.toJob(msg, queueName), nil
+	}
+
+	messages, err := s.receive(ctx, queueURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		// No messages within the long poll
 		return nil, context.DeadlineExceeded
 	}
 
-	msg := resp.Messages[0]
+	buffer.add(messages[1:])
+	s.signalReceived()
+	return s.toJob(messages[0], queueName), nil
+}
+
+// PopQueues retrieves a job from the first of queueNames that has one, like
+// `queue:work --queue=high,low`. SQS cannot long poll several queues in one
+// request, so every queue is long polled at once and the messages are
+// buffered; a message of an earlier queue is always popped first.
+func (s *SQSDriver) PopQueues(ctx context.Context, queueNames []string) (*queue.Job, error) {
+	queueURLs := make([]string, len(queueNames))
+	buffers := make([]*messageBuffer, len(queueNames))
+	for i, name := range queueNames {
+		queueURL, err := s.QueueURL(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		queueURLs[i], buffers[i] = queueURL, s.buffer(queueURL)
+	}
+
+	round := &receiveRound{}
+	for {
+		received := s.receivedSignal()
+		for i, buffer := range buffers {
+			if msg, ok := buffer.next(); ok {
+				return s.toJob(msg, queueNames[i]), nil
+			}
+		}
+
+		running, polled, err := round.status()
+		if err != nil {
+			return nil, err
+		}
+		if polled && running == 0 {
+			// No queue had messages within the long poll
+			return nil, context.DeadlineExceeded
+		}
+		for i, buffer := range buffers {
+			s.startReceive(ctx, queueURLs[i], buffer, round)
+		}
 
-	// ID is ReceiptHandle (needed for deleting)
-	id := ""
-	if msg.ReceiptHandle != nil {
-		id = *msg.ReceiptHandle
+		select {
+		case <-received:
+		case <-ctx.Done():
+			return nil, s.giveUp(ctx, queueURLs...)
+		}
 	}
+}
 
-	body := []byte("")
-	if msg.Body != nil {
-		body = []byte(*msg.Body)
+// startReceive long polls a queue in the background unless another
+// goroutine is receiving its messages already
+func (s *SQSDriver) startReceive(ctx context.Context, queueURL string, buffer *messageBuffer, round *receiveRound) {
+	select {
+	case buffer.receiving <- struct{}{}:
+	default:
+		return
 	}
 
+	round.start()
+	s.receives.Add(1)
+	go func() {
+		defer s.receives.Done()
+
+		messages, err := s.receive(ctx, queueURL)
+		if err == nil && ctx.Err() != nil {
+			// Nobody is waiting for the messages anymore
+			err = s.changeVisibility(context.WithoutCancel(ctx), queueURL, messages)
+			messages = nil
+		}
+		buffer.add(messages)
+		round.finish(err)
+		<-buffer.receiving
+		s.signalReceived()
+	}()
+}
+
+// receive long polls a queue, returning the messages that are due
+func (s *SQSDriver) receive(ctx context.Context, queueURL string) ([]types.Message, error) {
+	resp, err := s.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(queueURL),
+		MaxNumberOfMessages: min(max(s.ReceiveBatchSize, 1), maxBatchSize),
+		WaitTimeSeconds:     s.WaitTimeSeconds,
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+			types.MessageSystemAttributeNameAll,
+		},
+		MessageAttributeNames: []string{availableAtAttribute},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.deferMessages(ctx, queueURL, resp.Messages)
+}
+
+// giveUp makes the messages buffered for the queues visible again when a pop
+// is cancelled, e.g. when the worker shuts down, and returns ctx's error
+func (s *SQSDriver) giveUp(ctx context.Context, queueURLs ...string) error {
+	for _, queueURL := range queueURLs {
+		_ = s.changeVisibility(context.WithoutCancel(ctx), queueURL, s.buffer(queueURL).drain())
+	}
+	return ctx.Err()
+}
+
+// Close waits for receives in flight and makes every buffered message
+// visible again, so other workers need not wait for their visibility timeout
+func (s *SQSDriver) Close() error {
+	s.receives.Wait()
+
+	s.mu.Lock()
+	buffers := make(map[string]*messageBuffer, len(s.buffers))
+	for queueURL, buffer := range s.buffers {
+		buffers[queueURL] = buffer
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for queueURL, buffer := range buffers {
+		errs = append(errs, s.changeVisibility(context.Background(), queueURL, buffer.drain()))
+	}
+	return errors.Join(errs...)
+}
+
+// changeVisibility makes received messages visible again right away, with
+// ChangeMessageVisibilityBatch
+func (s *SQSDriver) changeVisibility(ctx context.Context, queueURL string, messages []types.Message) error {
+	var errs []error
+	for start := 0; start < len(messages); start += maxBatchSize {
+		batch := messages[start:min(start+maxBatchSize, len(messages))]
+
+		entries := make([]types.ChangeMessageVisibilityBatchRequestEntry, len(batch))
+		for i, msg := range batch {
+			entries[i] = types.ChangeMessageVisibilityBatchRequestEntry{
+				Id:                aws.String(strconv.Itoa(i)),
+				ReceiptHandle:     msg.ReceiptHandle,
+				VisibilityTimeout: 0,
+			}
+		}
+
+		resp, err := s.client.ChangeMessageVisibilityBatch(ctx, &sqs.ChangeMessageVisibilityBatchInput{
+			QueueUrl: aws.String(queueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, failed := range resp.Failed {
+			errs = append(errs, fmt.Errorf("sqs: cannot make message visible: %s: %s", aws.ToString(failed.Code), aws.ToString(failed.Message)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deferMessages hides messages received before they are due for as long as
+// SQS allows, returning the messages that are due
+func (s *SQSDriver) deferMessages(ctx context.Context, queueURL string, messages []types.Message) ([]types.Message, error) {
+	due := messages[:0:0]
+	for _, msg := range messages {
+		availableAt, ok := messageAvailableAt(msg)
+		if !ok || !availableAt.After(time.Now()) {
+			due = append(due, msg)
+			continue
+		}
+
+		_, err := s.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+			QueueUrl:          aws.String(queueURL),
+			ReceiptHandle:     msg.ReceiptHandle,
+			VisibilityTimeout: visibilityTimeout(time.Until(availableAt)),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return due, nil
+}
+
+func (s *SQSDriver) toJob(msg types.Message, queueName string) *queue.Job {
+	// SQS counts receives itself, like SqsJob::attempts, but receives that
+	// deferred a delayed message are not attempts
+	attempts, _ := strconv.Atoi(msg.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+	attempts = max(attempts-deferrals(msg), 1)
+
 	return &queue.Job{
-		ID:   id,
-		Body: body,
-	}, nil
+		ID:       aws.ToString(msg.ReceiptHandle), // Needed for deleting
+		Queue:    queueName,
+		Body:     []byte(aws.ToString(msg.Body)),
+		Attempts: attempts,
+	}
 }
 
 // Push adds a job to SQS
 func (s *SQSDriver) Push(ctx context.Context, queueName string, body []byte) error {
+	return s.Later(ctx, queueName, body, 0)
+}
+
+// Later adds a job to SQS that becomes visible after delay. SQS delays
+// messages by at most 15 minutes, so the driver hides messages received
+// before they are due until their delay has elapsed. Jobs pushed onto FIFO
+// queues get a message group and deduplication id. FIFO queues only support
+// a delay for the whole queue, and hiding a message would hold back the rest
+// of its group, so delaying jobs on them is an error like in SQS.
+func (s *SQSDriver) Later(ctx context.Context, queueName string, body []byte, delay time.Duration) error {
+	queueURL, err := s.QueueURL(ctx, queueName)
+	if err != nil {
+		return err
+	}
+
 	input := &sqs.SendMessageInput{
-		QueueUrl:    aws.String(s.queueUrl),
+		QueueUrl:    aws.String(queueURL),
 		MessageBody: aws.String(string(body)),
 	}
 
-	_, err := s.client.SendMessage(ctx, input)
+	seconds := int64(delay / time.Second)
+	if isFIFO(queueURL) {
+		if seconds > 0 {
+			return fmt.Errorf("sqs: cannot delay jobs on FIFO queue %q", queueName)
+		}
+		input.MessageGroupId = aws.String(s.MessageGroupID(body))
+		input.MessageDeduplicationId = aws.String(s.DeduplicationID(body))
+	} else {
+		input.DelaySeconds = int32(min(seconds, maxDelaySeconds))
+	}
+
+	if seconds > int64(input.DelaySeconds) {
+		input.MessageAttributes = map[string]types.MessageAttributeValue{
+			availableAtAttribute: {
+				DataType:    aws.String("Number"),
+				StringValue: aws.String(strconv.FormatInt(time.Now().Add(delay).Unix(), 10)),
+			},
+		}
+	}
+
+	_, err = s.client.SendMessage(ctx, input)
 	return err
 }
 
-// Ack deletes the job from SQS
+// Ack deletes the job from SQS. Jobs acknowledged while a delete is in
+// flight are deleted together with DeleteMessageBatch.
 func (s *SQSDriver) Ack(ctx context.Context, job *queue.Job) error {
-	input := &sqs.DeleteMessageInput{
-		QueueUrl:      aws.String(s.queueUrl),
-		ReceiptHandle: aws.String(job.ID),
+	queueURL, err := s.QueueURL(ctx, job.Queue)
+	if err != nil {
+		return err
+	}
+	return s.deleteBatcher(queueURL).delete(ctx, job.ID)
+}
+
+// Release makes the message visible again after delay by changing its
+// visibility timeout, like Laravel's SqsJob::release.
+// SQS keeps the original body, so changes to job.Body are not persisted.
+func (s *SQSDriver) Release(ctx context.Context, job *queue.Job, delay time.Duration) error {
+	queueURL, err := s.QueueURL(ctx, job.Queue)
+	if err != nil {
+		return err
+	}
+
+	input := &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(queueURL),
+		ReceiptHandle:     aws.String(job.ID),
+		VisibilityTimeout: visibilityTimeout(delay),
 	}
 
-	_, err := s.client.DeleteMessage(ctx, input)
+	_, err = s.client.ChangeMessageVisibility(ctx, input)
 	return err
 }
+
+// ExtendLease changes the message's visibility timeout to d, keeping it
+// hidden from other workers while the job runs
+func (s *SQSDriver) ExtendLease(ctx context.Context, job *queue.Job, d time.Duration) error {
+	queueURL, err := s.QueueURL(ctx, job.Queue)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(queueURL),
+		ReceiptHandle:     aws.String(job.ID),
+		VisibilityTimeout: visibilityTimeout(d),
+	})
+	return err
+}
+
+func (s *SQSDriver) buffer(queueURL string) *messageBuffer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buffer, ok := s.buffers[queueURL]
+	if !ok {
+		buffer = &messageBuffer{receiving: make(chan struct{}, 1)}
+		s.buffers[queueURL] = buffer
+	}
+	return buffer
+}
+
+func (s *SQSDriver) deleteBatcher(queueURL string) *deleteBatcher {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batcher, ok := s.deletes[queueURL]
+	if !ok {
+		batcher = &deleteBatcher{client: s.client, queueURL: queueURL}
+		s.deletes[queueURL] = batcher
+	}
+	return batcher
+}
+
+// receivedSignal returns a channel that is closed when the next receive finishes
+func (s *SQSDriver) receivedSignal() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.received
+}
+
+func (s *SQSDriver) signalReceived() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.received)
+	s.received = make(chan struct{})
+}
+
+// receiveRound tracks the background receives started by one PopQueues call
+type receiveRound struct {
+	mu      sync.Mutex
+	running int
+	polled  bool
+	err     error
+}
+
+func (r *receiveRound) start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running++
+}
+
+func (r *receiveRound) finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running--
+	r.polled = true
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *receiveRound) status() (running int, polled bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running, r.polled, r.err
+}
+
+// messageBuffer holds received messages that were not popped yet
+type messageBuffer struct {
+	receiving chan struct{} // Held by the goroutine receiving messages
+	mu        sync.Mutex
+	messages  []types.Message
+}
+
+func (b *messageBuffer) next() (types.Message, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.messages) == 0 {
+		return types.Message{}, false
+	}
+	msg := b.messages[0]
+	b.messages = b.messages[1:]
+	return msg, true
+}
+
+// drain removes and returns all buffered messages
+func (b *messageBuffer) drain() []types.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	messages := b.messages
+	b.messages = nil
+	return messages
+}
+
+func (b *messageBuffer) add(messages []types.Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages = append(b.messages, messages...)
+}
+
+// pendingDelete is a message waiting to be deleted
+type pendingDelete struct {
+	receiptHandle string
+	result        chan error
+}
+
+// deleteBatcher deletes messages of one queue in batches. The first Ack
+// deletes its message right away; Acks arriving meanwhile are deleted
+// together in the next batch.
+type deleteBatcher struct {
+	client   *sqs.Client
+	queueURL string
+
+	mu       sync.Mutex
+	pending  []pendingDelete
+	flushing bool
+}
+
+func (b *deleteBatcher) delete(ctx context.Context, receiptHandle string) error {
+	entry := pendingDelete{receiptHandle: receiptHandle, result: make(chan error, 1)}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, entry)
+	flush := !b.flushing
+	b.flushing = true
+	b.mu.Unlock()
+
+	if flush {
+		// Deletes must go through even if the worker is stopping
+		b.flush(context.WithoutCancel(ctx))
+	}
+
+	select {
+	case err := <-entry.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush deletes pending messages until none are left
+func (b *deleteBatcher) flush(ctx context.Context) {
+	for {
+		b.mu.Lock()
+		n := min(len(b.pending), maxBatchSize)
+		if n == 0 {
+			b.flushing = false
+			b.mu.Unlock()
+			return
+		}
+		batch := b.pending[:n:n]
+		b.pending = b.pending[n:]
+		b.mu.Unlock()
+
+		b.deleteBatch(ctx, batch)
+	}
+}
+
+func (b *deleteBatcher) deleteBatch(ctx context.Context, batch []pendingDelete) {
+	entries := make([]types.DeleteMessageBatchRequestEntry, len(batch))
+	for i, entry := range batch {
+		entries[i] = types.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: aws.String(entry.receiptHandle),
+		}
+	}
+
+	resp, err := b.client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(b.queueURL),
+		Entries:  entries,
+	})
+	if err != nil {
+		for _, entry := range batch {
+			entry.result <- err
+		}
+		return
+	}
+
+	failed := make(map[string]error, len(resp.Failed))
+	for _, f := range resp.Failed {
+		failed[aws.ToString(f.Id)] = fmt.Errorf("sqs: cannot delete message: %s: %s", aws.ToString(f.Code), aws.ToString(f.Message))
+	}
+	for i, entry := range batch {
+		entry.result <- failed[strconv.Itoa(i)]
+	}
+}
+
+// deferrals returns how often a delayed message was received and hidden
+// again before it was due, assuming workers received it whenever it became
+// visible
+func deferrals(msg types.Message) int {
+	availableAt, ok := messageAvailableAt(msg)
+	if !ok {
+		return 0
+	}
+	sentMillis, err := strconv.ParseInt(msg.Attributes[string(types.MessageSystemAttributeNameSentTimestamp)], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	// SQS itself delayed the message for the first 15 minutes
+	hidden := availableAt.Sub(time.UnixMilli(sentMillis)) - maxDelaySeconds*time.Second
+	if hidden <= 0 {
+		return 0
+	}
+	return int((hidden + maxVisibilityTimeout*time.Second - 1) / (maxVisibilityTimeout * time.Second))
+}
+
+// messageAvailableAt returns when a message delayed beyond what SQS supports is due
+func messageAvailableAt(msg types.Message) (time.Time, bool) {
+	attribute, ok := msg.MessageAttributes[availableAtAttribute]
+	if !ok {
+		return time.Time{}, false
+	}
+	unix, err := strconv.ParseInt(aws.ToString(attribute.StringValue), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0), true
+}
+
+// visibilityTimeout converts a delay to a visibility timeout SQS accepts
+func visibilityTimeout(delay time.Duration) int32 {
+	seconds := int64((delay + time.Second - 1) / time.Second)
+	return int32(min(max(seconds, 0), maxVisibilityTimeout))
+}
+
+// DefaultMessageGroupID returns the messageGroup property of the job's
+// command, set with onGroup() or the MessageGroup dispatch option, or "default"
+func DefaultMessageGroupID(body []byte) string {
+	var payload queue.LaravelJob
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "default"
+	}
+	command, err := queue.UnserializeCommand(payload.Data)
+	if err != nil {
+		return "default"
+	}
+
+	switch group := queue.GetPHPProperty(command, "messageGroup").(type) {
+	case string:
+		return group
+	case int:
+		return strconv.Itoa(group)
+	default:
+		return "default"
+	}
+}
+
+// DefaultDeduplicationID returns the payload's deduplicationId, set with the
+// DeduplicationID dispatch option, or else its uuid, so only retries of the
+// same push are deduplicated
+func DefaultDeduplicationID(body []byte) string {
+	var payload queue.LaravelJob
+	if err := json.Unmarshal(body, &payload); err == nil {
+		if payload.DeduplicationID != "" {
+			return payload.DeduplicationID
+		}
+		if payload.UUID != "" {
+			return payload.UUID
+		}
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// isFIFO reports whether a queue URL or name is a FIFO queue
+func isFIFO(queue string) bool {
+	return strings.HasSuffix(queue, ".fifo")
+}
+
+// isURL reports whether a queue name is a full queue URL
+func isURL(name string) bool {
+	u, err := url.Parse(name)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// suffixQueue appends the suffix to a queue name, before the ".fifo" of FIFO
+// queues, unless the name already ends with it
+func suffixQueue(name string, suffix string) string {
+	if base, ok := strings.CutSuffix(name, ".fifo"); ok {
+		if !strings.HasSuffix(base, suffix) {
+			base += suffix
+		}
+		return base + ".fifo"
+	}
+	if !strings.HasSuffix(name, suffix) {
+		name += suffix
+	}
+	return name
+}
@@ -0,0 +1,521 @@
+package sqs
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/pixelvide/laravel-go/pkg/config"
+	"github.com/pixelvide/laravel-go/pkg/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMessage struct {
+	id              string
+	body            string
+	receiptHandle   string
+	receives        int
+	sentAt          time.Time
+	visibleAt       time.Time
+	availableAt     string // available_at message attribute
+	groupID         string
+	deduplicationID string
+}
+
+// fakeSQS is a minimal stand-in for the SQS JSON API, enough for the driver
+type fakeSQS struct {
+	mu       sync.Mutex
+	url      string
+	queues   map[string][]*fakeMessage
+	requests map[string]int
+	nextID   int
+}
+
+func newFakeSQS(queues ...string) *fakeSQS {
+	f := &fakeSQS{queues: make(map[string][]*fakeMessage), requests: make(map[string]int)}
+	for _, name := range queues {
+		f.queues[name] = nil
+	}
+	return f
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func (f *fakeSQS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var req struct {
+		QueueName           string
+		QueueUrl            string
+		MessageBody         string
+		DelaySeconds        int
+		MaxNumberOfMessages int
+		ReceiptHandle       string
+		VisibilityTimeout   int
+		Entries             []struct {
+			Id, ReceiptHandle string
+			VisibilityTimeout int
+		}
+
+		MessageGroupId         string
+		MessageDeduplicationId string
+		MessageAttributes      map[string]struct{ DataType, StringValue string }
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	operation := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSQS.")
+	f.requests[operation]++
+
+	name := req.QueueName
+	if req.QueueUrl != "" {
+		name = path.Base(req.QueueUrl)
+	}
+	messages, ok := f.queues[name]
+	if !ok {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"__type": "com.amazonaws.sqs#QueueDoesNotExist", "message": "The specified queue does not exist."})
+		return
+	}
+
+	var resp any
+	switch operation {
+	case "GetQueueUrl":
+		resp = map[string]any{"QueueUrl": f.url + "/000000000000/" + name}
+	case "SendMessage":
+		fifo := strings.HasSuffix(name, ".fifo")
+		if req.DelaySeconds > 900 || (fifo && req.DelaySeconds > 0) || fifo != (req.MessageGroupId != "") {
+			w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"__type": "com.amazonaws.sqs#InvalidParameterValue", "message": "invalid parameters"})
+			return
+		}
+
+		f.nextID++
+		id := strconv.Itoa(f.nextID)
+		f.queues[name] = append(messages, &fakeMessage{
+			id:              id,
+			body:            req.MessageBody,
+			sentAt:          time.Now(),
+			visibleAt:       time.Now().Add(time.Duration(req.DelaySeconds) * time.Second),
+			availableAt:     req.MessageAttributes["available_at"].StringValue,
+			groupID:         req.MessageGroupId,
+			deduplicationID: req.MessageDeduplicationId,
+		})
+		resp = map[string]any{"MessageId": id, "MD5OfMessageBody": md5Hex(req.MessageBody)}
+	case "ReceiveMessage":
+		received := []map[string]any{}
+		for _, msg := range messages {
+			if len(received) == req.MaxNumberOfMessages {
+				break
+			}
+			if msg.visibleAt.After(time.Now()) {
+				continue
+			}
+			msg.receives++
+			msg.receiptHandle = fmt.Sprintf("%s-%d", msg.id, msg.receives)
+			msg.visibleAt = time.Now().Add(30 * time.Second)
+			message := map[string]any{
+				"MessageId":     msg.id,
+				"ReceiptHandle": msg.receiptHandle,
+				"Body":          msg.body,
+				"MD5OfBody":     md5Hex(msg.body),
+				"Attributes": map[string]string{
+					"ApproximateReceiveCount": strconv.Itoa(msg.receives),
+					"SentTimestamp":           strconv.FormatInt(msg.sentAt.UnixMilli(), 10),
+				},
+			}
+			if msg.availableAt != "" {
+				message["MessageAttributes"] = map[string]any{
+					"available_at": map[string]string{"DataType": "Number", "StringValue": msg.availableAt},
+				}
+			}
+			received = append(received, message)
+		}
+		resp = map[string]any{"Messages": received}
+	case "DeleteMessageBatch":
+		successful, failed := []map[string]any{}, []map[string]any{}
+		for _, entry := range req.Entries {
+			found := false
+			for i, msg := range f.queues[name] {
+				if msg.receiptHandle == entry.ReceiptHandle {
+					f.queues[name] = append(f.queues[name][:i], f.queues[name][i+1:]...)
+					found = true
+					break
+				}
+			}
+			if found {
+				successful = append(successful, map[string]any{"Id": entry.Id})
+			} else {
+				failed = append(failed, map[string]any{"Id": entry.Id, "Code": "ReceiptHandleIsInvalid", "Message": "invalid", "SenderFault": true})
+			}
+		}
+		resp = map[string]any{"Successful": successful, "Failed": failed}
+	case "ChangeMessageVisibility":
+		for _, msg := range messages {
+			if msg.receiptHandle == req.ReceiptHandle {
+				msg.visibleAt = time.Now().Add(time.Duration(req.VisibilityTimeout) * time.Second)
+			}
+		}
+		resp = map[string]any{}
+	case "ChangeMessageVisibilityBatch":
+		successful := []map[string]any{}
+		for _, entry := range req.Entries {
+			for _, msg := range messages {
+				if msg.receiptHandle == entry.ReceiptHandle {
+					msg.visibleAt = time.Now().Add(time.Duration(entry.VisibilityTimeout) * time.Second)
+				}
+			}
+			successful = append(successful, map[string]any{"Id": entry.Id})
+		}
+		resp = map[string]any{"Successful": successful, "Failed": []map[string]any{}}
+	default:
+		http.Error(w, "unsupported operation", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeSQS) requestCount(operation string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[operation]
+}
+
+// elapse moves the messages of all queues d into the past, as if d had passed
+func (f *fakeSQS) elapse(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, messages := range f.queues {
+		for _, msg := range messages {
+			msg.sentAt = msg.sentAt.Add(-d)
+			msg.visibleAt = msg.visibleAt.Add(-d)
+			if unix, err := strconv.ParseInt(msg.availableAt, 10, 64); err == nil {
+				msg.availableAt = strconv.FormatInt(unix-int64(d/time.Second), 10)
+			}
+		}
+	}
+}
+
+func (f *fakeSQS) messages(name string) []fakeMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messages := make([]fakeMessage, len(f.queues[name]))
+	for i, msg := range f.queues[name] {
+		messages[i] = *msg
+	}
+	return messages
+}
+
+func (f *fakeSQS) messageCount(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.queues[name])
+}
+
+func newTestDriver(t *testing.T, cfg config.SQSConfig, queues ...string) (*SQSDriver, *fakeSQS) {
+	t.Helper()
+
+	fake := newFakeSQS(queues...)
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	fake.url = srv.URL
+
+	client := awssqs.New(awssqs.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})
+	if cfg.Prefix == "prefix" {
+		cfg.Prefix = srv.URL + "/000000000000/"
+	}
+
+	driver := NewSQSDriverFromConfig(client, cfg)
+	driver.WaitTimeSeconds = 0
+	return driver, fake
+}
+
+func TestSQSDriver_QueueURL(t *testing.T) {
+	driver, fake := newTestDriver(t, config.SQSConfig{Queue: "default", Suffix: "-production"}, "default-production", "emails-production")
+	ctx := context.Background()
+
+	// Without a prefix, names are looked up once
+	url, err := driver.QueueURL(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, fake.url+"/000000000000/default-production", url)
+
+	_, err = driver.QueueURL(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, 1, fake.requestCount("GetQueueUrl"))
+
+	_, err = driver.QueueURL(ctx, "missing")
+	assert.ErrorContains(t, err, `queue "missing-production"`)
+
+	// URLs are used as is
+	url, err = driver.QueueURL(ctx, "https://sqs.eu-west-1.amazonaws.com/123/other")
+	require.NoError(t, err)
+	assert.Equal(t, "https://sqs.eu-west-1.amazonaws.com/123/other", url)
+
+	prefixed := NewSQSDriverFromConfig(nil, config.SQSConfig{Prefix: "https://sqs.us-east-1.amazonaws.com/123/", Suffix: "-prod"})
+	url, err = prefixed.QueueURL(ctx, "orders.fifo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://sqs.us-east-1.amazonaws.com/123/orders-prod.fifo", url)
+
+	url, err = prefixed.QueueURL(ctx, "emails-prod")
+	require.NoError(t, err)
+	assert.Equal(t, "https://sqs.us-east-1.amazonaws.com/123/emails-prod", url)
+
+	// A driver created with a queue URL uses it as the default queue
+	url, err = NewSQSDriver(nil, "https://sqs.us-east-1.amazonaws.com/123/jobs").QueueURL(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://sqs.us-east-1.amazonaws.com/123/jobs", url)
+}
+
+func TestSQSDriver_RoutesJobsByQueue(t *testing.T) {
+	driver, fake := newTestDriver(t, config.SQSConfig{Prefix: "prefix", Queue: "default"}, "default", "emails")
+	ctx := context.Background()
+
+	require.NoError(t, driver.Push(ctx, "default", []byte(`{"uuid":"a"}`)))
+	require.NoError(t, driver.Push(ctx, "emails", []byte(`{"uuid":"b"}`)))
+
+	job, err := driver.Pop(ctx, "emails")
+	require.NoError(t, err)
+	assert.Equal(t, `{"uuid":"b"}`, string(job.Body))
+	assert.Equal(t, "emails", job.Queue)
+	assert.Equal(t, 1, job.Attempts)
+
+	require.NoError(t, driver.Ack(ctx, job))
+	assert.Equal(t, 0, fake.messageCount("emails"))
+	assert.Equal(t, 1, fake.messageCount("default"))
+
+	_, err = driver.Pop(ctx, "emails")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSQSDriver_ReceivesInBatches(t *testing.T) {
+	driver, fake := newTestDriver(t, config.SQSConfig{Prefix: "prefix"}, "default")
+	ctx := context.Background()
+
+	for i := 0; i < 12; i++ {
+		require.NoError(t, driver.Push(ctx, "default", []byte(strconv.Itoa(i))))
+	}
+
+	var mu sync.Mutex
+	var jobs []*queue.Job
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 3; j++ {
+				job, err := driver.Pop(ctx, "default")
+				if !assert.NoError(t, err) {
+					return
+				}
+				mu.Lock()
+				jobs = append(jobs, job)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, jobs, 12)
+	assert.Equal(t, 2, fake.requestCount("ReceiveMessage"))
+
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job *queue.Job) {
+			defer wg.Done()
+			assert.NoError(t, driver.Ack(ctx, job))
+		}(job)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 0, fake.messageCount("default"))
+	assert.LessOrEqual(t, fake.requestCount("DeleteMessageBatch"), 12)
+	assert.GreaterOrEqual(t, fake.requestCount("DeleteMessageBatch"), 2)
+}
+
+func TestSQSDriver_PopQueues(t *testing.T) {
+	driver, _ := newTestDriver(t, config.SQSConfig{Prefix: "prefix"}, "high", "low")
+	ctx := context.Background()
+
+	// Empty queues end the long poll like Pop
+	_, err := driver.PopQueues(ctx, []string{"high", "low"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, driver.Push(ctx, "low", []byte("low")))
+	job, err := driver.PopQueues(ctx, []string{"high", "low"})
+	require.NoError(t, err)
+	assert.Equal(t, "low", job.Queue)
+	driver.receives.Wait()
+
+	// Received messages of earlier queues are popped first
+	for i := 0; i < 2; i++ {
+		require.NoError(t, driver.Push(ctx, "low", []byte("low")))
+		require.NoError(t, driver.Push(ctx, "high", []byte("high")))
+	}
+	_, err = driver.Pop(ctx, "low")
+	require.NoError(t, err)
+	_, err = driver.Pop(ctx, "high")
+	require.NoError(t, err)
+
+	var popped []string
+	for i := 0; i < 2; i++ {
+		job, err := driver.PopQueues(ctx, []string{"high", "low"})
+		require.NoError(t, err)
+		popped = append(popped, string(job.Body))
+	}
+	assert.Equal(t, []string{"high", "low"}, popped)
+}
+
+func TestSQSDriver_CloseReturnsBufferedMessages(t *testing.T) {
+	driver, fake := newTestDriver(t, config.SQSConfig{Prefix: "prefix"}, "default")
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, driver.Push(ctx, "default", []byte(strconv.Itoa(i))))
+	}
+
+	job, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+
+	// The messages received with the popped one are visible again
+	require.NoError(t, driver.Close())
+	assert.Equal(t, 1, fake.requestCount("ChangeMessageVisibilityBatch"))
+	for _, msg := range fake.messages("default") {
+		if msg.receiptHandle == job.ID {
+			assert.True(t, msg.visibleAt.After(time.Now()))
+		} else {
+			assert.False(t, msg.visibleAt.After(time.Now()))
+		}
+	}
+}
+
+func TestSQSDriver_AckReportsFailedDeletes(t *testing.T) {
+	driver, _ := newTestDriver(t, config.SQSConfig{Prefix: "prefix"}, "default")
+
+	err := driver.Ack(context.Background(), &queue.Job{ID: "unknown", Queue: "default"})
+	assert.ErrorContains(t, err, "ReceiptHandleIsInvalid")
+}
+
+func TestSQSDriver_Release(t *testing.T) {
+	driver, fake := newTestDriver(t, config.SQSConfig{Prefix: "prefix"}, "default")
+	ctx := context.Background()
+
+	require.NoError(t, driver.Push(ctx, "default", []byte(`{"uuid":"a"}`)))
+	job, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+
+	require.NoError(t, driver.Release(ctx, job, 0))
+	job, err = driver.Pop(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Equal(t, 1, fake.requestCount("ChangeMessageVisibility"))
+}
+
+func TestSQSDriver_ExtendLease(t *testing.T) {
+	driver, fake := newTestDriver(t, config.SQSConfig{Prefix: "prefix"}, "default")
+	ctx := context.Background()
+
+	require.NoError(t, driver.Push(ctx, "default", []byte(`{"uuid":"a"}`)))
+	job, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+
+	require.NoError(t, driver.ExtendLease(ctx, job, 10*time.Minute))
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), fake.messages("default")[0].visibleAt, 2*time.Second)
+}
+
+func TestSQSDriver_LaterBeyondSQSLimit(t *testing.T) {
+	driver, fake := newTestDriver(t, config.SQSConfig{Prefix: "prefix"}, "default")
+	ctx := context.Background()
+
+	require.NoError(t, driver.Later(ctx, "default", []byte(`{"uuid":"a"}`), time.Hour))
+
+	messages := fake.messages("default")
+	require.Len(t, messages, 1)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), messages[0].visibleAt, 2*time.Second)
+	assert.Equal(t, strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10), messages[0].availableAt)
+
+	// SQS makes the message visible after 15 minutes, the driver hides it again
+	fake.elapse(15 * time.Minute)
+	_, err := driver.Pop(ctx, "default")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.WithinDuration(t, time.Now().Add(45*time.Minute), fake.messages("default")[0].visibleAt, 2*time.Second)
+
+	fake.elapse(45 * time.Minute)
+	job, err := driver.Pop(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, `{"uuid":"a"}`, string(job.Body))
+	assert.Equal(t, 1, job.Attempts, "the receive that deferred the job is not an attempt")
+}
+
+func TestSQSDriver_FIFOQueues(t *testing.T) {
+	driver, fake := newTestDriver(t, config.SQSConfig{Prefix: "prefix"}, "orders.fifo")
+	ctx := context.Background()
+
+	require.NoError(t, driver.Push(ctx, "orders.fifo", []byte(`{"uuid":"a","data":{}}`)))
+
+	publisher := queue.NewPublisher(driver)
+	require.NoError(t, publisher.Dispatch(ctx, "App\\Jobs\\ShipOrder", map[string]interface{}{"orderId": 1},
+		queue.OnQueue("orders.fifo"), queue.MessageGroup("customer-7"), queue.DeduplicationID("order-1")))
+
+	messages := fake.messages("orders.fifo")
+	require.Len(t, messages, 2)
+	assert.Equal(t, "default", messages[0].groupID)
+	assert.Equal(t, "a", messages[0].deduplicationID)
+	assert.Empty(t, messages[0].availableAt)
+
+	assert.Equal(t, "customer-7", messages[1].groupID)
+	assert.Equal(t, "order-1", messages[1].deduplicationID)
+
+	driver.MessageGroupID = func(body []byte) string { return "custom" }
+	require.NoError(t, driver.Push(ctx, "orders.fifo", []byte(`{"uuid":"b"}`)))
+	assert.Equal(t, "custom", fake.messages("orders.fifo")[2].groupID)
+}
+
+func TestSQSDriver_FIFOQueuesRejectDelays(t *testing.T) {
+	driver, fake := newTestDriver(t, config.SQSConfig{Prefix: "prefix"}, "orders.fifo")
+	ctx := context.Background()
+	publisher := queue.NewPublisher(driver)
+
+	// A delayed job would hold back the rest of its message group
+	err := publisher.Dispatch(ctx, "App\\Jobs\\ShipOrder", map[string]interface{}{"orderId": 1},
+		queue.OnQueue("orders.fifo"), queue.MessageGroup("customer-7"), queue.Delay(time.Minute))
+	assert.ErrorContains(t, err, "cannot delay jobs on FIFO queue")
+	assert.Empty(t, fake.messages("orders.fifo"))
+
+	// The next job of the group is received right away
+	require.NoError(t, publisher.Dispatch(ctx, "App\\Jobs\\ShipOrder", map[string]interface{}{"orderId": 2},
+		queue.OnQueue("orders.fifo"), queue.MessageGroup("customer-7")))
+
+	job, err := driver.Pop(ctx, "orders.fifo")
+	require.NoError(t, err)
+	assert.Contains(t, string(job.Body), `orderId\";i:2;`)
+}
@@ -0,0 +1,58 @@
+package syncdriver
+
+import (
+	"context"
+	"time"
+
+	"github.com/pixelvide/laravel-go/pkg/queue"
+	"github.com/pixelvide/laravel-go/pkg/worker"
+)
+
+// SyncDriver implements queue.Driver by running each job as soon as it is
+// pushed, like Laravel's SyncQueue. Push returns the job's error, so
+// dispatching behaves like dispatchSync(). Jobs are attempted once: failed
+// jobs are not retried and released jobs are dropped.
+//
+// Jobs run through Worker, so unique locks, chains, batches and failed jobs
+// are handled like in queue:work. Nothing is ever queued, so Pop blocks
+// until its context is done.
+type SyncDriver struct {
+	// Worker runs the pushed jobs. Set its FailedProvider, Encrypter, Locks
+	// or Batches to handle jobs like queue:work does.
+	Worker *worker.Worker
+}
+
+// NewSyncDriver creates a new sync driver, whose worker records failed jobs
+// under the "sync" connection
+func NewSyncDriver() *SyncDriver {
+	s := &SyncDriver{}
+	s.Worker = worker.NewWorker(s, nil, "default", 1, "", nil)
+	s.Worker.Connection = "sync"
+	return s
+}
+
+// Pop waits for ctx to be done, as the sync queue never holds jobs
+func (s *SyncDriver) Pop(ctx context.Context, queueName string) (*queue.Job, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// Push runs the job and returns the error it failed with
+func (s *SyncDriver) Push(ctx context.Context, queueName string, body []byte) error {
+	return s.Worker.Process(ctx, &queue.Job{Queue: queueName, Body: body})
+}
+
+// Later runs the job right away, like SyncQueue::later
+func (s *SyncDriver) Later(ctx context.Context, queueName string, body []byte, delay time.Duration) error {
+	return s.Push(ctx, queueName, body)
+}
+
+// Ack does nothing, as jobs are done once Push returns
+func (s *SyncDriver) Ack(ctx context.Context, job *queue.Job) error {
+	return nil
+}
+
+// Release drops the job, like SyncJob::release
+func (s *SyncDriver) Release(ctx context.Context, job *queue.Job, delay time.Duration) error {
+	return nil
+}
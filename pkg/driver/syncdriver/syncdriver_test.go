@@ -0,0 +1,86 @@
+package syncdriver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pixelvide/laravel-go/pkg/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingFailedProvider records logged failed jobs
+type recordingFailedProvider struct {
+	queue.FailedJobProvider
+	connections []string
+	exceptions  []string
+}
+
+func (r *recordingFailedProvider) Log(ctx context.Context, connection string, queueName string, payload []byte, exception string) error {
+	r.connections = append(r.connections, connection)
+	r.exceptions = append(r.exceptions, exception)
+	return nil
+}
+
+func TestSyncDriver_RunsJobsWhenPushed(t *testing.T) {
+	var handled []*queue.Job
+	queue.Register("App\\Jobs\\SyncPodcast", func(ctx context.Context, job *queue.Job) error {
+		handled = append(handled, job)
+		return nil
+	})
+
+	publisher := queue.NewPublisher(NewSyncDriver())
+	ctx := context.Background()
+
+	require.NoError(t, publisher.Dispatch(ctx, "App\\Jobs\\SyncPodcast", map[string]any{"podcastId": 7}, queue.OnQueue("podcasts")))
+	require.NoError(t, publisher.Dispatch(ctx, "App\\Jobs\\SyncPodcast", map[string]any{"podcastId": 8}, queue.Delay(time.Hour)))
+
+	require.Len(t, handled, 2)
+	assert.Equal(t, "podcasts", handled[0].Queue)
+	assert.Equal(t, 1, handled[0].Attempts)
+	assert.EqualValues(t, 7, handled[0].GetArg("podcastId"))
+	assert.EqualValues(t, 8, handled[1].GetArg("podcastId"))
+}
+
+func TestSyncDriver_ReturnsAndLogsFailures(t *testing.T) {
+	queue.Register("App\\Jobs\\SyncFailing", func(ctx context.Context, job *queue.Job) error {
+		return errors.New("boom")
+	})
+	queue.Register("App\\Jobs\\SyncReleasing", func(ctx context.Context, job *queue.Job) error {
+		return queue.ReleaseAfter(time.Minute)
+	})
+
+	driver := NewSyncDriver()
+	failed := &recordingFailedProvider{}
+	driver.Worker.FailedProvider = failed
+	publisher := queue.NewPublisher(driver)
+	ctx := context.Background()
+
+	err := publisher.Dispatch(ctx, "App\\Jobs\\SyncFailing", nil)
+	assert.EqualError(t, err, "boom")
+	require.Len(t, failed.exceptions, 1)
+	assert.Equal(t, []string{"sync"}, failed.connections)
+	assert.Contains(t, failed.exceptions[0], "boom")
+
+	assert.NoError(t, publisher.Dispatch(ctx, "App\\Jobs\\SyncReleasing", nil))
+	assert.Len(t, failed.exceptions, 1)
+
+	// Jobs fail on their first error, even with tries left
+	err = driver.Push(ctx, "default", []byte(`{"uuid":"a","displayName":"App\\Jobs\\SyncFailing","maxTries":3}`))
+	assert.EqualError(t, err, "boom")
+	assert.Len(t, failed.exceptions, 2)
+
+	err = publisher.Dispatch(ctx, "App\\Jobs\\SyncUnregistered", nil)
+	assert.Error(t, err)
+	assert.Len(t, failed.exceptions, 3)
+}
+
+func TestSyncDriver_PopWaitsForContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := NewSyncDriver().Pop(ctx, "default")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
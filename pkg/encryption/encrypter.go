@@ -0,0 +1,343 @@
+// Package encryption implements Laravel's Illuminate\Encryption\Encrypter, so
+// values encrypted with encrypt() in PHP can be decrypted in Go and the other
+// way around. It is used for the commands of jobs implementing ShouldBeEncrypted.
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pixelvide/laravel-go/pkg/config"
+	"github.com/yvasiyarov/php_session_decoder/php_serialize"
+)
+
+var (
+	// ErrInvalidPayload is returned for payloads that were not created by an Encrypter
+	ErrInvalidPayload = errors.New("encryption: the payload is invalid")
+
+	// ErrInvalidMAC is returned when no key matches the payload's MAC
+	ErrInvalidMAC = errors.New("encryption: the MAC is invalid")
+
+	// ErrDecrypt is returned when the payload cannot be decrypted with any key
+	ErrDecrypt = errors.New("encryption: could not decrypt the data")
+)
+
+// supportedCiphers maps Laravel's cipher names to their key size
+var supportedCiphers = map[string]int{
+	"aes-128-cbc": 16,
+	"aes-256-cbc": 32,
+	"aes-128-gcm": 16,
+	"aes-256-gcm": 32,
+}
+
+// Encrypter encrypts and decrypts values with AES-CBC and an HMAC-SHA256 MAC,
+// or with AES-GCM
+type Encrypter struct {
+	key          []byte
+	cipher       string
+	previousKeys [][]byte
+}
+
+// payload is the JSON structure Laravel base64 encodes as the encrypted value
+type payload struct {
+	IV    string `json:"iv"`
+	Value string `json:"value"`
+	MAC   string `json:"mac"`
+	Tag   string `json:"tag"`
+}
+
+// NewEncrypter creates an encrypter for one of the ciphers Laravel supports:
+// AES-128-CBC, AES-256-CBC (the default), AES-128-GCM or AES-256-GCM
+func NewEncrypter(key []byte, cipherName string) (*Encrypter, error) {
+	cipherName = strings.ToLower(cipherName)
+	if cipherName == "" {
+		cipherName = "aes-256-cbc"
+	}
+
+	size, ok := supportedCiphers[cipherName]
+	if !ok {
+		return nil, fmt.Errorf("encryption: unsupported cipher %q", cipherName)
+	}
+	if len(key) != size {
+		return nil, fmt.Errorf("encryption: %s needs a %d byte key, got %d bytes", cipherName, size, len(key))
+	}
+	return &Encrypter{key: key, cipher: cipherName}, nil
+}
+
+// NewFromConfig creates an encrypter from APP_KEY, APP_CIPHER and APP_PREVIOUS_KEYS
+func NewFromConfig(cfg config.AppConfig) (*Encrypter, error) {
+	if cfg.Key == "" {
+		return nil, errors.New("encryption: no application encryption key has been specified")
+	}
+
+	key, err := ParseKey(cfg.Key)
+	if err != nil {
+		return nil, err
+	}
+	encrypter, err := NewEncrypter(key, cfg.Cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, previous := range cfg.PreviousKeys {
+		if previous = strings.TrimSpace(previous); previous == "" {
+			continue
+		}
+		key, err := ParseKey(previous)
+		if err != nil {
+			return nil, err
+		}
+		if err := encrypter.AddPreviousKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return encrypter, nil
+}
+
+// ParseKey decodes an APP_KEY value, which is base64 encoded when it starts with "base64:"
+func ParseKey(key string) ([]byte, error) {
+	encoded, ok := strings.CutPrefix(key, "base64:")
+	if !ok {
+		return []byte(key), nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: invalid base64 key: %w", err)
+	}
+	return decoded, nil
+}
+
+// AddPreviousKey adds a key that values encrypted before a key rotation can
+// still be decrypted with
+func (e *Encrypter) AddPreviousKey(key []byte) error {
+	if len(key) != supportedCiphers[e.cipher] {
+		return fmt.Errorf("encryption: %s needs a %d byte key, got %d bytes", e.cipher, supportedCiphers[e.cipher], len(key))
+	}
+	e.previousKeys = append(e.previousKeys, key)
+	return nil
+}
+
+// Encrypt encrypts a PHP serialized string, like encrypt($value) does for strings
+func (e *Encrypter) Encrypt(value string) (string, error) {
+	serialized, err := php_serialize.NewSerializer().Encode(value)
+	if err != nil {
+		return "", err
+	}
+	return e.EncryptString(serialized)
+}
+
+// Decrypt decrypts a value encrypted with encrypt($value) and unserializes it,
+// which must give a string
+func (e *Encrypter) Decrypt(encrypted string) (string, error) {
+	decrypted, err := e.DecryptString(encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := php_serialize.UnSerialize(decrypted)
+	if err != nil {
+		return "", fmt.Errorf("encryption: cannot unserialize the decrypted value: %w", err)
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case nil:
+		// The decoder returns empty strings as null
+		return "", nil
+	default:
+		return "", fmt.Errorf("encryption: the decrypted value is a %T, not a string", value)
+	}
+}
+
+// EncryptString encrypts a value without serializing it, like encryptString
+func (e *Encrypter) EncryptString(value string) (string, error) {
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		return "", err
+	}
+
+	var p payload
+	if e.isAEAD() {
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return "", err
+		}
+		iv := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(iv); err != nil {
+			return "", err
+		}
+
+		sealed := gcm.Seal(nil, iv, []byte(value), nil)
+		ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+		p = payload{
+			IV:    base64.StdEncoding.EncodeToString(iv),
+			Value: base64.StdEncoding.EncodeToString(ciphertext),
+			Tag:   base64.StdEncoding.EncodeToString(tag),
+		}
+	} else {
+		iv := make([]byte, aes.BlockSize)
+		if _, err := rand.Read(iv); err != nil {
+			return "", err
+		}
+
+		plaintext := pad([]byte(value), aes.BlockSize)
+		ciphertext := make([]byte, len(plaintext))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+		p = payload{
+			IV:    base64.StdEncoding.EncodeToString(iv),
+			Value: base64.StdEncoding.EncodeToString(ciphertext),
+		}
+		p.MAC = mac(p.IV, p.Value, e.key)
+	}
+
+	encoded, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encoded), nil
+}
+
+// DecryptString decrypts a value without unserializing it, like decryptString.
+// The current key is tried first, then the previous keys.
+func (e *Encrypter) DecryptString(encrypted string) (string, error) {
+	p, iv, err := e.parsePayload(encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(p.Value)
+	if err != nil {
+		return "", ErrInvalidPayload
+	}
+
+	foundValidMAC := false
+	for _, key := range e.keys() {
+		if !e.isAEAD() {
+			if !hmac.Equal([]byte(mac(p.IV, p.Value, key)), []byte(p.MAC)) {
+				continue
+			}
+			foundValidMAC = true
+		}
+
+		if decrypted, err := e.decrypt(key, iv, ciphertext, p.Tag); err == nil {
+			return string(decrypted), nil
+		}
+	}
+
+	if !e.isAEAD() && !foundValidMAC {
+		return "", ErrInvalidMAC
+	}
+	return "", ErrDecrypt
+}
+
+func (e *Encrypter) decrypt(key, iv, ciphertext []byte, encodedTag string) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.isAEAD() {
+		tag, err := base64.StdEncoding.DecodeString(encodedTag)
+		if err != nil || len(tag) != 16 {
+			return nil, ErrDecrypt
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		return gcm.Open(nil, iv, append(bytes.Clone(ciphertext), tag...), nil)
+	}
+
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, ErrDecrypt
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	return unpad(plaintext, aes.BlockSize)
+}
+
+// parsePayload decodes a payload and checks it has the fields and IV length Laravel requires
+func (e *Encrypter) parsePayload(encrypted string) (payload, []byte, error) {
+	var p payload
+
+	decoded, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return p, nil, ErrInvalidPayload
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(decoded, &fields); err != nil {
+		return p, nil, ErrInvalidPayload
+	}
+	for _, name := range []string{"iv", "value", "mac"} {
+		if _, ok := fields[name].(string); !ok {
+			return p, nil, ErrInvalidPayload
+		}
+	}
+	if tag, ok := fields["tag"]; ok && tag != nil {
+		if _, ok := tag.(string); !ok {
+			return p, nil, ErrInvalidPayload
+		}
+	}
+	if err := json.Unmarshal(decoded, &p); err != nil {
+		return p, nil, ErrInvalidPayload
+	}
+
+	iv, err := base64.StdEncoding.DecodeString(p.IV)
+	if err != nil || len(iv) != e.ivLength() {
+		return p, nil, ErrInvalidPayload
+	}
+	return p, iv, nil
+}
+
+func (e *Encrypter) keys() [][]byte {
+	return append([][]byte{e.key}, e.previousKeys...)
+}
+
+func (e *Encrypter) isAEAD() bool {
+	return strings.HasSuffix(e.cipher, "-gcm")
+}
+
+func (e *Encrypter) ivLength() int {
+	if e.isAEAD() {
+		return 12
+	}
+	return aes.BlockSize
+}
+
+// mac computes the MAC of a CBC payload from its base64 encoded IV and value
+func mac(iv, value string, key []byte) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(iv + value))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// pad applies PKCS#7 padding, as OpenSSL does
+func pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	return append(data, bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+func unpad(data []byte, blockSize int) ([]byte, error) {
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize || n > len(data) {
+		return nil, ErrDecrypt
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, ErrDecrypt
+		}
+	}
+	return data[:len(data)-n], nil
+}
@@ -0,0 +1,102 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/pixelvide/laravel-go/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = []byte("abcdefghijklmnopqrstuvwxyz123456")
+
+// laravelPayload is encrypt("hello") with testKey and the IV "0123456789abcdef"
+const laravelPayload = "eyJpdiI6Ik1ERXlNelExTmpjNE9XRmlZMlJsWmc9PSIsInZhbHVlIjoiK1c2czN6VW5jWlFnRnRqYXZxMHdQdz09IiwibWFjIjoiMWM5N2E0OWU3ZmJkZDA5NjA4YTRiODQ4Mzc3NDU1ZWVmOGI2NzAzN2QwYWQyMmI1NDZkMTRiMDc5YzAxOWVhNyIsInRhZyI6IiJ9"
+
+func TestEncrypter_DecryptsLaravelPayload(t *testing.T) {
+	encrypter, err := NewEncrypter(testKey, "AES-256-CBC")
+	require.NoError(t, err)
+
+	value, err := encrypter.Decrypt(laravelPayload)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", value)
+
+	raw, err := encrypter.DecryptString(laravelPayload)
+	require.NoError(t, err)
+	assert.Equal(t, `s:5:"hello";`, raw)
+}
+
+func TestEncrypter_RoundTrip(t *testing.T) {
+	for _, cipherName := range []string{"AES-128-CBC", "AES-256-CBC", "AES-128-GCM", "AES-256-GCM"} {
+		t.Run(cipherName, func(t *testing.T) {
+			key := testKey
+			if cipherName[4:7] == "128" {
+				key = testKey[:16]
+			}
+			encrypter, err := NewEncrypter(key, cipherName)
+			require.NoError(t, err)
+
+			encrypted, err := encrypter.Encrypt(`O:8:"App\User":0:{}`)
+			require.NoError(t, err)
+
+			decrypted, err := encrypter.Decrypt(encrypted)
+			require.NoError(t, err)
+			assert.Equal(t, `O:8:"App\User":0:{}`, decrypted)
+		})
+	}
+}
+
+func TestEncrypter_RejectsTamperedPayloads(t *testing.T) {
+	encrypter, err := NewEncrypter(testKey, "")
+	require.NoError(t, err)
+
+	other, err := NewEncrypter([]byte("0123456789abcdef0123456789abcdef"), "")
+	require.NoError(t, err)
+
+	_, err = other.Decrypt(laravelPayload)
+	assert.ErrorIs(t, err, ErrInvalidMAC)
+
+	_, err = encrypter.Decrypt("not a payload")
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+
+	_, err = encrypter.Decrypt(base64.StdEncoding.EncodeToString([]byte(`{"iv":"MDEy","value":"","mac":""}`)))
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+
+	gcm, err := NewEncrypter(testKey, "aes-256-gcm")
+	require.NoError(t, err)
+	encrypted, err := gcm.EncryptString("secret")
+	require.NoError(t, err)
+
+	rotated, err := NewEncrypter([]byte("0123456789abcdef0123456789abcdef"), "aes-256-gcm")
+	require.NoError(t, err)
+	_, err = rotated.DecryptString(encrypted)
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestEncrypter_PreviousKeys(t *testing.T) {
+	encrypter, err := NewFromConfig(config.AppConfig{
+		Key:          "base64:" + base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")),
+		Cipher:       "AES-256-CBC",
+		PreviousKeys: []string{string(testKey)},
+	})
+	require.NoError(t, err)
+
+	value, err := encrypter.Decrypt(laravelPayload)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", value)
+}
+
+func TestNewEncrypter_ValidatesKey(t *testing.T) {
+	_, err := NewEncrypter(testKey[:16], "AES-256-CBC")
+	assert.Error(t, err)
+
+	_, err = NewEncrypter(testKey, "des-cbc")
+	assert.Error(t, err)
+
+	_, err = NewFromConfig(config.AppConfig{})
+	assert.Error(t, err)
+
+	_, err = ParseKey("base64:not base64")
+	assert.Error(t, err)
+}
@@ -0,0 +1,112 @@
+package queue
+
+import (
+	"fmt"
+
+	"github.com/yvasiyarov/php_session_decoder/php_serialize"
+)
+
+// ChainLink is the next job of a chain, ready to be pushed
+type ChainLink struct {
+	Connection string // Connection to push onto; empty for the current one
+	Queue      string // Queue to push onto; empty for the connection's default queue
+	Body       []byte
+}
+
+// NextInChain returns the job to dispatch after job completes successfully,
+// like Queueable::dispatchNextJobInChain, or nil if job is not part of a chain
+// or is its last link. The next job inherits the rest of the chain along with
+// chainConnection, chainQueue and chainCatchCallbacks.
+func NextInChain(job *Job) (*ChainLink, error) {
+	command, ok := job.UnserializedData.(*php_serialize.PhpObject)
+	if !ok {
+		return nil, nil
+	}
+
+	chained, ok := orderLists(GetPHPProperty(command, "chained")).(php_serialize.PhpSlice)
+	if !ok || len(chained) == 0 {
+		return nil, nil
+	}
+
+	serialized, ok := chained[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid chained job %v", chained[0])
+	}
+	decoded, err := php_serialize.UnSerialize(serialized)
+	if err != nil {
+		return nil, fmt.Errorf("unserializing chained job: %w", err)
+	}
+	next, ok := decoded.(*php_serialize.PhpObject)
+	if !ok {
+		return nil, fmt.Errorf("chained job is not an object: %T", decoded)
+	}
+
+	chainConnection := GetPHPProperty(command, "chainConnection")
+	chainQueue := GetPHPProperty(command, "chainQueue")
+
+	link := &ChainLink{
+		Connection: firstString(GetPHPProperty(next, "connection"), chainConnection),
+		Queue:      firstString(GetPHPProperty(next, "queue"), chainQueue),
+	}
+
+	rest := make(php_serialize.PhpSlice, len(chained)-1)
+	copy(rest, chained[1:])
+
+	setProperty(next, "chained", rest)
+	setProperty(next, "connection", stringOrNil(link.Connection))
+	setProperty(next, "queue", stringOrNil(link.Queue))
+	setProperty(next, "chainConnection", chainConnection)
+	setProperty(next, "chainQueue", chainQueue)
+	setProperty(next, "chainCatchCallbacks", GetPHPProperty(command, "chainCatchCallbacks"))
+
+	link.Body, err = CommandPayload(next)
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// ChainCommands serializes the commands following the first link of a chain,
+// like Queueable::chain
+func ChainCommands(commands []*php_serialize.PhpObject) (php_serialize.PhpSlice, error) {
+	chained := make(php_serialize.PhpSlice, 0, len(commands))
+	for _, command := range commands {
+		serialized, err := SerializeCommand(command)
+		if err != nil {
+			return nil, err
+		}
+		chained = append(chained, serialized)
+	}
+	return chained, nil
+}
+
+// setProperty sets a property of a command, keeping its visibility if it
+// already exists. The Queueable properties are public.
+func setProperty(command *php_serialize.PhpObject, name string, value php_serialize.PhpValue) {
+	if _, ok := command.GetProtected(name); ok {
+		command.SetProtected(name, value)
+		return
+	}
+	if _, ok := command.GetPrivate(name); ok {
+		command.SetPrivate(name, value)
+		return
+	}
+	command.SetPublic(name, value)
+}
+
+// firstString returns the first non-empty string among values
+func firstString(values ...any) string {
+	for _, v := range values {
+		if s, ok := v.(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func stringOrNil(s string) php_serialize.PhpValue {
+	if s == "" {
+		return nil
+	}
+	return s
+}
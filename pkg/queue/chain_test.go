@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yvasiyarov/php_session_decoder/php_serialize"
+)
+
+// decodeCommand unserializes the command of a job payload like the worker does
+func decodeCommand(t *testing.T, body []byte) *Job {
+	t.Helper()
+
+	var payload LaravelJob
+	require.NoError(t, json.Unmarshal(body, &payload))
+	command, err := UnserializeCommand(payload.Data)
+	require.NoError(t, err)
+
+	return &Job{Body: body, Payload: &payload, UnserializedData: command}
+}
+
+func TestNextInChain(t *testing.T) {
+	chained, err := ChainCommands([]*php_serialize.PhpObject{
+		NewCommand("App\\Jobs\\Second", map[string]interface{}{"step": 2, "tries": 3}),
+		NewCommand("App\\Jobs\\Third", map[string]interface{}{"step": 3}),
+	})
+	require.NoError(t, err)
+
+	first := NewCommand("App\\Jobs\\First", map[string]interface{}{"step": 1})
+	first.SetPublic("chained", chained)
+	first.SetPublic("chainQueue", "chains")
+	body, err := CommandPayload(first)
+	require.NoError(t, err)
+
+	next, err := NextInChain(decodeCommand(t, body))
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, "", next.Connection)
+	assert.Equal(t, "chains", next.Queue)
+
+	second := decodeCommand(t, next.Body)
+	assert.Equal(t, "App\\Jobs\\Second", second.Payload.DisplayName)
+	assert.Equal(t, 3, *second.Payload.MaxTries)
+	assert.Equal(t, 2, second.GetArg("step"))
+	assert.Equal(t, "chains", second.GetArg("queue"))
+	assert.Equal(t, "chains", second.GetArg("chainQueue"))
+
+	last, err := NextInChain(second)
+	require.NoError(t, err)
+	require.NotNil(t, last)
+
+	third := decodeCommand(t, last.Body)
+	assert.Equal(t, "App\\Jobs\\Third", third.Payload.DisplayName)
+
+	end, err := NextInChain(third)
+	assert.NoError(t, err)
+	assert.Nil(t, end)
+}
+
+func TestNextInChain_LaravelCommand(t *testing.T) {
+	// A command serialized by PHP without a chain
+	data := json.RawMessage(`{"commandName":"App\\Jobs\\TestGoServiceJob","command":"O:25:\"App\\Jobs\\TestGoServiceJob\":3:{s:7:\"message\";s:5:\"Hello\";s:10:\"chainQueue\";N;s:7:\"chained\";a:0:{}}"}`)
+	command, err := UnserializeCommand(data)
+	require.NoError(t, err)
+
+	next, err := NextInChain(&Job{UnserializedData: command})
+	assert.NoError(t, err)
+	assert.Nil(t, next)
+}
+
+func TestSerializeCommand_KeepsListOrder(t *testing.T) {
+	command := NewCommand("App\\Jobs\\Sync", map[string]interface{}{
+		"ids": php_serialize.PhpArray{2: "c", 0: "a", 1: "b"},
+	})
+
+	serialized, err := SerializeCommand(command)
+	require.NoError(t, err)
+	assert.Equal(t, `O:13:"App\Jobs\Sync":1:{s:3:"ids";a:3:{i:0;s:1:"a";i:1;s:1:"b";i:2;s:1:"c";}}`, serialized)
+}
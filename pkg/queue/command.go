@@ -0,0 +1,157 @@
+package queue
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/yvasiyarov/php_session_decoder/php_serialize"
+)
+
+// callQueuedHandler is the job handler of every queued command
+const callQueuedHandler = "Illuminate\\Queue\\CallQueuedHandler@call"
+
+// NewCommand creates a PHP command object of the given class with args as its
+// public properties. ModelIdentifier args are stored as the objects
+// SerializesModels writes.
+func NewCommand(class string, args map[string]interface{}) *php_serialize.PhpObject {
+	command := php_serialize.NewPhpObject(class)
+	for key, value := range args {
+		if model, ok := value.(ModelIdentifier); ok {
+			value = model.Object()
+		}
+		command.SetPublic(key, value)
+	}
+	return command
+}
+
+// SerializeCommand serializes a PHP command like PHP's serialize(). Lists are
+// written in index order, so array_shift on them behaves as in PHP.
+func SerializeCommand(command *php_serialize.PhpObject) (string, error) {
+	return php_serialize.NewSerializer().Encode(orderLists(command))
+}
+
+// CommandPayload builds the JSON envelope Laravel's Queue::createObjectPayload
+// creates for a command, reading its tries, timeout, backoff, maxExceptions and
+// failOnTimeout properties
+func CommandPayload(command *php_serialize.PhpObject) ([]byte, error) {
+	return EncryptedCommandPayload(command, nil)
+}
+
+// EncryptedCommandPayload builds the payload of a command like CommandPayload,
+// encrypting the serialized command like a job implementing ShouldBeEncrypted.
+// A nil encrypter leaves the command unencrypted.
+func EncryptedCommandPayload(command *php_serialize.PhpObject, encrypter Encrypter) ([]byte, error) {
+	serialized, err := SerializeCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if encrypter != nil {
+		if serialized, err = encrypter.Encrypt(serialized); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"commandName": command.GetClassName(),
+		"command":     serialized,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	job := LaravelJob{
+		UUID:          uuid.New().String(),
+		DisplayName:   command.GetClassName(),
+		Job:           callQueuedHandler,
+		MaxTries:      intProperty(command, "tries"),
+		MaxExceptions: intProperty(command, "maxExceptions"),
+		Backoff:       backoffProperty(command),
+		Timeout:       intProperty(command, "timeout"),
+		Data:          data,
+	}
+	if v := GetPHPProperty(command, "failOnTimeout"); v != nil {
+		job.FailOnTimeout = php_serialize.PhpValueBool(v)
+	}
+
+	return json.Marshal(job)
+}
+
+// intProperty returns a numeric property of a command, or nil if it is not set
+func intProperty(command *php_serialize.PhpObject, name string) *int {
+	v := GetPHPProperty(command, name)
+	if v == nil {
+		return nil
+	}
+	n := php_serialize.PhpValueInt(v)
+	return &n
+}
+
+// backoffProperty reads the backoff property, which is a number or a list of numbers
+func backoffProperty(command *php_serialize.PhpObject) Backoff {
+	switch v := GetPHPProperty(command, "backoff").(type) {
+	case nil:
+		return nil
+	case php_serialize.PhpArray:
+		list := orderLists(v)
+		slice, ok := list.(php_serialize.PhpSlice)
+		if !ok {
+			return nil
+		}
+		backoff := make(Backoff, 0, len(slice))
+		for _, item := range slice {
+			backoff = append(backoff, php_serialize.PhpValueInt(item))
+		}
+		return backoff
+	default:
+		return Backoff{php_serialize.PhpValueInt(v)}
+	}
+}
+
+// orderLists converts arrays keyed 0..n-1 to slices, recursively, as the
+// serializer writes maps in random order
+func orderLists(v php_serialize.PhpValue) php_serialize.PhpValue {
+	switch t := v.(type) {
+	case *php_serialize.PhpObject:
+		members := make(php_serialize.PhpArray, len(t.GetMembers()))
+		for k, member := range t.GetMembers() {
+			members[k] = orderLists(member)
+		}
+		return php_serialize.NewPhpObject(t.GetClassName()).SetMembers(members)
+	case php_serialize.PhpArray:
+		keys := make([]int, 0, len(t))
+		for k := range t {
+			i, ok := k.(int)
+			if !ok {
+				keys = nil
+				break
+			}
+			keys = append(keys, i)
+		}
+		sort.Ints(keys)
+
+		isList := len(keys) == len(t)
+		for i, k := range keys {
+			if i != k {
+				isList = false
+				break
+			}
+		}
+
+		if isList && len(t) > 0 {
+			list := make(php_serialize.PhpSlice, len(t))
+			for i := range list {
+				list[i] = orderLists(t[i])
+			}
+			return list
+		}
+
+		array := make(php_serialize.PhpArray, len(t))
+		for k, item := range t {
+			array[k] = orderLists(item)
+		}
+		return array
+	default:
+		return v
+	}
+}
@@ -0,0 +1,416 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yvasiyarov/php_session_decoder/php_serialize"
+)
+
+// DecodeError reports a PHP value that cannot be stored in a Go value
+type DecodeError struct {
+	Path    string       // Path of the property, e.g. "podcast.tags[2]"
+	PHPType string       // PHP type of the value, e.g. "string"
+	GoType  reflect.Type // Type of the Go value
+}
+
+func (e *DecodeError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("queue: cannot decode PHP %s into Go value of type %s", e.PHPType, e.GoType)
+	}
+	return fmt.Sprintf("queue: cannot decode PHP %s into Go field %s of type %s", e.PHPType, e.Path, e.GoType)
+}
+
+// Decode stores the properties of the job's PHP command in the struct v points to.
+//
+// Properties are matched to fields by the name in the field's php tag, or else
+// by the field name, ignoring case. Public, protected and private properties
+// are all matched; fields tagged php:"-" are skipped. Nested objects decode
+// into structs or maps, arrays into slices, maps or structs, and Carbon dates
+// into time.Time. Numbers decode into time.Duration as seconds, and models of
+// jobs using SerializesModels into ModelIdentifier.
+func (j *Job) Decode(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("queue: Decode needs a non-nil pointer, got %T", v)
+	}
+	if j.UnserializedData == nil {
+		return errors.New("queue: job has no PHP command to decode")
+	}
+	return decodeValue(j.UnserializedData, rv.Elem(), "")
+}
+
+// Bind decodes the job's PHP command into a new T, see Job.Decode
+func Bind[T any](job *Job) (T, error) {
+	var v T
+	err := job.Decode(&v)
+	return v, err
+}
+
+// TypedHandler is a handler receiving the job's PHP command decoded into T
+type TypedHandler[T any] func(ctx context.Context, job *Job, command T) error
+
+// RegisterTyped adds a handler receiving the job's command decoded into T.
+// Jobs that cannot be decoded fail with a *DecodeError.
+func RegisterTyped[T any](name string, handler TypedHandler[T], opts ...HandlerOption) {
+	Register(name, func(ctx context.Context, job *Job) error {
+		command, err := Bind[T](job)
+		if err != nil {
+			return err
+		}
+		return handler(ctx, job, command)
+	}, opts...)
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	modelIdentifierType = reflect.TypeOf(ModelIdentifier{})
+)
+
+func decodeValue(src any, dst reflect.Value, path string) error {
+	if src == nil {
+		dst.SetZero()
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Pointer:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return decodeValue(src, dst.Elem(), path)
+	case reflect.Interface:
+		if dst.NumMethod() == 0 {
+			dst.Set(reflect.ValueOf(src))
+			return nil
+		}
+	}
+
+	mismatch := &DecodeError{Path: path, PHPType: phpType(src), GoType: dst.Type()}
+
+	switch s := src.(type) {
+	case string:
+		if dst.Kind() != reflect.String {
+			return mismatch
+		}
+		dst.SetString(s)
+	case bool:
+		if dst.Kind() != reflect.Bool {
+			return mismatch
+		}
+		dst.SetBool(s)
+	case int:
+		if dst.Type() == durationType {
+			// Delays and timeouts are seconds in PHP
+			dst.SetInt(int64(time.Duration(s) * time.Second))
+			return nil
+		}
+		return decodeInt(int64(s), dst, mismatch)
+	case float64:
+		return decodeFloat(s, dst, mismatch)
+	case time.Time:
+		// Scanned by database/sql, see ModelIdentifier.Load
+		if dst.Type() != timeType {
+			return mismatch
+		}
+		dst.Set(reflect.ValueOf(s))
+	case php_serialize.PhpArray:
+		return decodeArray(s, dst, path, mismatch)
+	case map[string]interface{}, []interface{}:
+		// Jobs without a PHP command expose their JSON data
+		return decodeValue(fromJSON(s), dst, path)
+	case *php_serialize.PhpObject:
+		if dst.Type() == timeType {
+			return decodeCarbon(s, dst, mismatch)
+		}
+		if dst.Type() == modelIdentifierType {
+			model, ok := AsModelIdentifier(s)
+			if !ok {
+				return mismatch
+			}
+			dst.Set(reflect.ValueOf(model))
+			return nil
+		}
+		return decodeMembers(s.GetMembers(), dst, path, mismatch)
+	default:
+		return mismatch
+	}
+	return nil
+}
+
+func decodeInt(n int64, dst reflect.Value, mismatch *DecodeError) error {
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if dst.OverflowInt(n) {
+			return mismatch
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n < 0 || dst.OverflowUint(uint64(n)) {
+			return mismatch
+		}
+		dst.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		dst.SetFloat(float64(n))
+	default:
+		return mismatch
+	}
+	return nil
+}
+
+func decodeFloat(f float64, dst reflect.Value, mismatch *DecodeError) error {
+	switch dst.Kind() {
+	case reflect.Float32, reflect.Float64:
+		dst.SetFloat(f)
+		return nil
+	}
+	if f == math.Trunc(f) && f >= math.MinInt64 && f <= math.MaxInt64 {
+		return decodeInt(int64(f), dst, mismatch)
+	}
+	return mismatch
+}
+
+// decodeArray decodes a PHP array into a slice, an array, a map or a struct
+func decodeArray(src php_serialize.PhpArray, dst reflect.Value, path string, mismatch *DecodeError) error {
+	switch dst.Kind() {
+	case reflect.Slice, reflect.Array:
+		keys, ok := listKeys(src)
+		if !ok {
+			return mismatch
+		}
+		if dst.Kind() == reflect.Slice {
+			dst.Set(reflect.MakeSlice(dst.Type(), len(keys), len(keys)))
+		} else if dst.Len() < len(keys) {
+			return mismatch
+		}
+		for i, k := range keys {
+			if err := decodeValue(src[k], dst.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return decodeMembers(src, dst, path, mismatch)
+	}
+}
+
+// decodeMembers decodes the members of an object or an associative array into a map or a struct
+func decodeMembers(src php_serialize.PhpArray, dst reflect.Value, path string, mismatch *DecodeError) error {
+	switch dst.Kind() {
+	case reflect.Map:
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(dst.Type(), len(src)))
+		}
+		for k, v := range src {
+			key := reflect.New(dst.Type().Key()).Elem()
+			if err := decodeKey(k, key); err != nil {
+				return &DecodeError{Path: path, PHPType: "array key " + phpType(k), GoType: key.Type()}
+			}
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := decodeValue(v, elem, joinPath(path, fmt.Sprint(propertyName(k)))); err != nil {
+				return err
+			}
+			dst.SetMapIndex(key, elem)
+		}
+		return nil
+	case reflect.Struct:
+		fields := structFields(dst.Type())
+		for k, v := range src {
+			name, ok := propertyName(k).(string)
+			if !ok {
+				continue
+			}
+			index, ok := fields[strings.ToLower(name)]
+			if !ok {
+				continue
+			}
+			field, ok := allocFieldByIndex(dst, index)
+			if !ok {
+				continue
+			}
+			if err := decodeValue(v, field, joinPath(path, name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return mismatch
+	}
+}
+
+// allocFieldByIndex returns the nested field at index like FieldByIndex, but
+// allocates the nil embedded pointers on the way. It reports false when such a
+// pointer cannot be set, as for an embedded pointer to an unexported type.
+func allocFieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// decodeKey stores a PHP array key in a string or integer map key
+func decodeKey(k any, dst reflect.Value) error {
+	k = propertyName(k)
+	if dst.Kind() == reflect.String {
+		dst.SetString(fmt.Sprint(k))
+		return nil
+	}
+	if s, ok := k.(string); ok {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		k = n
+	}
+	return decodeValue(k, dst, "")
+}
+
+// decodeCarbon decodes a serialized Carbon or DateTime object
+func decodeCarbon(src *php_serialize.PhpObject, dst reflect.Value, mismatch *DecodeError) error {
+	date, ok := GetPHPProperty(src, "date").(string)
+	if !ok {
+		return mismatch
+	}
+
+	loc := time.UTC
+	if tz, ok := GetPHPProperty(src, "timezone").(string); ok {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		} else if offset, err := time.Parse("-07:00", tz); err == nil {
+			_, seconds := offset.Zone()
+			loc = time.FixedZone(tz, seconds)
+		}
+	}
+
+	t, err := time.ParseInLocation("2006-01-02 15:04:05.000000", date, loc)
+	if err != nil {
+		return mismatch
+	}
+	dst.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// structFields maps the lower cased property names of a struct's fields to their index
+func structFields(t reflect.Type) map[string][]int {
+	fields := make(map[string][]int)
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name, _ := parseTag(f)
+		if name == "-" {
+			continue
+		}
+		key := strings.ToLower(name)
+		if _, exists := fields[key]; !exists || f.Tag.Get("php") != "" {
+			fields[key] = f.Index
+		}
+	}
+	return fields
+}
+
+// parseTag returns the property name of a field and the options of its php tag
+func parseTag(f reflect.StructField) (string, []string) {
+	tag := f.Tag.Get("php")
+	if tag == "" {
+		return f.Name, nil
+	}
+	parts := strings.Split(tag, ",")
+	if parts[0] == "" {
+		parts[0] = f.Name
+	}
+	return parts[0], parts[1:]
+}
+
+// propertyName strips the visibility prefix of protected ("\0*\0name") and
+// private ("\0Class\0name") property names
+func propertyName(k any) any {
+	s, ok := k.(string)
+	if !ok || !strings.HasPrefix(s, "\x00") {
+		return k
+	}
+	if i := strings.LastIndex(s, "\x00"); i > 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// listKeys returns the keys of a PHP list in order, or false if the array is associative
+func listKeys(a php_serialize.PhpArray) ([]int, bool) {
+	keys := make([]int, 0, len(a))
+	for k := range a {
+		i, ok := k.(int)
+		if !ok {
+			return nil, false
+		}
+		keys = append(keys, i)
+	}
+	sort.Ints(keys)
+	return keys, true
+}
+
+// fromJSON converts decoded JSON objects and arrays to PHP arrays
+func fromJSON(v any) any {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		array := make(php_serialize.PhpArray, len(t))
+		for k, item := range t {
+			array[k] = fromJSON(item)
+		}
+		return array
+	case []interface{}:
+		array := make(php_serialize.PhpArray, len(t))
+		for i, item := range t {
+			array[i] = fromJSON(item)
+		}
+		return array
+	default:
+		return v
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// phpType names the PHP type of an unserialized value
+func phpType(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case int:
+		return "int"
+	case float64:
+		return "float"
+	case php_serialize.PhpArray:
+		return "array"
+	case *php_serialize.PhpObject:
+		return "object " + t.GetClassName()
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yvasiyarov/php_session_decoder/php_serialize"
+)
+
+type podcastCommand struct {
+	PodcastID int               `php:"podcastId"`
+	Title     string            // Matched by name, ignoring case
+	Secret    string            `php:"secret"`
+	Tags      []string          `php:"tags"`
+	Meta      map[string]string `php:"meta"`
+	Owner     *owner            `php:"owner"`
+	Rating    float64           `php:"rating"`
+	PublishAt time.Time         `php:"publishAt"`
+	Queue     string            `php:"-"`
+}
+
+type owner struct {
+	Name  string `php:"name"`
+	Admin bool   `php:"admin"`
+}
+
+func unserializedJob(t *testing.T, command string) *Job {
+	t.Helper()
+	decoded, err := php_serialize.UnSerialize(command)
+	require.NoError(t, err)
+	return &Job{UnserializedData: decoded}
+}
+
+func TestJob_Decode(t *testing.T) {
+	job := unserializedJob(t, `O:23:"App\Jobs\ProcessPodcast":9:{`+
+		`s:9:"podcastId";i:42;`+
+		`s:5:"title";s:12:"Go & Laravel";`+
+		"s:9:\"\x00*\x00secret\";s:3:\"abc\";"+
+		`s:4:"tags";a:2:{i:1;s:2:"go";i:0;s:3:"php";}`+
+		`s:4:"meta";a:1:{s:4:"lang";s:2:"en";}`+
+		`s:5:"owner";O:8:"App\User":2:{s:4:"name";s:3:"Ada";s:5:"admin";b:1;}`+
+		`s:6:"rating";i:5;`+
+		`s:9:"publishAt";O:13:"Carbon\Carbon":3:{s:4:"date";s:26:"2024-05-01 12:30:00.000000";s:13:"timezone_type";i:3;s:8:"timezone";s:3:"UTC";}`+
+		`s:5:"queue";s:7:"podcast";}`)
+
+	var cmd podcastCommand
+	require.NoError(t, job.Decode(&cmd))
+
+	assert.Equal(t, podcastCommand{
+		PodcastID: 42,
+		Title:     "Go & Laravel",
+		Secret:    "abc",
+		Tags:      []string{"php", "go"},
+		Meta:      map[string]string{"lang": "en"},
+		Owner:     &owner{Name: "Ada", Admin: true},
+		Rating:    5,
+		PublishAt: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+	}, cmd)
+}
+
+func TestJob_DecodeTypeMismatch(t *testing.T) {
+	job := unserializedJob(t, `O:23:"App\Jobs\ProcessPodcast":1:{s:5:"owner";O:8:"App\User":1:{s:4:"name";i:7;}}`)
+
+	_, err := Bind[podcastCommand](job)
+
+	var decodeErr *DecodeError
+	require.True(t, errors.As(err, &decodeErr))
+	assert.Equal(t, "owner.name", decodeErr.Path)
+	assert.EqualError(t, err, "queue: cannot decode PHP int into Go field owner.name of type string")
+}
+
+func TestJob_DecodeRejectsOverflow(t *testing.T) {
+	job := unserializedJob(t, `O:8:"App\Jobs":1:{s:5:"count";i:300;}`)
+
+	var cmd struct {
+		Count uint8
+	}
+	assert.Error(t, job.Decode(&cmd))
+}
+
+func TestJob_DecodeEmbeddedPointer(t *testing.T) {
+	job := unserializedJob(t, `O:20:"App\Jobs\SendInvoice":2:{s:9:"invoiceId";i:7;s:5:"queue";s:8:"invoices";}`)
+
+	var cmd struct {
+		*Queueable
+		InvoiceID int `php:"invoiceId"`
+	}
+	require.NoError(t, job.Decode(&cmd))
+
+	assert.Equal(t, 7, cmd.InvoiceID)
+	require.NotNil(t, cmd.Queueable)
+	require.NotNil(t, cmd.Queue)
+	assert.Equal(t, "invoices", *cmd.Queue)
+}
+
+func TestRegisterTyped(t *testing.T) {
+	var got podcastCommand
+	RegisterTyped("App\\Jobs\\TypedPodcast", func(ctx context.Context, job *Job, cmd podcastCommand) error {
+		got = cmd
+		return nil
+	})
+
+	handler, err := GetHandler("App\\Jobs\\TypedPodcast")
+	require.NoError(t, err)
+
+	job := unserializedJob(t, `O:21:"App\Jobs\TypedPodcast":1:{s:9:"podcastId";i:7;}`)
+	require.NoError(t, handler(context.Background(), job))
+	assert.Equal(t, 7, got.PodcastID)
+
+	job = unserializedJob(t, `O:21:"App\Jobs\TypedPodcast":1:{s:9:"podcastId";s:1:"x";}`)
+	var decodeErr *DecodeError
+	assert.ErrorAs(t, handler(context.Background(), job), &decodeErr)
+}
@@ -0,0 +1,237 @@
+package queue
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yvasiyarov/php_session_decoder/php_serialize"
+)
+
+// PHPObject is implemented by structs that are serialized as PHP objects
+// rather than associative arrays, returning their PHP class name
+type PHPObject interface {
+	PHPClass() string
+}
+
+// carbonClass is the class Laravel uses for dates
+const carbonClass = "Illuminate\\Support\\Carbon"
+
+// Queueable holds the properties PHP's Queueable and InteractsWithQueue
+// traits add to every job. Embed it in structs dispatched with
+// Publisher.DispatchObject so PHP unserializes the same object dispatch()
+// would have produced.
+type Queueable struct {
+	Job                 any           `php:"job"`
+	Connection          *string       `php:"connection"`
+	Queue               *string       `php:"queue"`
+	ChainConnection     *string       `php:"chainConnection"`
+	ChainQueue          *string       `php:"chainQueue"`
+	ChainCatchCallbacks *[]any        `php:"chainCatchCallbacks"`
+	Delay               time.Duration `php:"delay,omitempty"` // Seconds in PHP; left out when zero, so PHP keeps its null default
+	AfterCommit         *bool         `php:"afterCommit"`
+	Middleware          []any         `php:"middleware"`
+	Chained             []string      `php:"chained"`                // Serialized commands, see ChainCommands
+	MessageGroup        *string       `php:"messageGroup,omitempty"` // SQS FIFO message group, see MessageGroup
+}
+
+// EncodeObject converts a struct to a PHP object of the class it returns.
+//
+// Fields are stored under the name in their php tag, or else under the field
+// name with its first letter lower cased. The tag options "protected" and
+// "private" set the visibility of the property, "omitempty" leaves out zero
+// values and "class=App\\Models\\Name" encodes a nested struct as an object of
+// that class. Nested structs implementing PHPObject are encoded as objects,
+// other structs and maps as associative arrays, and slices as lists. Nil
+// pointers become null, nil slices and maps empty arrays, time.Time a Carbon
+// instance and time.Duration a number of seconds.
+func EncodeObject(v PHPObject) (*php_serialize.PhpObject, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("queue: cannot encode nil %T", v)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("queue: cannot encode %T as a PHP object, it is not a struct", v)
+	}
+	return encodeStruct(rv, v.PHPClass(), "")
+}
+
+func encodeStruct(rv reflect.Value, class string, path string) (*php_serialize.PhpObject, error) {
+	object := php_serialize.NewPhpObject(class)
+
+	for _, f := range reflect.VisibleFields(rv.Type()) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name, opts := parseTag(f)
+		if name == "-" {
+			continue
+		}
+		if f.Tag.Get("php") == "" || strings.HasPrefix(f.Tag.Get("php"), ",") {
+			name = lowerFirst(name)
+		}
+
+		field, err := rv.FieldByIndexErr(f.Index)
+		if err != nil {
+			// Field of a nil embedded pointer
+			continue
+		}
+		if hasOption(opts, "omitempty") && field.IsZero() {
+			continue
+		}
+
+		value, err := encodeValue(field, tagOption(opts, "class"), joinPath(path, name))
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case class == "":
+			// Array keys have no visibility
+			object.SetPublic(name, value)
+		case hasOption(opts, "protected"):
+			object.SetProtected(name, value)
+		case hasOption(opts, "private"):
+			object.SetPrivate(name, value)
+		default:
+			object.SetPublic(name, value)
+		}
+	}
+	return object, nil
+}
+
+func encodeValue(rv reflect.Value, class string, path string) (php_serialize.PhpValue, error) {
+	if !rv.IsValid() {
+		return nil, nil
+	}
+
+	switch v := rv.Interface().(type) {
+	case time.Time:
+		return encodeCarbon(v), nil
+	case time.Duration:
+		return int(v / time.Second), nil
+	case php_serialize.PhpArray, php_serialize.PhpSlice, *php_serialize.PhpObject:
+		return v, nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return encodeValue(rv.Elem(), class, path)
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("queue: cannot encode %s: %d overflows a PHP int", path, rv.Uint())
+		}
+		return int(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return string(rv.Bytes()), nil
+		}
+		list := make(php_serialize.PhpSlice, rv.Len())
+		for i := range list {
+			item, err := encodeValue(rv.Index(i), "", fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = item
+		}
+		return list, nil
+	case reflect.Map:
+		array := make(php_serialize.PhpArray, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := encodeKey(iter.Key(), path)
+			if err != nil {
+				return nil, err
+			}
+			item, err := encodeValue(iter.Value(), "", joinPath(path, fmt.Sprint(key)))
+			if err != nil {
+				return nil, err
+			}
+			array[key] = item
+		}
+		return array, nil
+	case reflect.Struct:
+		if obj, ok := rv.Interface().(PHPObject); ok && class == "" {
+			class = obj.PHPClass()
+		}
+		if class != "" {
+			return encodeStruct(rv, class, path)
+		}
+		object, err := encodeStruct(rv, "", path)
+		if err != nil {
+			return nil, err
+		}
+		return object.GetMembers(), nil
+	default:
+		return nil, fmt.Errorf("queue: cannot encode %s of type %s", path, rv.Type())
+	}
+}
+
+// encodeKey converts a map key to a PHP array key
+func encodeKey(rv reflect.Value, path string) (php_serialize.PhpValue, error) {
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(rv.Uint()), nil
+	default:
+		return nil, fmt.Errorf("queue: cannot encode %s: map keys of type %s are not valid PHP array keys", path, rv.Type())
+	}
+}
+
+// encodeCarbon converts a time to the serialized form of a Carbon instance
+func encodeCarbon(t time.Time) *php_serialize.PhpObject {
+	object := php_serialize.NewPhpObject(carbonClass)
+	object.SetPublic("date", t.Format("2006-01-02 15:04:05.000000"))
+	object.SetPublic("timezone_type", 3)
+	object.SetPublic("timezone", t.Location().String())
+	if t.Location() == time.Local {
+		object.SetPublic("timezone", t.Format("-07:00"))
+		object.SetPublic("timezone_type", 1)
+	}
+	return object
+}
+
+func hasOption(opts []string, option string) bool {
+	for _, opt := range opts {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// tagOption returns the value of a key=value tag option
+func tagOption(opts []string, key string) string {
+	for _, opt := range opts {
+		if value, ok := strings.CutPrefix(opt, key+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
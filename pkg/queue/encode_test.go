@@ -0,0 +1,114 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yvasiyarov/php_session_decoder/php_serialize"
+)
+
+type sendInvoice struct {
+	Queueable
+	InvoiceID int               `php:"invoiceId"`
+	Token     string            `php:"token,protected"`
+	Secret    string            `php:"secret,private"`
+	Customer  customer          `php:"customer,class=App\\Models\\Customer"`
+	Lines     []invoiceLine     `php:"lines"`
+	Meta      map[string]string `php:"meta"`
+	DueAt     time.Time         `php:"dueAt"`
+	Tries     int               `php:"tries,omitempty"`
+	Note      *string
+	Ignored   string `php:"-"`
+}
+
+func (sendInvoice) PHPClass() string { return "App\\Jobs\\SendInvoice" }
+
+type customer struct {
+	Email string `php:"email"`
+}
+
+type invoiceLine struct {
+	Amount float64 `php:"amount"`
+}
+
+func TestEncodeObject(t *testing.T) {
+	due := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	object, err := EncodeObject(sendInvoice{
+		InvoiceID: 7,
+		Token:     "tok",
+		Secret:    "s3cret",
+		Customer:  customer{Email: "ada@example.com"},
+		Lines:     []invoiceLine{{Amount: 9.5}},
+		DueAt:     due,
+		Ignored:   "x",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "App\\Jobs\\SendInvoice", object.GetClassName())
+
+	members := object.GetMembers()
+	assert.Equal(t, 7, members["invoiceId"])
+	assert.Equal(t, "tok", members["\x00*\x00token"])
+	assert.Equal(t, "s3cret", members["\x00App\\Jobs\\SendInvoice\x00secret"])
+	assert.Nil(t, members["note"])
+	assert.Contains(t, members, "note")
+	assert.NotContains(t, members, "tries")
+	assert.NotContains(t, members, "Ignored")
+	assert.NotContains(t, members, "delay")
+
+	// Queueable properties
+	assert.Contains(t, members, "queue")
+	assert.Equal(t, php_serialize.PhpSlice{}, members["middleware"])
+	assert.Equal(t, php_serialize.PhpSlice{}, members["chained"])
+	assert.Contains(t, members, "job")
+	assert.Nil(t, members["job"])
+
+	nested, ok := members["customer"].(*php_serialize.PhpObject)
+	require.True(t, ok)
+	assert.Equal(t, "App\\Models\\Customer", nested.GetClassName())
+
+	lines, ok := members["lines"].(php_serialize.PhpSlice)
+	require.True(t, ok)
+	assert.Equal(t, php_serialize.PhpArray{"amount": 9.5}, lines[0])
+}
+
+func TestEncodeObject_RoundTrip(t *testing.T) {
+	queueName := "invoices"
+	in := sendInvoice{
+		Queueable: Queueable{Queue: &queueName, Delay: 30 * time.Second},
+		InvoiceID: 7,
+		Token:     "tok",
+		Customer:  customer{Email: "ada@example.com"},
+		Lines:     []invoiceLine{{Amount: 9.5}, {Amount: 1}},
+		Meta:      map[string]string{"po": "123"},
+		DueAt:     time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC),
+	}
+
+	object, err := EncodeObject(in)
+	require.NoError(t, err)
+	serialized, err := SerializeCommand(object)
+	require.NoError(t, err)
+
+	decoded, err := php_serialize.UnSerialize(serialized)
+	require.NoError(t, err)
+
+	out, err := Bind[sendInvoice](&Job{UnserializedData: decoded})
+	require.NoError(t, err)
+
+	// Nil slices come back as empty PHP arrays
+	in.Middleware, in.Chained = []any{}, []string{}
+	assert.Equal(t, in, out)
+}
+
+func TestEncodeObject_UnsupportedType(t *testing.T) {
+	_, err := EncodeObject(withChannel{Events: make(chan int)})
+	assert.EqualError(t, err, "queue: cannot encode events of type chan int")
+}
+
+type withChannel struct {
+	Events chan int `php:"events"`
+}
+
+func (withChannel) PHPClass() string { return "App\\Jobs\\WithChannel" }
@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// maxStackDepth limits the number of frames captured for a failed job
+const maxStackDepth = 64
+
+// stackError is an error annotated with the stack it was raised from
+type stackError struct {
+	err   error
+	stack []uintptr
+}
+
+func (e *stackError) Error() string { return e.err.Error() }
+func (e *stackError) Unwrap() error { return e.err }
+
+// WithStack annotates err with the caller's stack trace, which FormatException
+// includes in the failed job record. Errors that already carry a stack are
+// returned as is.
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	var se *stackError
+	if errors.As(err, &se) {
+		return err
+	}
+	return &stackError{err: err, stack: callers(3)}
+}
+
+// PanicError converts a value recovered from a panic into an error carrying
+// the stack of the panic. It must be called from the deferred function that
+// recovered.
+func PanicError(recovered any) error {
+	err, ok := recovered.(error)
+	if !ok {
+		err = fmt.Errorf("%v", recovered)
+	}
+	err = fmt.Errorf("panic: %w", err)
+
+	// Start the trace at the frame that panicked rather than in the runtime
+	pcs := callers(3)
+	frames := runtime.CallersFrames(pcs)
+	for i := 0; ; i++ {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.gopanic" {
+			pcs = pcs[i+1:]
+			break
+		}
+		if !more {
+			break
+		}
+	}
+
+	return &stackError{err: err, stack: pcs}
+}
+
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip, pcs)
+	return pcs[:n]
+}
+
+// FormatException renders err the way PHP casts a Throwable to a string, which
+// is what Laravel stores in the exception column of failed_jobs:
+//
+//	*errors.errorString: something went wrong in /app/jobs/send.go:42
+//	Stack trace:
+//	#0 /app/jobs/send.go(42): main.SendMail()
+//	#1 {main}
+//
+// The stack trace is only available for errors created with WithStack or
+// PanicError. The worker annotates the errors of failed jobs with WithStack,
+// so they are traced from where the failure was recorded at the latest.
+func FormatException(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	var se *stackError
+	if !errors.As(err, &se) {
+		fmt.Fprintf(&b, "%T: %s", err, err.Error())
+		return b.String()
+	}
+
+	frames := runtime.CallersFrames(se.stack)
+	first, more := frames.Next()
+
+	fmt.Fprintf(&b, "%T: %s in %s:%d\nStack trace:\n", se.err, err.Error(), first.File, first.Line)
+
+	i := 0
+	for frame := first; ; frame, more = frames.Next() {
+		if frame.Function != "" {
+			fmt.Fprintf(&b, "#%d %s(%d): %s()\n", i, frame.File, frame.Line, frame.Function)
+			i++
+		}
+		if !more {
+			break
+		}
+	}
+	fmt.Fprintf(&b, "#%d {main}", i)
+
+	return b.String()
+}
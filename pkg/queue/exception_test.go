@@ -0,0 +1,44 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatException_WithoutStack(t *testing.T) {
+	assert.Equal(t, "*errors.errorString: boom", FormatException(errors.New("boom")))
+	assert.Equal(t, "", FormatException(nil))
+}
+
+func TestFormatException_WithStack(t *testing.T) {
+	err := fmt.Errorf("sending mail: %w", WithStack(errors.New("boom")))
+	formatted := FormatException(err)
+
+	lines := strings.Split(formatted, "\n")
+	assert.Regexp(t, `^\*errors\.errorString: sending mail: boom in .*exception_test\.go:\d+$`, lines[0])
+	assert.Equal(t, "Stack trace:", lines[1])
+	assert.Regexp(t, `^#0 .*exception_test\.go\(\d+\): .*TestFormatException_WithStack\(\)$`, lines[2])
+	assert.Regexp(t, `^#\d+ \{main\}$`, lines[len(lines)-1])
+}
+
+func TestPanicError_StartsAtPanic(t *testing.T) {
+	var err error
+	func() {
+		defer func() {
+			err = PanicError(recover())
+		}()
+		panicking()
+	}()
+
+	assert.EqualError(t, err, "panic: kaboom")
+	lines := strings.Split(FormatException(err), "\n")
+	assert.Regexp(t, `^#0 .*exception_test\.go\(\d+\): .*queue\.panicking\(\)$`, lines[2])
+}
+
+func panicking() {
+	panic("kaboom")
+}
@@ -0,0 +1,204 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultFailedFileLimit is the number of failed jobs Laravel keeps in the file
+const defaultFailedFileLimit = 100
+
+// FileFailedJobProvider stores failed jobs in a JSON file using the format of
+// Laravel's FileFailedJobProvider, keeping only the most recent ones.
+type FileFailedJobProvider struct {
+	path  string
+	limit int
+	mu    sync.Mutex
+}
+
+// FailedJobEntry is a failed job in the JSON format of Laravel's
+// FileFailedJobProvider, which other providers storing JSON reuse
+type FailedJobEntry struct {
+	ID                string `json:"id"`
+	Connection        string `json:"connection"`
+	Queue             string `json:"queue"`
+	Payload           string `json:"payload"`
+	Exception         string `json:"exception"`
+	FailedAt          string `json:"failed_at"`
+	FailedAtTimestamp int64  `json:"failed_at_timestamp"`
+}
+
+// NewFailedJobEntry creates the entry of a job failing now
+func NewFailedJobEntry(connection string, queue string, payload []byte, exception string) FailedJobEntry {
+	now := time.Now()
+	return FailedJobEntry{
+		ID:                FailedJobUUID(payload),
+		Connection:        connection,
+		Queue:             queue,
+		Payload:           string(payload),
+		Exception:         exception,
+		FailedAt:          now.Format(time.DateTime),
+		FailedAtTimestamp: now.Unix(),
+	}
+}
+
+// NewFileFailedJobProvider creates a provider writing to path, keeping at most
+// limit jobs (100 when limit is 0)
+func NewFileFailedJobProvider(path string, limit int) *FileFailedJobProvider {
+	if limit <= 0 {
+		limit = defaultFailedFileLimit
+	}
+	return &FileFailedJobProvider{path: path, limit: limit}
+}
+
+// Log prepends the failed job to the file
+func (p *FileFailedJobProvider) Log(ctx context.Context, connection string, queue string, payload []byte, exception string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	jobs, err := p.read()
+	if err != nil {
+		return err
+	}
+
+	jobs = append([]FailedJobEntry{NewFailedJobEntry(connection, queue, payload, exception)}, jobs...)
+
+	if len(jobs) > p.limit {
+		jobs = jobs[:p.limit]
+	}
+	return p.write(jobs)
+}
+
+// All returns the failed jobs matching the filter, most recent first
+func (p *FileFailedJobProvider) All(ctx context.Context, filter FailedJobFilter) ([]FailedJob, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	jobs, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []FailedJob
+	for _, job := range jobs {
+		if failed := job.ToFailedJob(); filter.Matches(failed) {
+			result = append(result, failed)
+		}
+	}
+	return result, nil
+}
+
+// Find returns a failed job by uuid, or nil if it does not exist
+func (p *FileFailedJobProvider) Find(ctx context.Context, id string) (*FailedJob, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	jobs, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range jobs {
+		if job.ID == id {
+			failed := job.ToFailedJob()
+			return &failed, nil
+		}
+	}
+	return nil, nil
+}
+
+// Forget deletes a failed job by uuid, reporting whether it existed
+func (p *FileFailedJobProvider) Forget(ctx context.Context, id string) (bool, error) {
+	return p.remove(func(job FailedJobEntry) bool { return job.ID == id })
+}
+
+// Flush deletes failed jobs older than the given number of hours, or all of them if hours is 0
+func (p *FileFailedJobProvider) Flush(ctx context.Context, hours int) error {
+	cutoff := time.Now().Add(-time.Duration(hours) * time.Hour).Unix()
+	_, err := p.remove(func(job FailedJobEntry) bool {
+		return hours <= 0 || job.FailedAtTimestamp <= cutoff
+	})
+	return err
+}
+
+// remove deletes the jobs matching fn, reporting whether any were deleted
+func (p *FileFailedJobProvider) remove(fn func(FailedJobEntry) bool) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	jobs, err := p.read()
+	if err != nil {
+		return false, err
+	}
+
+	kept := jobs[:0]
+	for _, job := range jobs {
+		if !fn(job) {
+			kept = append(kept, job)
+		}
+	}
+
+	if len(kept) == len(jobs) {
+		return false, nil
+	}
+	return true, p.write(kept)
+}
+
+func (p *FileFailedJobProvider) read() ([]FailedJobEntry, error) {
+	data, err := os.ReadFile(p.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []FailedJobEntry
+	if len(data) == 0 {
+		return jobs, nil
+	}
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// write replaces the file atomically so a crash never leaves it half written
+func (p *FileFailedJobProvider) write(jobs []FailedJobEntry) error {
+	if jobs == nil {
+		jobs = []FailedJobEntry{}
+	}
+	data, err := json.MarshalIndent(jobs, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.path)
+}
+
+// ToFailedJob converts the entry, whose id is the job's uuid
+func (j FailedJobEntry) ToFailedJob() FailedJob {
+	return FailedJob{
+		ID:         j.ID,
+		UUID:       j.ID,
+		Connection: j.Connection,
+		Queue:      j.Queue,
+		Payload:    []byte(j.Payload),
+		Exception:  j.Exception,
+		FailedAt:   time.Unix(j.FailedAtTimestamp, 0),
+	}
+}
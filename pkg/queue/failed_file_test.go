@@ -0,0 +1,121 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pixelvide/laravel-go/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileFailedJobProvider_LogFindForget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "framework", "cache", "failed-jobs.json")
+	provider := NewFileFailedJobProvider(path, 0)
+	ctx := context.Background()
+
+	require.NoError(t, provider.Log(ctx, "redis", "default", []byte(`{"uuid":"first","displayName":"App\\Jobs\\A"}`), "boom"))
+	require.NoError(t, provider.Log(ctx, "redis", "emails", []byte(`{"uuid":"second","displayName":"App\\Jobs\\B"}`), "bang"))
+
+	// The file uses Laravel's format, newest first
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var stored []map[string]any
+	require.NoError(t, json.Unmarshal(data, &stored))
+	require.Len(t, stored, 2)
+	assert.Equal(t, "second", stored[0]["id"])
+	assert.Equal(t, `{"uuid":"second","displayName":"App\\Jobs\\B"}`, stored[0]["payload"])
+	assert.Contains(t, stored[0], "failed_at_timestamp")
+
+	jobs, err := provider.All(ctx, FailedJobFilter{Queue: "emails"})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, `App\Jobs\B`, jobs[0].DisplayName())
+
+	job, err := provider.Find(ctx, "first")
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, "boom", job.Exception)
+
+	deleted, err := provider.Forget(ctx, "first")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	job, err = provider.Find(ctx, "first")
+	require.NoError(t, err)
+	assert.Nil(t, job)
+}
+
+func TestFileFailedJobProvider_LimitAndFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "failed-jobs.json")
+	provider := NewFileFailedJobProvider(path, 2)
+	ctx := context.Background()
+
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, provider.Log(ctx, "redis", "default", []byte(`{"uuid":"`+id+`"}`), "boom"))
+	}
+
+	jobs, err := provider.All(ctx, FailedJobFilter{})
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "c", jobs[0].ID)
+	assert.Equal(t, "b", jobs[1].ID)
+
+	// Nothing is old enough to be pruned yet
+	require.NoError(t, provider.Flush(ctx, 1))
+	jobs, err = provider.All(ctx, FailedJobFilter{})
+	require.NoError(t, err)
+	assert.Len(t, jobs, 2)
+
+	require.NoError(t, provider.Flush(ctx, 0))
+	jobs, err = provider.All(ctx, FailedJobFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+}
+
+func TestFailedJobUUID(t *testing.T) {
+	assert.Equal(t, "9a5d1e2c-7c2f-4b8e-9d3a-1f2e3d4c5b6a", FailedJobUUID([]byte(`{"uuid":"9a5d1e2c-7c2f-4b8e-9d3a-1f2e3d4c5b6a"}`)))
+
+	// Payloads without a uuid get a fresh one
+	for _, payload := range []string{`not json`, `{"displayName":"App\\Jobs\\Test"}`} {
+		_, err := uuid.Parse(FailedJobUUID([]byte(payload)))
+		assert.NoError(t, err, payload)
+	}
+}
+
+func TestNewFailedJobProvider(t *testing.T) {
+	cfg := &config.Config{}
+
+	cfg.Queue.FailedDriver = "null"
+	provider, err := NewFailedJobProvider(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, NullFailedJobProvider{}, provider)
+
+	cfg.Queue.FailedDriver = "unknown"
+	_, err = NewFailedJobProvider(cfg)
+	assert.EqualError(t, err, "unsupported failed job driver: unknown")
+
+	RegisterFailedJobProvider("custom", func(cfg *config.Config) (FailedJobProvider, error) {
+		return NewFileFailedJobProvider(filepath.Join(t.TempDir(), "failed.json"), 0), nil
+	})
+	cfg.Queue.FailedDriver = "custom"
+	provider, err = NewFailedJobProvider(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &FileFailedJobProvider{}, provider)
+}
+
+func TestFileFailedJobProvider_FailedAt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "failed-jobs.json")
+	provider := NewFileFailedJobProvider(path, 0)
+
+	require.NoError(t, provider.Log(context.Background(), "redis", "default", []byte(`{"uuid":"a"}`), "boom"))
+
+	job, err := provider.Find(context.Background(), "a")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), job.FailedAt, 2*time.Second)
+}
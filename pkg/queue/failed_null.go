@@ -0,0 +1,31 @@
+package queue
+
+import "context"
+
+// NullFailedJobProvider discards failed jobs, like Laravel's NullFailedJobProvider
+type NullFailedJobProvider struct{}
+
+// Log discards the failed job
+func (NullFailedJobProvider) Log(ctx context.Context, connection string, queue string, payload []byte, exception string) error {
+	return nil
+}
+
+// All returns no failed jobs
+func (NullFailedJobProvider) All(ctx context.Context, filter FailedJobFilter) ([]FailedJob, error) {
+	return nil, nil
+}
+
+// Find never finds a failed job
+func (NullFailedJobProvider) Find(ctx context.Context, id string) (*FailedJob, error) {
+	return nil, nil
+}
+
+// Forget has nothing to delete
+func (NullFailedJobProvider) Forget(ctx context.Context, id string) (bool, error) {
+	return false, nil
+}
+
+// Flush has nothing to delete
+func (NullFailedJobProvider) Flush(ctx context.Context, hours int) error {
+	return nil
+}
@@ -2,10 +2,110 @@ package queue
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pixelvide/laravel-go/pkg/config"
 )
 
-// FailedJobProvider defines the interface for logging failed jobs
+// FailedJobProvider defines the interface for logging and managing failed jobs
 type FailedJobProvider interface {
 	// Log records a failed job
 	Log(ctx context.Context, connection string, queue string, payload []byte, exception string) error
+
+	// All returns the failed jobs matching the filter, most recent first
+	All(ctx context.Context, filter FailedJobFilter) ([]FailedJob, error)
+
+	// Find returns a failed job by id or uuid, or nil if it does not exist
+	Find(ctx context.Context, id string) (*FailedJob, error)
+
+	// Forget deletes a failed job by id or uuid, reporting whether it existed
+	Forget(ctx context.Context, id string) (bool, error)
+
+	// Flush deletes failed jobs older than the given number of hours, or all of them if hours is 0
+	Flush(ctx context.Context, hours int) error
+}
+
+// FailedJob is a job recorded by a FailedJobProvider
+type FailedJob struct {
+	ID         string
+	UUID       string
+	Connection string
+	Queue      string
+	Payload    []byte
+	Exception  string
+	FailedAt   time.Time
+}
+
+// FailedJobFilter narrows down the failed jobs returned by FailedJobProvider.All.
+// Empty fields match everything.
+type FailedJobFilter struct {
+	Connection string
+	Queue      string
+}
+
+// Matches reports whether a failed job passes the filter
+func (f FailedJobFilter) Matches(job FailedJob) bool {
+	return (f.Connection == "" || f.Connection == job.Connection) &&
+		(f.Queue == "" || f.Queue == job.Queue)
+}
+
+// FailedJobUUID returns the uuid a failed job is recorded under: the uuid of
+// its payload, or a fresh one for payloads without one (e.g. unparseable bodies)
+func FailedJobUUID(payload []byte) string {
+	var job LaravelJob
+	if err := json.Unmarshal(payload, &job); err == nil && job.UUID != "" {
+		return job.UUID
+	}
+	return uuid.New().String()
+}
+
+// DisplayName returns the job class from the payload, or an empty string if it cannot be decoded
+func (j FailedJob) DisplayName() string {
+	var payload LaravelJob
+	if err := json.Unmarshal(j.Payload, &payload); err != nil {
+		return ""
+	}
+	return payload.DisplayName
+}
+
+// FailedJobProviderFactory creates a failed job provider from the application configuration
+type FailedJobProviderFactory func(cfg *config.Config) (FailedJobProvider, error)
+
+var (
+	// failedProviders maps QUEUE_FAILED_DRIVER values to provider factories
+	failedProviders   = make(map[string]FailedJobProviderFactory)
+	failedProvidersMu sync.RWMutex
+)
+
+// RegisterFailedJobProvider makes a failed job provider available under a
+// QUEUE_FAILED_DRIVER name. Driver packages register their providers in init.
+func RegisterFailedJobProvider(name string, factory FailedJobProviderFactory) {
+	failedProvidersMu.Lock()
+	defer failedProvidersMu.Unlock()
+	failedProviders[name] = factory
+}
+
+// NewFailedJobProvider creates the provider selected by cfg.Queue.FailedDriver
+func NewFailedJobProvider(cfg *config.Config) (FailedJobProvider, error) {
+	failedProvidersMu.RLock()
+	factory, ok := failedProviders[cfg.Queue.FailedDriver]
+	failedProvidersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported failed job driver: %s", cfg.Queue.FailedDriver)
+	}
+	return factory(cfg)
+}
+
+func init() {
+	RegisterFailedJobProvider("null", func(cfg *config.Config) (FailedJobProvider, error) {
+		return NullFailedJobProvider{}, nil
+	})
+	RegisterFailedJobProvider("file", func(cfg *config.Config) (FailedJobProvider, error) {
+		return NewFileFailedJobProvider(cfg.Queue.FailedPath, 0), nil
+	})
 }
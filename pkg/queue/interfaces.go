@@ -2,12 +2,15 @@ package queue
 
 import (
 	"context"
+	"time"
 )
 
 // Job represents a generic job retrieved from the queue
 type Job struct {
 	ID               string
+	Queue            string // The queue the job was popped from, if known
 	Body             []byte
+	Attempts         int         // Number of times the job has been reserved, including this one, as tracked by the driver
 	Payload          *LaravelJob // The parsed JSON envelope
 	UnserializedData any         // The unserialized PHP command properties (if applicable)
 }
@@ -24,6 +27,19 @@ func (j *Job) GetArg(key string) any {
 // Handler is the function signature for processing a job
 type Handler func(ctx context.Context, job *Job) error
 
+// Middleware wraps a Handler with cross-cutting behaviour, like Laravel job
+// middleware. It may skip the job by not calling next, or release it by
+// returning ReleaseAfter.
+type Middleware func(next Handler) Handler
+
+// Chain wraps handler in middleware, the first middleware being the outermost
+func Chain(handler Handler, middleware ...Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
 // Driver defines the interface for queue backends
 type Driver interface {
 	// Pop retrieves a job from the queue. It should block until a job is available.
@@ -33,3 +49,34 @@ type Driver interface {
 	// Ack acknowledges that the job has been processed and can be removed
 	Ack(ctx context.Context, job *Job) error
 }
+
+// Releaser is implemented by drivers that can put a reserved job back onto
+// its queue after a delay, like Laravel's Job::release($delay).
+// Drivers without it are retried by pushing a copy and acknowledging the original.
+type Releaser interface {
+	// Release makes the job available again once delay has elapsed
+	Release(ctx context.Context, job *Job, delay time.Duration) error
+}
+
+// LaterPusher is implemented by drivers that can push a job that only becomes
+// available after a delay, like Laravel's Queue::later
+type LaterPusher interface {
+	// Later adds a job payload to the queue, available once delay has elapsed
+	Later(ctx context.Context, queueName string, body []byte, delay time.Duration) error
+}
+
+// MultiQueuePopper is implemented by drivers that can wait on several queues
+// at once, as with `queue:work --queue=high,default,low`.
+type MultiQueuePopper interface {
+	// PopQueues retrieves a job from the first queue in queueNames that has one available.
+	// It should block until a job is available.
+	PopQueues(ctx context.Context, queueNames []string) (*Job, error)
+}
+
+// LeaseExtender is implemented by drivers whose reservations expire, such as
+// SQS's visibility timeout or Laravel's retry_after. The worker extends the
+// lease of long running jobs so they are not redelivered while still running.
+type LeaseExtender interface {
+	// ExtendLease keeps the reserved job from becoming available again until d from now
+	ExtendLease(ctx context.Context, job *Job, d time.Duration) error
+}
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/pixelvide/laravel-go/pkg/cache"
+	"github.com/pixelvide/laravel-go/pkg/queue"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) (*cache.RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return cache.NewPrefixedRedisStore(client, "laravel_cache_"), mr
+}
+
+func testJob(class string) *queue.Job {
+	return &queue.Job{Payload: &queue.LaravelJob{UUID: "abc", DisplayName: class}}
+}
+
+func TestRateLimited(t *testing.T) {
+	store, mr := newTestStore(t)
+	limiter := NewRateLimiter(store)
+
+	runs := 0
+	handler := queue.Chain(func(ctx context.Context, job *queue.Job) error {
+		runs++
+		return nil
+	}, RateLimited(limiter, "backups", func(job *queue.Job) Limit {
+		return PerMinute(2).By("user-1")
+	}))
+
+	ctx := context.Background()
+	require.NoError(t, handler(ctx, testJob("App\\Jobs\\Backup")))
+	require.NoError(t, handler(ctx, testJob("App\\Jobs\\Backup")))
+
+	err := handler(ctx, testJob("App\\Jobs\\Backup"))
+	delay, released := queue.IsRelease(err)
+	assert.True(t, released)
+	assert.InDelta(t, 63, delay.Seconds(), 1)
+	assert.Equal(t, 2, runs)
+
+	// Laravel's key: md5 of the limiter name and the limit key
+	sum := md5.Sum([]byte("backups" + "user-1"))
+	key := "laravel_cache_" + hex.EncodeToString(sum[:])
+	hits, err := mr.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, "2", hits)
+	assert.True(t, mr.Exists(key+":timer"))
+
+	// Once the window passes the job runs again
+	mr.FastForward(time.Minute)
+	require.NoError(t, handler(ctx, testJob("App\\Jobs\\Backup")))
+	assert.Equal(t, 3, runs)
+}
+
+func TestRateLimiter_HitIsAtomic(t *testing.T) {
+	store, mr := newTestStore(t)
+	limiter := NewRateLimiter(store)
+	ctx := context.Background()
+
+	_, err := limiter.Hit(ctx, "key", time.Minute)
+	require.NoError(t, err)
+
+	// Workers hitting the running window at once all count
+	var wg sync.WaitGroup
+	for i := 0; i < 49; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := limiter.Hit(ctx, "key", time.Minute)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	hits, err := mr.Get("laravel_cache_key")
+	require.NoError(t, err)
+	assert.Equal(t, "50", hits)
+	assert.Equal(t, time.Minute, mr.TTL("laravel_cache_key"))
+}
+
+func TestWithoutOverlapping(t *testing.T) {
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+	lockKey := "laravel_cache_laravel-queue-overlap:App\\Jobs\\Sync:42"
+
+	overlapping := WithoutOverlapping(store, "42", ReleaseAfter(10*time.Second))
+	noop := queue.Chain(func(ctx context.Context, job *queue.Job) error { return nil }, overlapping)
+
+	var nested error
+	handler := queue.Chain(func(ctx context.Context, job *queue.Job) error {
+		// The lock is held while the job runs, so the same job can't run concurrently
+		assert.True(t, mr.Exists(lockKey))
+		nested = noop(ctx, job)
+		return nil
+	}, overlapping)
+
+	require.NoError(t, handler(ctx, testJob("App\\Jobs\\Sync")))
+
+	delay, released := queue.IsRelease(nested)
+	assert.True(t, released)
+	assert.Equal(t, 10*time.Second, delay)
+
+	// The lock is released afterwards
+	assert.False(t, mr.Exists(lockKey))
+}
+
+func TestWithoutOverlapping_DontRelease(t *testing.T) {
+	store, mr := newTestStore(t)
+	mr.Set("laravel_cache_laravel-queue-overlap:shared-key", "php-owner")
+
+	runs := 0
+	handler := queue.Chain(func(ctx context.Context, job *queue.Job) error {
+		runs++
+		return nil
+	}, WithoutOverlapping(store, "shared-key", Shared(), DontRelease()))
+
+	// A PHP worker holds the lock, so the job is dropped
+	assert.NoError(t, handler(context.Background(), testJob("App\\Jobs\\Sync")))
+	assert.Equal(t, 0, runs)
+
+	// Locks owned by someone else are left alone
+	got, _ := mr.Get("laravel_cache_laravel-queue-overlap:shared-key")
+	assert.Equal(t, "php-owner", got)
+}
+
+func TestThrottlesExceptions(t *testing.T) {
+	store, _ := newTestStore(t)
+	limiter := NewRateLimiter(store)
+
+	failing := errors.New("api down")
+	runs := 0
+	handler := queue.Chain(func(ctx context.Context, job *queue.Job) error {
+		runs++
+		return failing
+	}, ThrottlesExceptions(limiter, 2, 5*time.Minute, ThrottleBackoff(30*time.Second)))
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		delay, released := queue.IsRelease(handler(ctx, testJob("App\\Jobs\\CallApi")))
+		assert.True(t, released)
+		assert.Equal(t, 30*time.Second, delay)
+	}
+
+	// Throttled: the handler is not called until the window passes
+	delay, released := queue.IsRelease(handler(ctx, testJob("App\\Jobs\\CallApi")))
+	assert.True(t, released)
+	assert.InDelta(t, (5*time.Minute + 3*time.Second).Seconds(), delay.Seconds(), 1)
+	assert.Equal(t, 2, runs)
+}
+
+func TestThrottlesExceptions_SucceedsWhenClearFails(t *testing.T) {
+	store, mr := newTestStore(t)
+	limiter := NewRateLimiter(store)
+
+	// The cache goes away while the job runs
+	handler := queue.Chain(func(ctx context.Context, job *queue.Job) error {
+		mr.Close()
+		return nil
+	}, ThrottlesExceptions(limiter, 2, time.Minute))
+
+	assert.NoError(t, handler(context.Background(), testJob("App\\Jobs\\CallApi")))
+}
+
+func TestThrottlesExceptions_When(t *testing.T) {
+	store, _ := newTestStore(t)
+	limiter := NewRateLimiter(store)
+
+	failing := errors.New("validation failed")
+	handler := queue.Chain(func(ctx context.Context, job *queue.Job) error {
+		return failing
+	}, ThrottlesExceptions(limiter, 1, time.Minute, ThrottleWhen(func(err error) bool { return false })))
+
+	assert.ErrorIs(t, handler(context.Background(), testJob("App\\Jobs\\CallApi")), failing)
+}
+
+func TestSkip(t *testing.T) {
+	runs := 0
+	handler := func(ctx context.Context, job *queue.Job) error {
+		runs++
+		return nil
+	}
+	ctx := context.Background()
+
+	cancelled := func(ctx context.Context, job *queue.Job) bool { return job.Payload.UUID == "abc" }
+
+	require.NoError(t, queue.Chain(handler, SkipWhen(cancelled))(ctx, testJob("App\\Jobs\\Notify")))
+	assert.Equal(t, 0, runs)
+
+	require.NoError(t, queue.Chain(handler, SkipUnless(cancelled))(ctx, testJob("App\\Jobs\\Notify")))
+	assert.Equal(t, 1, runs)
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"time"
+
+	"github.com/pixelvide/laravel-go/pkg/queue"
+)
+
+// Limit is the number of jobs allowed per decay window, like Illuminate\Cache\RateLimiting\Limit
+type Limit struct {
+	Key         string // Segments the limit, e.g. per user
+	MaxAttempts int
+	Decay       time.Duration
+}
+
+// PerMinute allows maxAttempts jobs per minute
+func PerMinute(maxAttempts int) Limit {
+	return Limit{MaxAttempts: maxAttempts, Decay: time.Minute}
+}
+
+// PerHour allows maxAttempts jobs per hour
+func PerHour(maxAttempts int) Limit {
+	return Limit{MaxAttempts: maxAttempts, Decay: time.Hour}
+}
+
+// By segments the limit by key
+func (l Limit) By(key string) Limit {
+	l.Key = key
+	return l
+}
+
+// RateLimited releases jobs once the named limit is reached, like Laravel's
+// RateLimited middleware with RateLimiter::for($name, ...). The limit
+// callback plays the role of the limiter closure.
+func RateLimited(limiter *RateLimiter, name string, limit func(job *queue.Job) Limit) queue.Middleware {
+	return func(next queue.Handler) queue.Handler {
+		return func(ctx context.Context, job *queue.Job) error {
+			l := limit(job)
+			sum := md5.Sum([]byte(name + l.Key))
+			key := hex.EncodeToString(sum[:])
+
+			tooMany, err := limiter.TooManyAttempts(ctx, key, l.MaxAttempts)
+			if err != nil {
+				return err
+			}
+			if tooMany {
+				// Laravel waits three more seconds than the window needs
+				return queue.ReleaseAfter(limiter.AvailableIn(ctx, key) + 3*time.Second)
+			}
+
+			if _, err := limiter.Hit(ctx, key, l.Decay); err != nil {
+				return err
+			}
+			return next(ctx, job)
+		}
+	}
+}
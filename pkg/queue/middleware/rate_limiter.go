@@ -0,0 +1,134 @@
+// Package middleware provides job middleware equivalent to Laravel's
+// Illuminate\Queue\Middleware classes. State is kept in a cache.Store or
+// cache.LockStore using Laravel's keys, so limits and locks are shared with
+// PHP workers using the same cache.
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/pixelvide/laravel-go/pkg/cache"
+)
+
+// RateLimiter counts hits in a cache store like Illuminate\Cache\RateLimiter.
+// Hits are only counted atomically with stores implementing cache.Adder and
+// cache.Incrementer, such as cache.RedisStore; with other stores concurrent
+// hits may be lost.
+type RateLimiter struct {
+	store cache.Store
+}
+
+// NewRateLimiter creates a rate limiter backed by store
+func NewRateLimiter(store cache.Store) *RateLimiter {
+	return &RateLimiter{store: store}
+}
+
+// TooManyAttempts reports whether key has been hit maxAttempts times within its decay
+func (l *RateLimiter) TooManyAttempts(ctx context.Context, key string, maxAttempts int) (bool, error) {
+	attempts, err := l.Attempts(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if attempts < int64(maxAttempts) {
+		return false, nil
+	}
+
+	if _, ok := l.get(ctx, key+":timer"); ok {
+		return true, nil
+	}
+	return false, l.Clear(ctx, key)
+}
+
+// Hit increments the attempts of key, starting a window of decay if none is
+// running, like RateLimiter::hit
+func (l *RateLimiter) Hit(ctx context.Context, key string, decay time.Duration) (int64, error) {
+	availableAt := time.Now().Add(decay).Unix()
+	if _, err := l.add(ctx, key+":timer", strconv.FormatInt(availableAt, 10), decay); err != nil {
+		return 0, err
+	}
+
+	added, err := l.add(ctx, key, "0", decay)
+	if err != nil {
+		return 0, err
+	}
+
+	hits, err := l.increment(ctx, key, decay)
+	if err != nil {
+		return 0, err
+	}
+
+	// The counter expired between adding and incrementing it, so restart it
+	if !added && hits == 1 {
+		return hits, l.store.Put(ctx, key, "1", decay)
+	}
+	return hits, nil
+}
+
+// Attempts returns the number of hits of key in the current window
+func (l *RateLimiter) Attempts(ctx context.Context, key string) (int64, error) {
+	value, ok := l.get(ctx, key)
+	if !ok {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// AvailableIn returns how long until key can be hit again
+func (l *RateLimiter) AvailableIn(ctx context.Context, key string) time.Duration {
+	value, ok := l.get(ctx, key+":timer")
+	if !ok {
+		return 0
+	}
+	availableAt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	if until := time.Until(time.Unix(availableAt, 0)); until > 0 {
+		return until.Round(time.Second)
+	}
+	return 0
+}
+
+// Clear resets the hits and window of key
+func (l *RateLimiter) Clear(ctx context.Context, key string) error {
+	if err := l.store.Forget(ctx, key); err != nil {
+		return err
+	}
+	return l.store.Forget(ctx, key+":timer")
+}
+
+// get reads a key, treating any error as a miss since stores report misses as errors
+func (l *RateLimiter) get(ctx context.Context, key string) (string, bool) {
+	value, err := l.store.Get(ctx, key)
+	if err != nil || value == "" {
+		return "", false
+	}
+	return value, true
+}
+
+// add stores value unless key exists, reporting whether it was stored
+func (l *RateLimiter) add(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	if adder, ok := l.store.(cache.Adder); ok {
+		return adder.Add(ctx, key, value, ttl)
+	}
+
+	if _, ok := l.get(ctx, key); ok {
+		return false, nil
+	}
+	return true, l.store.Put(ctx, key, value, ttl)
+}
+
+func (l *RateLimiter) increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if incrementer, ok := l.store.(cache.Incrementer); ok {
+		return incrementer.Increment(ctx, key, 1)
+	}
+
+	attempts, err := l.Attempts(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	attempts++
+	return attempts, l.store.Put(ctx, key, strconv.FormatInt(attempts, 10), ttl)
+}
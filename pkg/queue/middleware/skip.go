@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/pixelvide/laravel-go/pkg/queue"
+)
+
+// SkipWhen deletes the job without running it when condition returns true,
+// like Laravel's Skip::when
+func SkipWhen(condition func(ctx context.Context, job *queue.Job) bool) queue.Middleware {
+	return func(next queue.Handler) queue.Handler {
+		return func(ctx context.Context, job *queue.Job) error {
+			if condition(ctx, job) {
+				return nil
+			}
+			return next(ctx, job)
+		}
+	}
+}
+
+// SkipUnless deletes the job without running it unless condition returns true,
+// like Laravel's Skip::unless
+func SkipUnless(condition func(ctx context.Context, job *queue.Job) bool) queue.Middleware {
+	return SkipWhen(func(ctx context.Context, job *queue.Job) bool {
+		return !condition(ctx, job)
+	})
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"time"
+
+	"github.com/pixelvide/laravel-go/pkg/queue"
+	"github.com/rs/zerolog"
+)
+
+// throttlePrefix is the cache key prefix of Laravel's ThrottlesExceptions
+const throttlePrefix = "laravel_throttles_exceptions:"
+
+type throttleConfig struct {
+	key     string
+	backoff time.Duration
+	when    func(error) bool
+}
+
+// ThrottleOption configures ThrottlesExceptions
+type ThrottleOption func(*throttleConfig)
+
+// ThrottleBy shares the exception count between jobs using the same key,
+// instead of counting per job class
+func ThrottleBy(key string) ThrottleOption {
+	return func(c *throttleConfig) {
+		c.key = key
+	}
+}
+
+// ThrottleBackoff sets how long to wait before retrying a job after an exception
+func ThrottleBackoff(delay time.Duration) ThrottleOption {
+	return func(c *throttleConfig) {
+		c.backoff = delay
+	}
+}
+
+// ThrottleWhen only throttles errors for which fn returns true; others are returned as is
+func ThrottleWhen(fn func(error) bool) ThrottleOption {
+	return func(c *throttleConfig) {
+		c.when = fn
+	}
+}
+
+// ThrottlesExceptions stops running jobs for decay once they returned
+// maxAttempts errors, releasing them instead, like Laravel's
+// ThrottlesExceptions middleware. Errors are turned into releases, so they
+// use up attempts but not maxExceptions.
+func ThrottlesExceptions(limiter *RateLimiter, maxAttempts int, decay time.Duration, opts ...ThrottleOption) queue.Middleware {
+	cfg := throttleConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next queue.Handler) queue.Handler {
+		return func(ctx context.Context, job *queue.Job) error {
+			key := throttlePrefix + cfg.key
+			if cfg.key == "" {
+				sum := md5.Sum([]byte(jobClass(job)))
+				key = throttlePrefix + hex.EncodeToString(sum[:])
+			}
+
+			tooMany, err := limiter.TooManyAttempts(ctx, key, maxAttempts)
+			if err != nil {
+				return err
+			}
+			if tooMany {
+				return queue.ReleaseAfter(limiter.AvailableIn(ctx, key) + 3*time.Second)
+			}
+
+			err = next(ctx, job)
+			if err == nil {
+				// The job succeeded, which a cache error must not undo
+				if clearErr := limiter.Clear(ctx, key); clearErr != nil {
+					zerolog.Ctx(ctx).Error().Err(clearErr).Str("key", key).Msg("Error clearing exception throttle")
+				}
+				return nil
+			}
+			if _, ok := queue.IsRelease(err); ok {
+				return err
+			}
+			if cfg.when != nil && !cfg.when(err) {
+				return err
+			}
+
+			if _, hitErr := limiter.Hit(ctx, key, decay); hitErr != nil {
+				return hitErr
+			}
+			return queue.ReleaseAfter(cfg.backoff)
+		}
+	}
+}
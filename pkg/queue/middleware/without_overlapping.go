@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/pixelvide/laravel-go/pkg/cache"
+	"github.com/pixelvide/laravel-go/pkg/queue"
+)
+
+// overlapPrefix is the lock key prefix of Laravel's WithoutOverlapping
+const overlapPrefix = "laravel-queue-overlap:"
+
+type overlapConfig struct {
+	releaseAfter time.Duration
+	dontRelease  bool
+	expireAfter  time.Duration
+	shared       bool
+}
+
+// OverlapOption configures WithoutOverlapping
+type OverlapOption func(*overlapConfig)
+
+// ReleaseAfter sets how long to wait before retrying a job that could not get the lock
+func ReleaseAfter(delay time.Duration) OverlapOption {
+	return func(c *overlapConfig) {
+		c.releaseAfter = delay
+	}
+}
+
+// DontRelease deletes overlapping jobs instead of releasing them
+func DontRelease() OverlapOption {
+	return func(c *overlapConfig) {
+		c.dontRelease = true
+	}
+}
+
+// ExpireAfter expires the lock, in case a worker dies while holding it
+func ExpireAfter(duration time.Duration) OverlapOption {
+	return func(c *overlapConfig) {
+		c.expireAfter = duration
+	}
+}
+
+// Shared shares the lock across job classes using the same key
+func Shared() OverlapOption {
+	return func(c *overlapConfig) {
+		c.shared = true
+	}
+}
+
+// WithoutOverlapping prevents jobs with the same key from running
+// concurrently, like Laravel's WithoutOverlapping middleware. The locks are
+// named "laravel-queue-overlap:..." like in PHP, so locks must store them
+// under the cache prefix only, as cache.RedisStore does, to share them with
+// PHP workers. Lock providers adding a prefix of their own, such as
+// schedule.RedisLockProvider, are not cache lock stores.
+func WithoutOverlapping(locks cache.LockStore, key string, opts ...OverlapOption) queue.Middleware {
+	cfg := overlapConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next queue.Handler) queue.Handler {
+		return func(ctx context.Context, job *queue.Job) error {
+			lockKey := overlapPrefix + key
+			if !cfg.shared {
+				lockKey = overlapPrefix + jobClass(job) + ":" + key
+			}
+
+			acquired, err := locks.GetLock(ctx, lockKey, cfg.expireAfter)
+			if err != nil {
+				return err
+			}
+			if !acquired {
+				if cfg.dontRelease {
+					return nil
+				}
+				return queue.ReleaseAfter(cfg.releaseAfter)
+			}
+			defer func() {
+				_ = locks.ReleaseLock(context.WithoutCancel(ctx), lockKey)
+			}()
+
+			return next(ctx, job)
+		}
+	}
+}
+
+// jobClass returns the PHP class of the job, which Laravel uses in lock keys
+func jobClass(job *queue.Job) string {
+	if job.Payload == nil {
+		return ""
+	}
+	return job.Payload.DisplayName
+}
@@ -0,0 +1,195 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/yvasiyarov/php_session_decoder/php_serialize"
+)
+
+const (
+	// ModelIdentifierClass is the class SerializesModels stores Eloquent models as
+	ModelIdentifierClass = "Illuminate\\Contracts\\Database\\ModelIdentifier"
+
+	// EloquentCollectionClass is the class of collections of models
+	EloquentCollectionClass = "Illuminate\\Database\\Eloquent\\Collection"
+)
+
+// ErrModelNotFound is returned by ModelIdentifier.Load when the model no
+// longer exists, like Laravel's ModelNotFoundException
+var ErrModelNotFound = errors.New("queue: no query results for model")
+
+// ModelIdentifier references an Eloquent model, or a collection of models, of
+// a job using the SerializesModels trait
+type ModelIdentifier struct {
+	Class           string   `php:"class"`
+	ID              any      `php:"id"` // Primary key, or a []any of keys for collections
+	Relations       []string `php:"relations"`
+	Connection      *string  `php:"connection"`
+	CollectionClass *string  `php:"collectionClass"`
+}
+
+// NewModelIdentifier references the model of the given class and primary key
+func NewModelIdentifier(class string, id any, relations ...string) ModelIdentifier {
+	return ModelIdentifier{Class: class, ID: id, Relations: relations}
+}
+
+// NewCollectionIdentifier references an Eloquent collection of models of the given class
+func NewCollectionIdentifier(class string, ids []any, relations ...string) ModelIdentifier {
+	collection := EloquentCollectionClass
+	return ModelIdentifier{Class: class, ID: ids, Relations: relations, CollectionClass: &collection}
+}
+
+// PHPClass implements PHPObject
+func (m ModelIdentifier) PHPClass() string {
+	return ModelIdentifierClass
+}
+
+// IsCollection reports whether the identifier references a collection of models
+func (m ModelIdentifier) IsCollection() bool {
+	return m.CollectionClass != nil
+}
+
+// IDs returns the primary keys of the referenced models
+func (m ModelIdentifier) IDs() []any {
+	if ids, ok := m.ID.([]any); ok {
+		return ids
+	}
+	if m.ID == nil {
+		return nil
+	}
+	return []any{m.ID}
+}
+
+// Object converts the identifier to the PHP object SerializesModels writes,
+// for use as a property of commands passed to Publisher.Dispatch
+func (m ModelIdentifier) Object() *php_serialize.PhpObject {
+	relations := make(php_serialize.PhpSlice, len(m.Relations))
+	for i, relation := range m.Relations {
+		relations[i] = relation
+	}
+
+	var id php_serialize.PhpValue = m.ID
+	if ids, ok := m.ID.([]any); ok {
+		list := make(php_serialize.PhpSlice, len(ids))
+		for i, key := range ids {
+			list[i] = key
+		}
+		id = list
+	}
+
+	object := php_serialize.NewPhpObject(ModelIdentifierClass)
+	object.SetPublic("class", m.Class)
+	object.SetPublic("id", id)
+	object.SetPublic("relations", relations)
+	object.SetPublic("connection", pointerValue(m.Connection))
+	object.SetPublic("collectionClass", pointerValue(m.CollectionClass))
+	return object
+}
+
+// pointerValue returns the string s points to, or null
+func pointerValue(s *string) php_serialize.PhpValue {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+// AsModelIdentifier converts an unserialized ModelIdentifier object, such as
+// the value of job.GetArg("podcast"), to a ModelIdentifier
+func AsModelIdentifier(v any) (ModelIdentifier, bool) {
+	object, ok := v.(*php_serialize.PhpObject)
+	if !ok || object.GetClassName() != ModelIdentifierClass {
+		return ModelIdentifier{}, false
+	}
+
+	m := ModelIdentifier{}
+	m.Class, _ = GetPHPProperty(object, "class").(string)
+	m.ID = GetPHPProperty(object, "id")
+	if ids, ok := m.ID.(php_serialize.PhpArray); ok {
+		keys, _ := listKeys(ids)
+		list := make([]any, len(keys))
+		for i, k := range keys {
+			list[i] = ids[k]
+		}
+		m.ID = list
+	}
+	if relations, ok := GetPHPProperty(object, "relations").(php_serialize.PhpArray); ok {
+		keys, _ := listKeys(relations)
+		for _, k := range keys {
+			if relation, ok := relations[k].(string); ok {
+				m.Relations = append(m.Relations, relation)
+			}
+		}
+	}
+	if connection, ok := GetPHPProperty(object, "connection").(string); ok {
+		m.Connection = &connection
+	}
+	if collection, ok := GetPHPProperty(object, "collectionClass").(string); ok {
+		m.CollectionClass = &collection
+	}
+	return m, true
+}
+
+// ModelResolver loads the database rows of the models an identifier
+// references, in the order of its IDs. Missing models are left out.
+type ModelResolver interface {
+	ResolveModels(ctx context.Context, model ModelIdentifier) ([]map[string]any, error)
+}
+
+// ModelResolverFunc adapts a function to a ModelResolver
+type ModelResolverFunc func(ctx context.Context, model ModelIdentifier) ([]map[string]any, error)
+
+// ResolveModels implements ModelResolver
+func (f ModelResolverFunc) ResolveModels(ctx context.Context, model ModelIdentifier) ([]map[string]any, error) {
+	return f(ctx, model)
+}
+
+// Load loads the referenced model into the struct or map v points to, or a
+// collection into the slice v points to, matching columns to fields like
+// Job.Decode. A missing model returns ErrModelNotFound; relations are not loaded.
+func (m ModelIdentifier) Load(ctx context.Context, resolver ModelResolver, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("queue: Load needs a non-nil pointer, got %T", v)
+	}
+
+	rows, err := resolver.ResolveModels(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	if rv.Elem().Kind() == reflect.Slice {
+		list := make(php_serialize.PhpArray, len(rows))
+		for i, row := range rows {
+			list[i] = fromRow(row)
+		}
+		return decodeValue(list, rv.Elem(), "")
+	}
+
+	if len(rows) == 0 {
+		return fmt.Errorf("%w [%s] %v", ErrModelNotFound, m.Class, m.ID)
+	}
+	return decodeValue(fromRow(rows[0]), rv.Elem(), "")
+}
+
+// fromRow converts the column values database/sql scans to PHP values
+func fromRow(row map[string]any) php_serialize.PhpArray {
+	array := make(php_serialize.PhpArray, len(row))
+	for column, value := range row {
+		switch v := value.(type) {
+		case []byte:
+			value = string(v)
+		case int64:
+			value = int(v)
+		case int32:
+			value = int(v)
+		case float32:
+			value = float64(v)
+		}
+		array[column] = value
+	}
+	return array
+}
@@ -0,0 +1,121 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type processPodcast struct {
+	Queueable
+	Podcast ModelIdentifier `php:"podcast,protected"`
+}
+
+func (processPodcast) PHPClass() string { return "App\\Jobs\\ProcessPodcast" }
+
+type podcast struct {
+	ID          int       `php:"id"`
+	Title       string    `php:"title"`
+	PublishedAt time.Time `php:"published_at"`
+}
+
+func TestJob_DecodeModelIdentifier(t *testing.T) {
+	job := unserializedJob(t, `O:23:"App\Jobs\ProcessPodcast":2:{`+
+		`s:7:"podcast";O:45:"Illuminate\Contracts\Database\ModelIdentifier":5:{`+
+		`s:5:"class";s:18:"App\Models\Podcast";s:2:"id";i:7;`+
+		`s:9:"relations";a:1:{i:0;s:6:"author";}s:10:"connection";s:5:"mysql";s:15:"collectionClass";N;}`+
+		`s:8:"podcasts";O:45:"Illuminate\Contracts\Database\ModelIdentifier":5:{`+
+		`s:5:"class";s:18:"App\Models\Podcast";s:2:"id";a:2:{i:0;i:3;i:1;i:1;}`+
+		`s:9:"relations";a:0:{}s:10:"connection";N;s:15:"collectionClass";s:39:"Illuminate\Database\Eloquent\Collection";}}`)
+
+	var cmd struct {
+		Podcast  ModelIdentifier `php:"podcast"`
+		Podcasts ModelIdentifier `php:"podcasts"`
+	}
+	require.NoError(t, job.Decode(&cmd))
+
+	assert.Equal(t, "App\\Models\\Podcast", cmd.Podcast.Class)
+	assert.Equal(t, 7, cmd.Podcast.ID)
+	assert.Equal(t, []string{"author"}, cmd.Podcast.Relations)
+	require.NotNil(t, cmd.Podcast.Connection)
+	assert.Equal(t, "mysql", *cmd.Podcast.Connection)
+	assert.False(t, cmd.Podcast.IsCollection())
+
+	assert.True(t, cmd.Podcasts.IsCollection())
+	assert.Equal(t, []any{3, 1}, cmd.Podcasts.IDs())
+	assert.Nil(t, cmd.Podcasts.Connection)
+
+	model, ok := AsModelIdentifier(job.GetArg("podcast"))
+	assert.True(t, ok)
+	assert.Equal(t, cmd.Podcast, model)
+}
+
+func TestJob_DecodeModelIdentifierRejectsOtherObjects(t *testing.T) {
+	job := unserializedJob(t, `O:23:"App\Jobs\ProcessPodcast":1:{s:7:"podcast";O:8:"App\User":0:{}}`)
+
+	var cmd struct {
+		Podcast ModelIdentifier `php:"podcast"`
+	}
+	var decodeErr *DecodeError
+	require.ErrorAs(t, job.Decode(&cmd), &decodeErr)
+	assert.Equal(t, "podcast", decodeErr.Path)
+}
+
+func TestModelIdentifier_Encode(t *testing.T) {
+	object, err := EncodeObject(processPodcast{
+		Podcast: NewModelIdentifier("App\\Models\\Podcast", 7, "author"),
+	})
+	require.NoError(t, err)
+
+	serialized, err := SerializeCommand(object)
+	require.NoError(t, err)
+	assert.Contains(t, serialized, `O:45:"Illuminate\Contracts\Database\ModelIdentifier":5:{`)
+
+	job := unserializedJob(t, serialized)
+	var cmd processPodcast
+	require.NoError(t, job.Decode(&cmd))
+	assert.Equal(t, NewModelIdentifier("App\\Models\\Podcast", 7, "author"), cmd.Podcast)
+
+	// Commands built from args store identifiers the same way
+	command := NewCommand("App\\Jobs\\PublishPodcasts", map[string]interface{}{
+		"podcasts": NewCollectionIdentifier("App\\Models\\Podcast", []any{3, 1}),
+	})
+	serialized, err = SerializeCommand(command)
+	require.NoError(t, err)
+
+	model, ok := AsModelIdentifier(unserializedJob(t, serialized).GetArg("podcasts"))
+	require.True(t, ok)
+	assert.Equal(t, []any{3, 1}, model.ID)
+	assert.True(t, model.IsCollection())
+}
+
+func TestModelIdentifier_Load(t *testing.T) {
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	resolver := ModelResolverFunc(func(ctx context.Context, model ModelIdentifier) ([]map[string]any, error) {
+		var rows []map[string]any
+		for _, id := range model.IDs() {
+			if id == 3 || id == 1 {
+				rows = append(rows, map[string]any{"id": int64(id.(int)), "title": []byte("Episode"), "published_at": published})
+			}
+		}
+		return rows, nil
+	})
+	ctx := context.Background()
+
+	var p podcast
+	require.NoError(t, NewModelIdentifier("App\\Models\\Podcast", 3).Load(ctx, resolver, &p))
+	assert.Equal(t, podcast{ID: 3, Title: "Episode", PublishedAt: published}, p)
+
+	var list []podcast
+	require.NoError(t, NewCollectionIdentifier("App\\Models\\Podcast", []any{3, 9, 1}).Load(ctx, resolver, &list))
+	require.Len(t, list, 2)
+	assert.Equal(t, 3, list[0].ID)
+	assert.Equal(t, 1, list[1].ID)
+
+	err := NewModelIdentifier("App\\Models\\Podcast", 9).Load(ctx, resolver, &p)
+	assert.True(t, errors.Is(err, ErrModelNotFound))
+}
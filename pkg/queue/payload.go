@@ -1,6 +1,12 @@
 package queue
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // LaravelJob represents the standard JSON structure of a Laravel queue job
 type LaravelJob struct {
@@ -9,8 +15,143 @@ type LaravelJob struct {
 	Job           string          `json:"job"`
 	MaxTries      *int            `json:"maxTries"`
 	MaxExceptions *int            `json:"maxExceptions"`
-	Backoff       *int            `json:"backoff"`
+	Backoff       Backoff         `json:"backoff"`
 	Timeout       *int            `json:"timeout"`
+	RetryUntil    *int64          `json:"retryUntil"`
+	TimeoutAt     *int64          `json:"timeoutAt"` // Name of retryUntil before Laravel 6
+	FailOnTimeout bool            `json:"failOnTimeout"`
 	Data          json.RawMessage `json:"data"`
-	Attempts      int             `json:"attempts"` // Laravel often stores attempts internally or in payload
+	Attempts      int             `json:"attempts"` // Attempts made when the payload was (re)queued; see Job.Attempts for the live count
+
+	// DeduplicationID is the message deduplication id of jobs pushed onto FIFO
+	// queues, set with the DeduplicationID dispatch option
+	DeduplicationID string `json:"deduplicationId,omitempty"`
+}
+
+// RetryUntilTime returns the time after which the job must not be retried, if
+// it has one. Like Job::retryUntil, the legacy timeoutAt key is honoured too.
+func (j *LaravelJob) RetryUntilTime() (time.Time, bool) {
+	until := j.RetryUntil
+	if until == nil {
+		until = j.TimeoutAt
+	}
+	if until == nil {
+		return time.Time{}, false
+	}
+	return time.Unix(*until, 0), true
+}
+
+// WithAttempts returns a copy of a raw job payload with its attempts set,
+// keeping every other key of the envelope intact
+func WithAttempts(body []byte, attempts int) ([]byte, error) {
+	return withPayloadKey(body, "attempts", attempts)
+}
+
+// withPayloadKey returns a copy of a raw job payload with one key set
+func withPayloadKey(body []byte, key string, value any) ([]byte, error) {
+	var decoded map[string]json.RawMessage
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, fmt.Errorf("decoding payload: %w", err)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoded[key] = encoded
+	return json.Marshal(decoded)
+}
+
+// Backoff holds the delays in seconds between retries of a job.
+// Laravel serializes it as a comma separated string ("1,5,30"), but plain
+// numbers and JSON arrays are accepted as well.
+type Backoff []int
+
+// ParseBackoff parses a comma separated list of seconds such as "1,5,30"
+func ParseBackoff(s string) (Backoff, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, ",")
+	backoff := make(Backoff, 0, len(parts))
+	for _, part := range parts {
+		seconds, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid backoff %q: %w", s, err)
+		}
+		backoff = append(backoff, seconds)
+	}
+	return backoff, nil
+}
+
+// Delay returns how long to wait before retrying after the given attempt
+// (1-based). Attempts beyond the list reuse its last value, matching
+// Illuminate\Queue\Worker::calculateBackoff.
+func (b Backoff) Delay(attempt int) time.Duration {
+	if len(b) == 0 {
+		return 0
+	}
+	i := attempt - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(b) {
+		i = len(b) - 1
+	}
+	return time.Duration(b[i]) * time.Second
+}
+
+// UnmarshalJSON accepts null, a number, a comma separated string or an array
+func (b *Backoff) UnmarshalJSON(data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	switch v := raw.(type) {
+	case nil:
+		*b = nil
+	case float64:
+		*b = Backoff{int(v)}
+	case string:
+		parsed, err := ParseBackoff(v)
+		if err != nil {
+			return err
+		}
+		*b = parsed
+	case []any:
+		parsed := make(Backoff, 0, len(v))
+		for _, item := range v {
+			switch n := item.(type) {
+			case float64:
+				parsed = append(parsed, int(n))
+			case string:
+				seconds, err := strconv.Atoi(n)
+				if err != nil {
+					return fmt.Errorf("invalid backoff value %q: %w", n, err)
+				}
+				parsed = append(parsed, seconds)
+			default:
+				return fmt.Errorf("invalid backoff value %v", item)
+			}
+		}
+		*b = parsed
+	default:
+		return fmt.Errorf("invalid backoff %s", string(data))
+	}
+	return nil
+}
+
+// MarshalJSON writes the backoff the way Laravel does: null or "1,5,30"
+func (b Backoff) MarshalJSON() ([]byte, error) {
+	if len(b) == 0 {
+		return []byte("null"), nil
+	}
+
+	parts := make([]string, len(b))
+	for i, seconds := range b {
+		parts[i] = strconv.Itoa(seconds)
+	}
+	return json.Marshal(strings.Join(parts, ","))
 }
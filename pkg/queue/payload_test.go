@@ -0,0 +1,78 @@
+package queue
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want Backoff
+	}{
+		{"null", `{"backoff":null}`, nil},
+		{"missing", `{}`, nil},
+		{"number", `{"backoff":10}`, Backoff{10}},
+		{"laravel string", `{"backoff":"1,5,30"}`, Backoff{1, 5, 30}},
+		{"array", `{"backoff":[1,5,30]}`, Backoff{1, 5, 30}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var job LaravelJob
+			err := json.Unmarshal([]byte(tt.json), &job)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, job.Backoff)
+		})
+	}
+}
+
+func TestBackoff_MarshalJSON(t *testing.T) {
+	body, err := json.Marshal(LaravelJob{Backoff: Backoff{1, 5, 30}})
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"backoff":"1,5,30"`)
+
+	body, err = json.Marshal(LaravelJob{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"backoff":null`)
+}
+
+func TestBackoff_Delay(t *testing.T) {
+	backoff := Backoff{1, 5, 30}
+
+	assert.Equal(t, 1*time.Second, backoff.Delay(1))
+	assert.Equal(t, 5*time.Second, backoff.Delay(2))
+	assert.Equal(t, 30*time.Second, backoff.Delay(3))
+	assert.Equal(t, 30*time.Second, backoff.Delay(10))
+	assert.Equal(t, time.Duration(0), Backoff(nil).Delay(1))
+}
+
+func TestWithAttempts_KeepsOtherKeys(t *testing.T) {
+	body := []byte(`{"uuid":"abc","attempts":3,"retryUntil":1700000000,"tags":["a"]}`)
+
+	updated, err := WithAttempts(body, 0)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"uuid":"abc","attempts":0,"retryUntil":1700000000,"tags":["a"]}`, string(updated))
+
+	_, err = WithAttempts([]byte(`not json`), 0)
+	assert.Error(t, err)
+}
+
+func TestLaravelJob_RetryUntilTime(t *testing.T) {
+	var job LaravelJob
+	_, ok := job.RetryUntilTime()
+	assert.False(t, ok)
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"timeoutAt":1700000000}`), &job))
+	until, ok := job.RetryUntilTime()
+	assert.True(t, ok)
+	assert.Equal(t, int64(1700000000), until.Unix())
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"retryUntil":1800000000,"timeoutAt":1700000000}`), &job))
+	until, _ = job.RetryUntilTime()
+	assert.Equal(t, int64(1800000000), until.Unix())
+}
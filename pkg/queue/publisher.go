@@ -2,15 +2,21 @@ package queue
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/pixelvide/laravel-go/pkg/cache"
 	"github.com/yvasiyarov/php_session_decoder/php_serialize"
 )
 
+// ErrJobNotUnique is returned by Dispatch when a unique job is already queued
+var ErrJobNotUnique = errors.New("queue: a unique job with the same id is already queued")
+
 // Publisher handles dispatching jobs to the queue
 type Publisher struct {
-	driver Driver
+	driver    Driver
+	encrypter Encrypter
 }
 
 // NewPublisher creates a new Publisher instance
@@ -18,100 +24,231 @@ func NewPublisher(driver Driver) *Publisher {
 	return &Publisher{driver: driver}
 }
 
-// Dispatch pushes a new job to the queue
-// jobName is the Laravel job class name (e.g., "App\Jobs\ProcessPodcast")
-// args is a map of public properties to set on the job object
-func (p *Publisher) Dispatch(ctx context.Context, jobName string, args map[string]interface{}) error {
-	// 1. Generate UUID
-	id := uuid.New().String()
+// WithEncrypter sets the encrypter of jobs dispatched with Encrypted or
+// implementing ShouldBeEncrypted, e.g. one created from APP_KEY
+func (p *Publisher) WithEncrypter(encrypter Encrypter) *Publisher {
+	p.encrypter = encrypter
+	return p
+}
+
+// ShouldBeEncrypted is implemented by structs passed to DispatchObject whose
+// PHP class implements ShouldBeEncrypted, so their command is encrypted
+type ShouldBeEncrypted interface {
+	PHPObject
+	ShouldBeEncrypted()
+}
 
-	// 2. Serialize the job object
-	// Create a PHP object representing the job class
-	phpObj := php_serialize.NewPhpObject(jobName)
+// dispatchConfig holds the options of a single dispatch
+type dispatchConfig struct {
+	queue       string
+	delay       time.Duration
+	uniqueLocks cache.LockStore
+	uniqueID    string
+	uniqueFor   time.Duration
+	encrypted   bool
+	group       string
+	dedupID     string
+}
+
+// DispatchOption configures a dispatched job
+type DispatchOption func(*dispatchConfig)
 
-	// Set properties
-	for key, value := range args {
-		// We assume these are public properties
-		phpObj.SetPublic(key, value)
+// OnQueue pushes the job onto the given queue instead of "default"
+func OnQueue(queueName string) DispatchOption {
+	return func(c *dispatchConfig) {
+		c.queue = queueName
 	}
+}
 
-	encoder := php_serialize.NewSerializer()
-	serializedCommand, err := encoder.Encode(phpObj)
-	if err != nil {
-		return err
+// Delay makes the job available only once delay has elapsed, like
+// dispatch()->delay(). The driver must implement LaterPusher.
+func Delay(delay time.Duration) DispatchOption {
+	return func(c *dispatchConfig) {
+		c.delay = delay
 	}
+}
 
-	// 3. Construct the payload data (commandName + command)
-	payloadData := map[string]interface{}{
-		"commandName": jobName,
-		"command":     serializedCommand,
+// MessageGroup sets the message group of a job pushed onto an SQS FIFO queue,
+// like onGroup(). Jobs of a group are processed one at a time, in order.
+func MessageGroup(group string) DispatchOption {
+	return func(c *dispatchConfig) {
+		c.group = group
 	}
+}
 
-	dataBytes, err := json.Marshal(payloadData)
-	if err != nil {
-		return err
+// DeduplicationID sets the message deduplication id of a job pushed onto an
+// SQS FIFO queue. SQS drops jobs with the id of one sent in the last 5 minutes.
+func DeduplicationID(id string) DispatchOption {
+	return func(c *dispatchConfig) {
+		c.dedupID = id
 	}
+}
 
-	// 4. Construct the LaravelJob payload
-	laravelJob := LaravelJob{
-		UUID:        id,
-		DisplayName: jobName,
-		Job:         "Illuminate\\Queue\\CallQueuedHandler@call",
-		Data:        dataBytes,
+// Unique dispatches the job only if no job of the same class and uniqueId is
+// queued, like a job implementing ShouldBeUnique. The lock is taken in locks
+// using Laravel's key and expires after uniqueFor (0 never expires). The id
+// must match what the PHP class's uniqueId returns.
+func Unique(locks cache.LockStore, uniqueID string, uniqueFor time.Duration) DispatchOption {
+	return func(c *dispatchConfig) {
+		c.uniqueLocks = locks
+		c.uniqueID = uniqueID
+		c.uniqueFor = uniqueFor
 	}
+}
+
+// Encrypted encrypts the job's command, like a job implementing
+// ShouldBeEncrypted. The publisher needs an encrypter, see WithEncrypter.
+func Encrypted() DispatchOption {
+	return func(c *dispatchConfig) {
+		c.encrypted = true
+	}
+}
+
+// Dispatch pushes a new job to the queue
+// jobName is the Laravel job class name (e.g., "App\Jobs\ProcessPodcast")
+// args is a map of public properties to set on the job object
+func (p *Publisher) Dispatch(ctx context.Context, jobName string, args map[string]interface{}, opts ...DispatchOption) error {
+	return p.dispatch(ctx, NewCommand(jobName, args), newDispatchConfig(opts))
+}
+
+// DispatchToQueue pushes a new job to a specific queue
+func (p *Publisher) DispatchToQueue(ctx context.Context, queueName string, jobName string, args map[string]interface{}, opts ...DispatchOption) error {
+	return p.Dispatch(ctx, jobName, args, append(opts, OnQueue(queueName))...)
+}
 
-	body, err := json.Marshal(laravelJob)
+// DispatchObject pushes a job built from a struct, see EncodeObject for how
+// its fields map to PHP properties. The job is pushed onto the queue and with
+// the delay of its queue and delay properties, e.g. those of an embedded
+// Queueable; OnQueue and Delay override them and are stored in the object
+// like PHP's onQueue() and delay() do.
+func (p *Publisher) DispatchObject(ctx context.Context, command PHPObject, opts ...DispatchOption) error {
+	object, err := EncodeObject(command)
 	if err != nil {
 		return err
 	}
 
-	// 5. Push to the queue
-	// Use "default" queue for now, or we could add queue name to Dispatch arguments
-	// TODO: Allow specifying queue name
-	return p.driver.Push(ctx, "default", body)
+	cfg := newDispatchConfig(opts)
+	if _, ok := command.(ShouldBeEncrypted); ok {
+		cfg.encrypted = true
+	}
+	if cfg.queue != "" {
+		object.SetPublic("queue", cfg.queue)
+	} else if queueName, ok := GetPHPProperty(object, "queue").(string); ok {
+		cfg.queue = queueName
+	}
+	if cfg.delay > 0 {
+		object.SetPublic("delay", int(cfg.delay/time.Second))
+	} else if seconds, ok := GetPHPProperty(object, "delay").(int); ok {
+		cfg.delay = time.Duration(seconds) * time.Second
+	}
+
+	return p.dispatch(ctx, object, cfg)
 }
 
-// DispatchToQueue pushes a new job to a specific queue
-func (p *Publisher) DispatchToQueue(ctx context.Context, queueName string, jobName string, args map[string]interface{}) error {
-	// 1. Generate UUID
-	id := uuid.New().String()
+// ChainedJob is a job of a chain dispatched with Publisher.Chain
+type ChainedJob struct {
+	Name string                 // Laravel job class name
+	Args map[string]interface{} // Public properties of the job object
+}
+
+// Chain dispatches jobs that run one after another, like Bus::chain(). Only
+// the first job is pushed; the worker that completes a link pushes the next
+// one, whether it is a Go or a PHP worker. OnQueue sends every job of the
+// chain to the given queue, like allOnQueue.
+func (p *Publisher) Chain(ctx context.Context, jobs []ChainedJob, opts ...DispatchOption) error {
+	if len(jobs) == 0 {
+		return errors.New("queue: a chain needs at least one job")
+	}
 
-	// 2. Serialize the job object
-	phpObj := php_serialize.NewPhpObject(jobName)
-	for key, value := range args {
-		phpObj.SetPublic(key, value)
+	commands := make([]*php_serialize.PhpObject, 0, len(jobs))
+	for _, job := range jobs {
+		commands = append(commands, NewCommand(job.Name, job.Args))
 	}
 
-	encoder := php_serialize.NewSerializer()
-	serializedCommand, err := encoder.Encode(phpObj)
+	chained, err := ChainCommands(commands[1:])
 	if err != nil {
 		return err
 	}
 
-	// 3. Construct the payload data
-	payloadData := map[string]interface{}{
-		"commandName": jobName,
-		"command":     serializedCommand,
+	cfg := newDispatchConfig(opts)
+	first := commands[0]
+	first.SetPublic("chained", chained)
+	if cfg.queue != "" {
+		first.SetPublic("queue", cfg.queue)
+		first.SetPublic("chainQueue", cfg.queue)
 	}
 
-	dataBytes, err := json.Marshal(payloadData)
-	if err != nil {
-		return err
+	return p.dispatch(ctx, first, cfg)
+}
+
+func newDispatchConfig(opts []DispatchOption) dispatchConfig {
+	var cfg dispatchConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// dispatch pushes a command, taking its unique lock first if it has one
+func (p *Publisher) dispatch(ctx context.Context, command *php_serialize.PhpObject, cfg dispatchConfig) error {
+	var encrypter Encrypter
+	if cfg.encrypted {
+		if p.encrypter == nil {
+			return errors.New("queue: cannot dispatch an encrypted job without an encrypter")
+		}
+		encrypter = p.encrypter
 	}
 
-	// 4. Construct the LaravelJob payload
-	laravelJob := LaravelJob{
-		UUID:        id,
-		DisplayName: jobName,
-		Job:         "Illuminate\\Queue\\CallQueuedHandler@call",
-		Data:        dataBytes,
+	if cfg.group != "" {
+		command.SetPublic("messageGroup", cfg.group)
 	}
 
-	body, err := json.Marshal(laravelJob)
+	body, err := EncryptedCommandPayload(command, encrypter)
 	if err != nil {
 		return err
 	}
+	if cfg.dedupID != "" {
+		if body, err = withPayloadKey(body, "deduplicationId", cfg.dedupID); err != nil {
+			return err
+		}
+	}
 
-	// 5. Push to the specified queue
-	return p.driver.Push(ctx, queueName, body)
+	queueName := cfg.queue
+	if queueName == "" {
+		queueName = "default"
+	}
+
+	// Take the unique lock like PendingDispatch::shouldDispatch
+	if cfg.uniqueLocks != nil {
+		key := UniqueLockKey(command.GetClassName(), cfg.uniqueID)
+		acquired, err := cfg.uniqueLocks.GetLock(ctx, key, cfg.uniqueFor)
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			return ErrJobNotUnique
+		}
+
+		if err := p.push(ctx, queueName, body, cfg.delay); err != nil {
+			// Nothing was queued, so don't block the next dispatch
+			_ = cfg.uniqueLocks.ForceReleaseLock(ctx, key)
+			return err
+		}
+		return nil
+	}
+
+	return p.push(ctx, queueName, body, cfg.delay)
+}
+
+// push pushes a payload, delayed if the driver supports it
+func (p *Publisher) push(ctx context.Context, queueName string, body []byte, delay time.Duration) error {
+	if delay <= 0 {
+		return p.driver.Push(ctx, queueName, body)
+	}
+
+	later, ok := p.driver.(LaterPusher)
+	if !ok {
+		return fmt.Errorf("queue: the %T driver does not support delayed jobs", p.driver)
+	}
+	return later.Later(ctx, queueName, body, delay)
 }
@@ -2,8 +2,11 @@ package queue
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -99,3 +102,175 @@ func TestPublisher_DispatchToQueue(t *testing.T) {
 
 	mockDriver.AssertExpectations(t)
 }
+
+// fakeLockStore implements cache.LockStore in memory
+type fakeLockStore struct {
+	locks map[string]time.Duration
+}
+
+func (f *fakeLockStore) GetLock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if _, held := f.locks[key]; held {
+		return false, nil
+	}
+	f.locks[key] = ttl
+	return true, nil
+}
+
+func (f *fakeLockStore) ReleaseLock(ctx context.Context, key string) error {
+	delete(f.locks, key)
+	return nil
+}
+
+func (f *fakeLockStore) ForceReleaseLock(ctx context.Context, key string) error {
+	delete(f.locks, key)
+	return nil
+}
+
+func TestPublisher_DispatchUnique(t *testing.T) {
+	mockDriver := new(MockDriver)
+	publisher := NewPublisher(mockDriver)
+	locks := &fakeLockStore{locks: make(map[string]time.Duration)}
+
+	jobName := "App\\Jobs\\UpdateSearchIndex"
+	mockDriver.On("Push", mock.Anything, "default", mock.Anything).Return(nil).Once()
+
+	err := publisher.Dispatch(context.Background(), jobName, nil, Unique(locks, "42", time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, locks.locks["laravel_unique_job:App\\Jobs\\UpdateSearchIndex42"])
+
+	err = publisher.Dispatch(context.Background(), jobName, nil, Unique(locks, "42", time.Hour))
+	assert.ErrorIs(t, err, ErrJobNotUnique)
+
+	mockDriver.AssertExpectations(t)
+}
+
+func TestPublisher_DispatchUnique_ReleasesLockWhenPushFails(t *testing.T) {
+	mockDriver := new(MockDriver)
+	publisher := NewPublisher(mockDriver)
+	locks := &fakeLockStore{locks: make(map[string]time.Duration)}
+
+	mockDriver.On("Push", mock.Anything, "default", mock.Anything).Return(errors.New("connection refused"))
+
+	err := publisher.Dispatch(context.Background(), "App\\Jobs\\UpdateSearchIndex", nil, Unique(locks, "42", 0))
+	assert.Error(t, err)
+	assert.Empty(t, locks.locks)
+}
+
+func TestPublisher_Chain(t *testing.T) {
+	mockDriver := new(MockDriver)
+	publisher := NewPublisher(mockDriver)
+
+	var pushed []byte
+	mockDriver.On("Push", mock.Anything, "podcasts", mock.Anything).Return(nil).Once().Run(func(args mock.Arguments) {
+		pushed = args.Get(2).([]byte)
+	})
+
+	err := publisher.Chain(context.Background(), []ChainedJob{
+		{Name: "App\\Jobs\\ProcessPodcast", Args: map[string]interface{}{"podcastId": 1}},
+		{Name: "App\\Jobs\\OptimizePodcast", Args: map[string]interface{}{"podcastId": 1}},
+	}, OnQueue("podcasts"))
+	assert.NoError(t, err)
+	mockDriver.AssertExpectations(t)
+
+	first := decodeCommand(t, pushed)
+	assert.Equal(t, "App\\Jobs\\ProcessPodcast", first.Payload.DisplayName)
+	assert.Equal(t, "podcasts", first.GetArg("chainQueue"))
+
+	next, err := NextInChain(first)
+	assert.NoError(t, err)
+	assert.Equal(t, "podcasts", next.Queue)
+	assert.Equal(t, "App\\Jobs\\OptimizePodcast", decodeCommand(t, next.Body).Payload.DisplayName)
+}
+
+// MockLaterDriver is a MockDriver that supports delayed jobs
+type MockLaterDriver struct {
+	MockDriver
+}
+
+func (m *MockLaterDriver) Later(ctx context.Context, queueName string, body []byte, delay time.Duration) error {
+	args := m.Called(ctx, queueName, body, delay)
+	return args.Error(0)
+}
+
+func TestPublisher_DispatchObject(t *testing.T) {
+	mockDriver := new(MockLaterDriver)
+	publisher := NewPublisher(mockDriver)
+
+	var pushed []byte
+	mockDriver.On("Later", mock.Anything, "invoices", mock.Anything, time.Minute).Return(nil).Run(func(args mock.Arguments) {
+		pushed = args.Get(2).([]byte)
+	})
+
+	err := publisher.DispatchObject(context.Background(), sendInvoice{InvoiceID: 7, Tries: 3}, OnQueue("invoices"), Delay(time.Minute))
+	assert.NoError(t, err)
+	mockDriver.AssertExpectations(t)
+
+	job := decodeCommand(t, pushed)
+	assert.Equal(t, "App\\Jobs\\SendInvoice", job.Payload.DisplayName)
+	assert.Equal(t, 3, *job.Payload.MaxTries)
+	assert.Equal(t, "invoices", job.GetArg("queue"))
+	assert.Equal(t, 60, job.GetArg("delay"))
+}
+
+func TestPublisher_DispatchObjectUsesQueueableProperties(t *testing.T) {
+	mockDriver := new(MockDriver)
+	publisher := NewPublisher(mockDriver)
+
+	queueName := "billing"
+	mockDriver.On("Push", mock.Anything, "billing", mock.Anything).Return(nil)
+
+	err := publisher.DispatchObject(context.Background(), sendInvoice{Queueable: Queueable{Queue: &queueName}})
+	assert.NoError(t, err)
+	mockDriver.AssertExpectations(t)
+
+	// Delays need a driver implementing LaterPusher
+	err = publisher.DispatchObject(context.Background(), sendInvoice{}, Delay(time.Minute))
+	assert.ErrorContains(t, err, "does not support delayed jobs")
+}
+
+// base64Encrypter stands in for Laravel's encrypter
+type base64Encrypter struct{}
+
+func (base64Encrypter) Encrypt(value string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(value)), nil
+}
+
+func (base64Encrypter) Decrypt(payload string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(payload)
+	return string(decoded), err
+}
+
+type sendSecret struct {
+	Token string `php:"token"`
+}
+
+func (sendSecret) PHPClass() string   { return "App\\Jobs\\SendSecret" }
+func (sendSecret) ShouldBeEncrypted() {}
+
+func TestPublisher_DispatchEncrypted(t *testing.T) {
+	mockDriver := new(MockDriver)
+	publisher := NewPublisher(mockDriver)
+
+	err := publisher.Dispatch(context.Background(), "App\\Jobs\\SendSecret", nil, Encrypted())
+	assert.ErrorContains(t, err, "without an encrypter")
+
+	var pushed []byte
+	mockDriver.On("Push", mock.Anything, "default", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		pushed = args.Get(2).([]byte)
+	})
+
+	publisher.WithEncrypter(base64Encrypter{})
+	err = publisher.DispatchObject(context.Background(), sendSecret{Token: "s3cret"})
+	assert.NoError(t, err)
+
+	var payload LaravelJob
+	assert.NoError(t, json.Unmarshal(pushed, &payload))
+	assert.NotContains(t, string(payload.Data), "s3cret")
+
+	_, err = UnserializeCommand(payload.Data)
+	assert.ErrorContains(t, err, "no encrypter")
+
+	command, err := UnserializeEncryptedCommand(payload.Data, base64Encrypter{})
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", GetPHPProperty(command, "token"))
+}
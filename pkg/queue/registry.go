@@ -3,27 +3,62 @@ package queue
 import (
 	"errors"
 	"sync"
+	"time"
 )
 
+// registration is a handler together with its own middleware
+type registration struct {
+	handler    Handler
+	middleware []Middleware
+	unique     *UniqueOptions
+	retryUntil func(job *Job) time.Time
+}
+
 var (
 	// registry stores the mapping between job names (display names) and handlers
-	registry = make(map[string]Handler)
-	mu       sync.RWMutex
+	registry = make(map[string]registration)
+	// globalMiddleware wraps every handler, outside of per-job middleware
+	globalMiddleware []Middleware
+	mu               sync.RWMutex
 )
 
+// HandlerOption configures a handler when it is registered
+type HandlerOption func(*registration)
+
+// WithMiddleware wraps the handler in middleware, like a Laravel job's middleware() method.
+// The first middleware is the outermost.
+func WithMiddleware(middleware ...Middleware) HandlerOption {
+	return func(r *registration) {
+		r.middleware = append(r.middleware, middleware...)
+	}
+}
+
 // Register adds a handler for a given job name (usually the Laravel class name)
-func Register(name string, handler Handler) {
+func Register(name string, handler Handler, opts ...HandlerOption) {
+	r := registration{handler: handler}
+	for _, opt := range opts {
+		opt(&r)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	registry[name] = r
+}
+
+// Use adds middleware that wraps every job handler
+func Use(middleware ...Middleware) {
 	mu.Lock()
 	defer mu.Unlock()
-	registry[name] = handler
+	globalMiddleware = append(globalMiddleware, middleware...)
 }
 
-// GetHandler retrieves a handler by name
+// GetHandler retrieves a handler by name, wrapped in the global and its own middleware
 func GetHandler(name string) (Handler, error) {
 	mu.RLock()
 	defer mu.RUnlock()
-	if handler, ok := registry[name]; ok {
-		return handler, nil
+	if r, ok := registry[name]; ok {
+		handler := Chain(r.handler, r.middleware...)
+		return Chain(handler, globalMiddleware...), nil
 	}
 	return nil, errors.New("handler not found: " + name)
 }
@@ -0,0 +1,36 @@
+package queue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetHandler_WrapsMiddleware(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, job *Job) error {
+				calls = append(calls, name)
+				return next(ctx, job)
+			}
+		}
+	}
+
+	Register("MiddlewareJob", func(ctx context.Context, job *Job) error {
+		calls = append(calls, "handler")
+		return nil
+	}, WithMiddleware(record("job-1"), record("job-2")))
+
+	// Global middleware applies to handlers registered before it as well
+	Use(record("global"))
+	t.Cleanup(func() { globalMiddleware = nil })
+
+	handler, err := GetHandler("MiddlewareJob")
+	require.NoError(t, err)
+	require.NoError(t, handler(context.Background(), &Job{}))
+
+	assert.Equal(t, []string{"global", "job-1", "job-2", "handler"}, calls)
+}
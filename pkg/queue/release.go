@@ -0,0 +1,32 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ReleaseError is returned by handlers to put the job back onto the queue
+// without counting it as a failure, like calling $this->release() in a
+// Laravel job. Releases still use up attempts.
+type ReleaseError struct {
+	Delay time.Duration
+}
+
+func (e *ReleaseError) Error() string {
+	return fmt.Sprintf("job released back onto the queue with a delay of %s", e.Delay)
+}
+
+// ReleaseAfter returns an error that makes the worker release the job after delay
+func ReleaseAfter(delay time.Duration) error {
+	return &ReleaseError{Delay: delay}
+}
+
+// IsRelease reports whether err asks for the job to be released, returning the delay
+func IsRelease(err error) (time.Duration, bool) {
+	var release *ReleaseError
+	if errors.As(err, &release) {
+		return release.Delay, true
+	}
+	return 0, false
+}
@@ -0,0 +1,42 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RetryUntil mirrors a job's retryUntil() method. PHP stores its result in
+// the payload when dispatching, and queue:retry calls until to give a retried
+// job a new deadline, like RetryCommand::refreshRetryUntil.
+func RetryUntil(until func(job *Job) time.Time) HandlerOption {
+	return func(r *registration) {
+		r.retryUntil = until
+	}
+}
+
+// RefreshRetryUntil returns a copy of a raw payload whose retryUntil is
+// recomputed by the RetryUntil registered for its class. The command is
+// unserialized first, decrypting it with encrypter if needed, so until can
+// read its properties. Payloads of other classes are returned unchanged.
+func RefreshRetryUntil(body []byte, encrypter Encrypter) ([]byte, error) {
+	var payload LaravelJob
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("decoding payload: %w", err)
+	}
+
+	mu.RLock()
+	until := registry[payload.DisplayName].retryUntil
+	mu.RUnlock()
+	if until == nil {
+		return body, nil
+	}
+
+	unserialized, err := UnserializeEncryptedCommand(payload.Data, encrypter)
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract job payload: %w", err)
+	}
+
+	job := &Job{Body: body, Payload: &payload, UnserializedData: unserialized}
+	return withPayloadKey(body, "retryUntil", until(job).Unix())
+}
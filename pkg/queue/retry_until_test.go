@@ -0,0 +1,37 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshRetryUntil(t *testing.T) {
+	Register("App\\Jobs\\RetryUntilJob", func(ctx context.Context, job *Job) error { return nil },
+		RetryUntil(func(job *Job) time.Time {
+			minutes, _ := job.GetArg("minutes").(int)
+			return time.Now().Add(time.Duration(minutes) * time.Minute)
+		}))
+
+	body := []byte(`{"uuid":"a","displayName":"App\\Jobs\\RetryUntilJob","retryUntil":1000,"attempts":0,"data":{"commandName":"App\\Jobs\\RetryUntilJob","command":"O:22:\"App\\Jobs\\RetryUntilJob\":1:{s:7:\"minutes\";i:10;}"}}`)
+
+	refreshed, err := RefreshRetryUntil(body, nil)
+	require.NoError(t, err)
+
+	var payload LaravelJob
+	require.NoError(t, json.Unmarshal(refreshed, &payload))
+	until, ok := payload.RetryUntilTime()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), until, 2*time.Second)
+	assert.Equal(t, "a", payload.UUID)
+
+	// Jobs without a registered RetryUntil keep their payload
+	other := []byte(`{"uuid":"b","displayName":"App\\Jobs\\Other","retryUntil":1000}`)
+	unchanged, err := RefreshRetryUntil(other, nil)
+	require.NoError(t, err)
+	assert.Equal(t, string(other), string(unchanged))
+}
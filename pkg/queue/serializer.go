@@ -2,13 +2,32 @@ package queue
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/yvasiyarov/php_session_decoder/php_serialize"
 )
 
+// Encrypter encrypts the commands of jobs implementing ShouldBeEncrypted like
+// Laravel's encrypt() and decrypt(), e.g. an *encryption.Encrypter
+type Encrypter interface {
+	Encrypt(value string) (string, error)
+	Decrypt(payload string) (string, error)
+}
+
+// ErrCommandDecryption is returned by UnserializeEncryptedCommand when an
+// encrypted command cannot be decrypted, e.g. without APP_KEY or with the wrong key
+var ErrCommandDecryption = errors.New("queue: the command cannot be decrypted")
+
 // UnserializeCommand attempts to parse the PHP serialized command from the job payload
 func UnserializeCommand(data json.RawMessage) (any, error) {
+	return UnserializeEncryptedCommand(data, nil)
+}
+
+// UnserializeEncryptedCommand parses the command from the job payload like
+// UnserializeCommand, decrypting the commands of ShouldBeEncrypted jobs first
+func UnserializeEncryptedCommand(data json.RawMessage, encrypter Encrypter) (any, error) {
 	// First, try to unmarshal data as a map to find "command"
 	var dataMap map[string]interface{}
 	if err := json.Unmarshal(data, &dataMap); err != nil {
@@ -21,6 +40,18 @@ func UnserializeCommand(data json.RawMessage) (any, error) {
 		return dataMap, nil
 	}
 
+	// Serialized commands start with their object, encrypted ones are base64
+	if !strings.HasPrefix(commandStr, "O:") {
+		if encrypter == nil {
+			return nil, fmt.Errorf("%w: no encrypter is configured", ErrCommandDecryption)
+		}
+		decrypted, err := encrypter.Decrypt(commandStr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCommandDecryption, err)
+		}
+		commandStr = decrypted
+	}
+
 	return php_serialize.UnSerialize(commandStr)
 }
 
@@ -0,0 +1,59 @@
+package queue
+
+import "fmt"
+
+// uniqueLockPrefix is the cache lock prefix of Illuminate\Bus\UniqueLock
+const uniqueLockPrefix = "laravel_unique_job:"
+
+// UniqueOptions describes a job class implementing ShouldBeUnique
+type UniqueOptions struct {
+	// UntilProcessing releases the lock when the job starts rather than when
+	// it completes, like ShouldBeUniqueUntilProcessing
+	UntilProcessing bool
+	// ID returns the job's uniqueId. By default the uniqueId property of the
+	// PHP command is used.
+	ID func(job *Job) string
+}
+
+// UniqueLockKey returns the cache lock Laravel holds while a unique job is queued
+func UniqueLockKey(class string, uniqueID string) string {
+	return uniqueLockPrefix + class + uniqueID
+}
+
+// ShouldBeUnique marks the handler's job as implementing ShouldBeUnique, so
+// the worker releases the unique lock PHP acquired once the job completes or fails.
+// id may be nil to use the command's uniqueId property.
+func ShouldBeUnique(id func(job *Job) string) HandlerOption {
+	return func(r *registration) {
+		r.unique = &UniqueOptions{ID: id}
+	}
+}
+
+// ShouldBeUniqueUntilProcessing is like ShouldBeUnique, but the lock is
+// released as soon as the job starts processing
+func ShouldBeUniqueUntilProcessing(id func(job *Job) string) HandlerOption {
+	return func(r *registration) {
+		r.unique = &UniqueOptions{UntilProcessing: true, ID: id}
+	}
+}
+
+// GetUniqueOptions returns the unique options of a registered job, if it is unique
+func GetUniqueOptions(name string) (UniqueOptions, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if r, ok := registry[name]; ok && r.unique != nil {
+		return *r.unique, true
+	}
+	return UniqueOptions{}, false
+}
+
+// UniqueID returns the uniqueId of a job
+func (o UniqueOptions) UniqueID(job *Job) string {
+	if o.ID != nil {
+		return o.ID(job)
+	}
+	if id := job.GetArg("uniqueId"); id != nil {
+		return fmt.Sprint(id)
+	}
+	return ""
+}
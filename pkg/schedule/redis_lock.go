@@ -10,15 +10,23 @@ import (
 // RedisLockProvider implements LockProvider using Redis SETNX
 type RedisLockProvider struct {
 	client *redis.Client
+	prefix string
 }
 
+// NewRedisLockProvider creates a new Redis lock provider whose keys are not prefixed
 func NewRedisLockProvider(client *redis.Client) *RedisLockProvider {
-	return &RedisLockProvider{client: client}
+	return NewPrefixedRedisLockProvider(client, "")
+}
+
+// NewPrefixedRedisLockProvider creates a new Redis lock provider.
+// prefix is prepended to every lock key, e.g. REDIS_PREFIX followed by CACHE_PREFIX.
+func NewPrefixedRedisLockProvider(client *redis.Client, prefix string) *RedisLockProvider {
+	return &RedisLockProvider{client: client, prefix: prefix}
 }
 
 func (r *RedisLockProvider) GetLock(ctx context.Context, name string, duration time.Duration) (bool, error) {
 	// SET name value NX EX duration
-	success, err := r.client.SetNX(ctx, "schedule_lock:"+name, "locked", duration).Result()
+	success, err := r.client.SetNX(ctx, r.key(name), "locked", duration).Result()
 	if err != nil {
 		return false, err
 	}
@@ -26,5 +34,9 @@ func (r *RedisLockProvider) GetLock(ctx context.Context, name string, duration t
 }
 
 func (r *RedisLockProvider) ReleaseLock(ctx context.Context, name string) error {
-	return r.client.Del(ctx, "schedule_lock:"+name).Err()
+	return r.client.Del(ctx, r.key(name)).Err()
+}
+
+func (r *RedisLockProvider) key(name string) string {
+	return r.prefix + "schedule_lock:" + name
 }
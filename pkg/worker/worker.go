@@ -3,9 +3,14 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/pixelvide/laravel-go/pkg/cache"
 	"github.com/pixelvide/laravel-go/pkg/queue"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -13,18 +18,70 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+// UnhandledJobAction decides what happens to jobs the worker cannot process:
+// bodies that are not valid JSON and jobs without a registered handler.
+type UnhandledJobAction string
+
+const (
+	// FailUnhandled logs the job with the failed job provider and removes it from the queue
+	FailUnhandled UnhandledJobAction = "fail"
+	// ReleaseUnhandled puts the job back on its queue, e.g. for a PHP worker to pick up
+	ReleaseUnhandled UnhandledJobAction = "release"
+)
+
+// unhandledReleaseDelay keeps released unhandled jobs from bouncing straight back to Go workers
+const unhandledReleaseDelay = 5 * time.Second
+
+// exceptionsTTL is how long Laravel keeps the exception count of a job
+const exceptionsTTL = 24 * time.Hour
+
+// leaseHeartbeats is how many heartbeats each lease extension lasts, so one
+// late or failed extension does not let the job be redelivered
+const leaseHeartbeats = 3
+
+// fallbackPollTimeout bounds each Pop when a driver cannot wait on several queues at once
+const fallbackPollTimeout = time.Second
+
+// ConnectionResolver returns the driver of a queue connection other than the worker's own
+type ConnectionResolver func(connection string) (queue.Driver, error)
+
+// BatchRecorder records the outcome of jobs that belong to a batch, e.g. a *batch.Recorder
+type BatchRecorder interface {
+	RecordSuccessfulJob(ctx context.Context, batchID string, jobID string) error
+	RecordFailedJob(ctx context.Context, batchID string, jobID string, err error) error
+}
+
 // Worker manages the processing of jobs
 type Worker struct {
 	Driver         queue.Driver
 	FailedProvider queue.FailedJobProvider
+	Connection     string // Queue connection name recorded with failed jobs, e.g. QUEUE_CONNECTION
 	QueueName      string
+	Queues         []string // Queues to process in priority order, parsed from QueueName
 	Concurrency    int
-	AppName        string // Added AppName
+	Backoff        queue.Backoff      // Default backoff for jobs that don't define one (--backoff)
+	UnhandledJobs  UnhandledJobAction // What to do with unparseable or unregistered jobs (--unhandled)
+	AppName        string             // Added AppName
+	Cache          cache.Store        // Counts exceptions for maxExceptions; in memory when nil
+	Locks          cache.LockStore    // Holds the locks of unique jobs; unique locks are left alone when nil
+	Connections    ConnectionResolver // Resolves other connections chained jobs are pushed to
+	Batches        BatchRecorder      // Updates the job_batches counters of batched jobs; ignored when nil
+	Encrypter      queue.Encrypter    // Decrypts the commands of ShouldBeEncrypted jobs, see encryption.NewFromConfig
+	Heartbeat      time.Duration      // How often the lease of running jobs is extended, if the driver is a queue.LeaseExtender; disabled when 0 (--heartbeat)
 	Tracer         trace.Tracer
+	exceptions     map[string]exceptionCount
+	exceptionsMu   sync.Mutex
 	wg             sync.WaitGroup
 	quit           chan struct{}
 }
 
+// exceptionCount is an in-memory maxExceptions count, which expires like
+// the cache key Laravel counts in
+type exceptionCount struct {
+	count     int64
+	expiresAt time.Time
+}
+
 // NewWorker creates a new worker instance
 func NewWorker(driver queue.Driver, failedProvider queue.FailedJobProvider, queueName string, concurrency int, appName string, tracer trace.Tracer) *Worker {
 	if tracer == nil {
@@ -36,14 +93,31 @@ func NewWorker(driver queue.Driver, failedProvider queue.FailedJobProvider, queu
 	return &Worker{
 		Driver:         driver,
 		FailedProvider: failedProvider,
+		Connection:     "redis",
 		QueueName:      queueName,
+		Queues:         parseQueues(queueName),
 		Concurrency:    concurrency,
+		UnhandledJobs:  FailUnhandled,
 		AppName:        appName,
 		Tracer:         tracer,
 		quit:           make(chan struct{}),
 	}
 }
 
+// parseQueues splits a comma separated queue list such as "high,default,low"
+func parseQueues(queueName string) []string {
+	var queues []string
+	for _, name := range strings.Split(queueName, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			queues = append(queues, name)
+		}
+	}
+	if len(queues) == 0 {
+		queues = []string{"default"}
+	}
+	return queues
+}
+
 // Run starts the worker pool
 func (w *Worker) Run(ctx context.Context) {
 	for i := 0; i < w.Concurrency; i++ {
@@ -65,11 +139,15 @@ func (w *Worker) processLoop(ctx context.Context, id int) {
 			return
 		default:
 			// Pop a job
-			job, err := w.Driver.Pop(ctx, w.QueueName)
+			job, err := w.pop(ctx)
 			if err != nil {
-				if err == context.Canceled || err == context.DeadlineExceeded {
+				if ctx.Err() != nil {
 					return
 				}
+				if errors.Is(err, context.DeadlineExceeded) {
+					// No job became available in time (e.g. an empty SQS long poll)
+					continue
+				}
 				log.Error().Err(err).Int("worker_id", id).Msg("Error popping job")
 				// Sleep a bit to avoid tight loop on error
 				time.Sleep(time.Second)
@@ -77,18 +155,59 @@ func (w *Worker) processLoop(ctx context.Context, id int) {
 			}
 
 			// Process the job
-			w.handleJob(ctx, job)
+			_ = w.handleJob(ctx, job, false)
+		}
+	}
+}
+
+// pop retrieves the next job, always preferring queues earlier in w.Queues
+func (w *Worker) pop(ctx context.Context) (*queue.Job, error) {
+	if len(w.Queues) == 1 {
+		return w.Driver.Pop(ctx, w.Queues[0])
+	}
+
+	if popper, ok := w.Driver.(queue.MultiQueuePopper); ok {
+		return popper.PopQueues(ctx, w.Queues)
+	}
+
+	// Check each queue in turn, like Laravel's Worker::getNextJob.
+	// Pop blocks, so every queue only gets a short window.
+	for _, name := range w.Queues {
+		popCtx, cancel := context.WithTimeout(ctx, fallbackPollTimeout)
+		job, err := w.Driver.Pop(popCtx, name)
+		cancel()
+
+		if err == nil {
+			return job, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
 		}
 	}
+	return nil, context.DeadlineExceeded
+}
+
+// Process runs a job the way the worker runs the jobs it pops, and returns the
+// error it failed with. Unlike popped jobs, a job that fails is not retried,
+// like with Laravel's SyncQueue. Jobs released by their handler return nil.
+func (w *Worker) Process(ctx context.Context, job *queue.Job) error {
+	return w.handleJob(ctx, job, true)
 }
 
-func (w *Worker) handleJob(ctx context.Context, job *queue.Job) {
+// handleJob runs a popped job and returns the error it failed with. Jobs
+// attempted once fail on their first error, whatever their tries.
+func (w *Worker) handleJob(ctx context.Context, job *queue.Job, attemptOnce bool) error {
 	var payload queue.LaravelJob
 	if err := json.Unmarshal(job.Body, &payload); err != nil {
-		log.Error().Err(err).Str("body", string(job.Body)).Msg("Error unmarshalling job")
-		// If we can't parse it, we probably can't process it.
-		// In a real system we might move to failed jobs.
-		return
+		logger := log.With().Str("service", w.AppName).Str("body", string(job.Body)).Logger()
+		logger.Error().Err(err).Msg("Error unmarshalling job")
+		// If we can't parse it, we can't process it, but it must not vanish
+		err = fmt.Errorf("unable to decode job payload: %w", err)
+		w.handleUnprocessable(logger.WithContext(ctx), job, err)
+		return err
 	}
 
 	// Start Trace
@@ -98,8 +217,8 @@ func (w *Worker) handleJob(ctx context.Context, job *queue.Job) {
 	// Extract TraceID and Setup Logger
 	traceID := span.SpanContext().TraceID().String()
 	logger := log.With().
-		Timestamp(). // Ensure timestamp is present
-		Str("service", w.AppName). // Add service name
+		Timestamp().                  // Ensure timestamp is present
+		Str("service", w.AppName).    // Add service name
 		Str("command", "queue:work"). // Add command name
 		Str("trace_id", traceID).
 		Str("job_uuid", payload.UUID).
@@ -112,17 +231,42 @@ func (w *Worker) handleJob(ctx context.Context, job *queue.Job) {
 	handler, err := queue.GetHandler(payload.DisplayName)
 	if err != nil {
 		logger.Error().Str("job_name", payload.DisplayName).Msg("No handler found for job")
-		// TODO: Handle unregistered jobs (maybe fail them?)
-		return
+		w.handleUnprocessable(ctx, job, err)
+		return err
 	}
 
 	// Attempt to unserialize PHP command if present
-	unserialized, _ := queue.UnserializeCommand(payload.Data)
+	job.Payload = &payload
+	unserialized, err := queue.UnserializeEncryptedCommand(payload.Data, w.Encrypter)
+	if errors.Is(err, queue.ErrCommandDecryption) {
+		// Running a ShouldBeEncrypted job without its properties would lose them
+		logger.Error().Err(err).Msg("Failed to decrypt job command")
+		w.handleUnprocessable(ctx, job, err)
+		return err
+	} else if err != nil {
+		logger.Warn().Err(err).Msg("Failed to unserialize job command")
+	}
 
 	// Populate job details
-	job.Payload = &payload
 	job.UnserializedData = unserialized
 
+	// Drivers that don't track attempts themselves rely on the payload,
+	// which counts the attempts made before this one
+	if job.Attempts == 0 {
+		job.Attempts = payload.Attempts + 1
+	}
+
+	// Stop jobs that were already attempted too often, e.g. released ones
+	if w.exceedsMaxAttempts(job, &payload, false) {
+		logger.Error().Int("attempts", job.Attempts).Msg("Job exceeded its maximum attempts")
+		err := fmt.Errorf("%s has been attempted too many times", payload.DisplayName)
+		w.fail(ctx, job, err)
+		return err
+	}
+
+	// ShouldBeUniqueUntilProcessing jobs may be dispatched again once they start
+	w.releaseUniqueLock(ctx, job, true)
+
 	// Execute handler
 	var jobCtx context.Context
 	var cancel context.CancelFunc
@@ -134,68 +278,407 @@ func (w *Worker) handleJob(ctx context.Context, job *queue.Job) {
 	}
 	defer cancel()
 
-	err = handler(jobCtx, job)
+	stopHeartbeat := w.heartbeat(jobCtx, job)
+	err = runHandler(jobCtx, handler, job)
+	stopHeartbeat()
+	if delay, ok := queue.IsRelease(err); ok {
+		// The handler asked for a release, which is not a failure
+		logger.Info().Dur("delay", delay).Msg("Job released")
+		w.release(ctx, job, delay)
+		return nil
+	}
+
+	if errors.Is(jobCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		err = fmt.Errorf("%s has timed out: %w", payload.DisplayName, jobCtx.Err())
+		logger.Error().Err(err).Msg("Job timed out")
+		w.handleTimeout(ctx, job, payload, err, attemptOnce)
+		return err
+	}
+
 	if err != nil {
 		logger.Error().Err(err).Msg("Job failed")
-		w.handleFailure(ctx, payload, err)
+		w.handleFailure(ctx, job, payload, err, attemptOnce)
+		return err
+	}
+
+	// Job success. Like CallQueuedHandler::call, the unique lock is held
+	// until the next job of the chain is pushed.
+	if err := w.dispatchNextInChain(ctx, job); err != nil {
+		logger.Error().Err(err).Msg("Error dispatching the next job in the chain")
+		w.handleFailure(ctx, job, payload, err, attemptOnce)
+		return err
+	}
+	w.recordBatchJob(ctx, job, nil)
+	w.releaseUniqueLock(ctx, job, false)
+	w.forgetJobExceptions(job)
+	if ackErr := w.Driver.Ack(ctx, job); ackErr != nil {
+		logger.Error().Err(ackErr).Msg("Error acknowledging job")
 	} else {
-		// Job success
-		if ackErr := w.Driver.Ack(ctx, job); ackErr != nil {
-			logger.Error().Err(ackErr).Msg("Error acknowledging job")
-		} else {
-			logger.Info().Msg("Job processed successfully")
+		logger.Info().Msg("Job processed successfully")
+	}
+	return nil
+}
+
+// runHandler calls the handler, turning a panic into an error that keeps its stack trace
+func runHandler(ctx context.Context, handler queue.Handler, job *queue.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = queue.PanicError(r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// heartbeat extends the lease of the job every Heartbeat until the returned
+// function is called or ctx is done, e.g. when the job times out. Stopping
+// waits for an extension in flight, so it cannot undo a release or delete.
+func (w *Worker) heartbeat(ctx context.Context, job *queue.Job) (stop func()) {
+	extender, ok := w.Driver.(queue.LeaseExtender)
+	if !ok || w.Heartbeat <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(w.Heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := extender.ExtendLease(ctx, job, leaseHeartbeats*w.Heartbeat); err != nil && ctx.Err() == nil {
+					zerolog.Ctx(ctx).Warn().Err(err).Msg("Error extending the lease of the job")
+				}
+			}
 		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
 	}
 }
 
-func (w *Worker) handleFailure(ctx context.Context, payload queue.LaravelJob, err error) {
+// handleFailure fails the job if it used up its attempts or exceptions, or is
+// attempted once, otherwise it is released to be retried after its backoff
+func (w *Worker) handleFailure(ctx context.Context, job *queue.Job, payload queue.LaravelJob, err error, attemptOnce bool) {
 	logger := zerolog.Ctx(ctx)
 
-	// Increment attempts
-	payload.Attempts++
+	if attemptOnce || w.exceedsMaxAttempts(job, &payload, true) || w.exceedsMaxExceptions(ctx, &payload) {
+		logger.Error().Int("attempts", job.Attempts).Msg("Job failed permanently")
+		w.fail(ctx, job, err)
+		return
+	}
+
+	delay := w.backoff(job, &payload)
+	logger.Info().Int("attempt", job.Attempts).Dur("delay", delay).Msg("Retrying job")
+	w.release(ctx, job, delay)
+}
+
+// handleTimeout fails a job that timed out if it asked for it with
+// failOnTimeout, used up its attempts or is attempted once. Timeouts don't
+// count as exceptions.
+func (w *Worker) handleTimeout(ctx context.Context, job *queue.Job, payload queue.LaravelJob, err error, attemptOnce bool) {
+	if attemptOnce || payload.FailOnTimeout || w.exceedsMaxAttempts(job, &payload, true) {
+		w.fail(ctx, job, err)
+		return
+	}
+	w.release(ctx, job, w.backoff(job, &payload))
+}
+
+// exceedsMaxAttempts mirrors Worker::markJobAsFailedIfAlreadyExceedsMaxAttempts
+// and, once the current attempt has failed, markJobAsFailedIfWillExceedMaxAttempts.
+// Jobs with a retryUntil are retried until then, regardless of their tries.
+func (w *Worker) exceedsMaxAttempts(job *queue.Job, payload *queue.LaravelJob, attemptFailed bool) bool {
+	if until, ok := payload.RetryUntilTime(); ok {
+		if attemptFailed {
+			return !time.Now().Before(until)
+		}
+		return time.Now().After(until)
+	}
 
 	maxTries := 1 // default
 	if payload.MaxTries != nil {
 		maxTries = *payload.MaxTries
 	}
 
-	if payload.Attempts < maxTries {
-		logger.Info().Int("attempt", payload.Attempts).Int("max_tries", maxTries).Msg("Retrying job")
+	// A maxTries of 0 retries the job forever, like Laravel
+	if maxTries == 0 {
+		return false
+	}
+	if attemptFailed {
+		return job.Attempts >= maxTries
+	}
+	return job.Attempts > maxTries
+}
 
-		// Serialize back to JSON
-		body, marshalErr := json.Marshal(payload)
-		if marshalErr != nil {
-			logger.Error().Err(marshalErr).Msg("Error marshalling job for retry")
-			return
+// exceedsMaxExceptions counts the failure towards the job's maxExceptions
+// under Laravel's "job-exceptions:<uuid>" cache key, so PHP and Go workers
+// share the count when they share a cache store
+func (w *Worker) exceedsMaxExceptions(ctx context.Context, payload *queue.LaravelJob) bool {
+	if payload.MaxExceptions == nil || payload.UUID == "" {
+		return false
+	}
+
+	key := "job-exceptions:" + payload.UUID
+	count, err := w.incrementExceptions(ctx, key)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("Error counting job exceptions")
+		return false
+	}
+
+	if count < int64(*payload.MaxExceptions) {
+		return false
+	}
+
+	w.forgetExceptions(ctx, key)
+	return true
+}
+
+func (w *Worker) incrementExceptions(ctx context.Context, key string) (int64, error) {
+	if w.Cache == nil {
+		w.exceptionsMu.Lock()
+		defer w.exceptionsMu.Unlock()
+		if w.exceptions == nil {
+			w.exceptions = make(map[string]exceptionCount)
 		}
 
-		// Push back to queue
-		// Note: In a real Laravel system, we would respect 'backoff' (delayed queue).
-		// Here we just push back to the main list (immediate retry) for simplicity,
-		// or we could implement a sleep if we are blocking the worker (but that blocks the worker).
-		// Ideally the driver supports 'Release(..., delay)'.
-		// For MVP, we simply RPUSH (put at end of queue).
-		if pushErr := w.Driver.Push(ctx, w.QueueName, body); pushErr != nil {
-			logger.Error().Err(pushErr).Msg("Error pushing job back to queue")
+		// Counts of jobs that moved on to other workers are never forgotten
+		now := time.Now()
+		for k, c := range w.exceptions {
+			if !now.Before(c.expiresAt) {
+				delete(w.exceptions, k)
+			}
 		}
+
+		c := w.exceptions[key]
+		if c.count == 0 {
+			c.expiresAt = now.Add(exceptionsTTL)
+		}
+		c.count++
+		w.exceptions[key] = c
+		return c.count, nil
+	}
+
+	// Like Laravel, start from 0 with a one day expiry
+	current, err := w.Cache.Get(ctx, key)
+	if err != nil || current == "" {
+		current = "0"
+		if err := w.Cache.Put(ctx, key, current, exceptionsTTL); err != nil {
+			return 0, err
+		}
+	}
+
+	if incrementer, ok := w.Cache.(cache.Incrementer); ok {
+		return incrementer.Increment(ctx, key, 1)
+	}
+
+	count, _ := strconv.ParseInt(current, 10, 64)
+	count++
+	return count, w.Cache.Put(ctx, key, strconv.FormatInt(count, 10), exceptionsTTL)
+}
+
+func (w *Worker) forgetExceptions(ctx context.Context, key string) {
+	if w.Cache == nil {
+		w.exceptionsMu.Lock()
+		delete(w.exceptions, key)
+		w.exceptionsMu.Unlock()
+		return
+	}
+	if err := w.Cache.Forget(ctx, key); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("Error clearing job exceptions")
+	}
+}
+
+// forgetJobExceptions drops the in-memory exception count of a job that
+// completed or failed. Counts in a cache store expire instead, like in Laravel.
+func (w *Worker) forgetJobExceptions(job *queue.Job) {
+	if w.Cache != nil || job.Payload == nil || job.Payload.MaxExceptions == nil {
+		return
+	}
+	w.exceptionsMu.Lock()
+	defer w.exceptionsMu.Unlock()
+	delete(w.exceptions, "job-exceptions:"+job.Payload.UUID)
+}
+
+// backoff picks the delay before the next attempt from the job's backoff,
+// falling back to the worker's (Worker::calculateBackoff)
+func (w *Worker) backoff(job *queue.Job, payload *queue.LaravelJob) time.Duration {
+	backoff := payload.Backoff
+	if len(backoff) == 0 {
+		backoff = w.Backoff
+	}
+	return backoff.Delay(job.Attempts)
+}
+
+// release puts the job back onto its queue after delay
+func (w *Worker) release(ctx context.Context, job *queue.Job, delay time.Duration) {
+	logger := zerolog.Ctx(ctx)
+
+	// Prefer releasing the reserved job, the driver keeps counting its attempts
+	if releaser, ok := w.Driver.(queue.Releaser); ok {
+		if releaseErr := releaser.Release(ctx, job, delay); releaseErr != nil {
+			logger.Error().Err(releaseErr).Msg("Error releasing job back to queue")
+		}
+		return
+	}
+
+	// The driver cannot delay jobs, so push a copy back for an immediate
+	// retry, carrying the attempts in the payload
+	if delay > 0 {
+		logger.Warn().Dur("delay", delay).Msg("Driver does not support delayed release, retrying immediately")
+	}
+	body, marshalErr := queue.WithAttempts(job.Body, job.Attempts)
+	if marshalErr != nil {
+		logger.Error().Err(marshalErr).Msg("Error marshalling job for retry")
+		return
+	}
+	if pushErr := w.Driver.Push(ctx, w.queueFor(job), body); pushErr != nil {
+		logger.Error().Err(pushErr).Msg("Error pushing job back to queue")
+		return
+	}
+
+	// The attempt has been pushed back as a new job, so the original
+	// reservation can be removed.
+	if ackErr := w.Driver.Ack(ctx, job); ackErr != nil {
+		logger.Error().Err(ackErr).Msg("Error acknowledging released job")
+	}
+}
+
+// fail records the job as failed and removes it from the queue.
+// Like Laravel, the raw body is recorded so it can be retried as is.
+func (w *Worker) fail(ctx context.Context, job *queue.Job, err error) {
+	// Errors without a stack of their own are traced from here, so every
+	// failed job record has a stack trace like Laravel's
+	err = queue.WithStack(err)
+	w.logFailed(ctx, job, job.Body, err)
+	w.releaseUniqueLock(ctx, job, false)
+	w.recordBatchJob(ctx, job, err)
+	w.forgetJobExceptions(job)
+
+	if ackErr := w.Driver.Ack(ctx, job); ackErr != nil {
+		zerolog.Ctx(ctx).Error().Err(ackErr).Msg("Error acknowledging failed job")
+	}
+}
+
+// releaseUniqueLock releases the lock PHP acquired when dispatching a unique
+// job, like CallQueuedHandler::ensureUniqueJobLockIsReleased. processing tells
+// whether the job is about to start, which only releases the lock of
+// ShouldBeUniqueUntilProcessing jobs; otherwise only ShouldBeUnique locks are released.
+func (w *Worker) releaseUniqueLock(ctx context.Context, job *queue.Job, processing bool) {
+	if w.Locks == nil || job.Payload == nil {
+		return
+	}
+	opts, ok := queue.GetUniqueOptions(job.Payload.DisplayName)
+	if !ok || opts.UntilProcessing != processing {
+		return
+	}
+
+	key := queue.UniqueLockKey(job.Payload.DisplayName, opts.UniqueID(job))
+	if err := w.Locks.ForceReleaseLock(ctx, key); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("lock", key).Msg("Error releasing unique job lock")
+	}
+}
+
+// dispatchNextInChain pushes the next job of the chain job belongs to, like
+// CallQueuedHandler::ensureNextJobInChainIsDispatched. Chain catch callbacks
+// are closures and can only be run by PHP, so they are passed along untouched.
+func (w *Worker) dispatchNextInChain(ctx context.Context, job *queue.Job) error {
+	next, err := queue.NextInChain(job)
+	if err != nil || next == nil {
+		return err
+	}
+
+	driver := w.Driver
+	if next.Connection != "" && next.Connection != w.Connection {
+		if w.Connections == nil {
+			return fmt.Errorf("no driver for queue connection %q of the next chained job", next.Connection)
+		}
+		if driver, err = w.Connections(next.Connection); err != nil {
+			return err
+		}
+	}
+
+	queueName := next.Queue
+	if queueName == "" {
+		queueName = "default"
+	}
+	return driver.Push(ctx, queueName, next.Body)
+}
+
+// recordBatchJob updates the batch of a completed job, like
+// CallQueuedHandler::ensureSuccessfulBatchJobIsRecorded when err is nil and
+// ensureFailedBatchJobIsRecorded otherwise
+func (w *Worker) recordBatchJob(ctx context.Context, job *queue.Job, err error) {
+	if w.Batches == nil || job.Payload == nil {
+		return
+	}
+	batchID, ok := job.GetArg("batchId").(string)
+	if !ok || batchID == "" {
+		return
+	}
+
+	var recordErr error
+	if err == nil {
+		recordErr = w.Batches.RecordSuccessfulJob(ctx, batchID, job.Payload.UUID)
 	} else {
-		logger.Error().Int("attempts", payload.Attempts).Msg("Job failed permanently")
+		recordErr = w.Batches.RecordFailedJob(ctx, batchID, job.Payload.UUID, err)
+	}
+	if recordErr != nil {
+		zerolog.Ctx(ctx).Error().Err(recordErr).Str("batch_id", batchID).Msg("Error recording batched job")
+	}
+}
+
+// handleUnprocessable deals with jobs the worker cannot run at all: bodies
+// that are not valid JSON and jobs without a registered handler. Depending on
+// w.UnhandledJobs they are either failed or released for another worker.
+func (w *Worker) handleUnprocessable(ctx context.Context, job *queue.Job, err error) {
+	logger := zerolog.Ctx(ctx)
 
-		// Serialize payload
-		body, marshalErr := json.Marshal(payload)
-		if marshalErr != nil {
-			logger.Error().Err(marshalErr).Msg("Error marshalling job for failure")
+	if w.UnhandledJobs == ReleaseUnhandled {
+		// Leave the job for another worker, e.g. `php artisan queue:work`
+		if releaser, ok := w.Driver.(queue.Releaser); ok {
+			if releaseErr := releaser.Release(ctx, job, unhandledReleaseDelay); releaseErr != nil {
+				logger.Error().Err(releaseErr).Msg("Error releasing unhandled job")
+			}
 			return
 		}
 
-		if w.FailedProvider != nil {
-			// Using "redis" (or driver name) as connection name is a simplification.
-			// Ideally we know the connection name from config.
-			if failErr := w.FailedProvider.Log(ctx, "redis", w.QueueName, body, err.Error()); failErr != nil {
-				logger.Error().Err(failErr).Msg("Error logging failed job")
-			}
-		} else {
-			logger.Error().Msg("No failed job provider configured. Job lost")
+		if pushErr := w.Driver.Push(ctx, w.queueFor(job), job.Body); pushErr != nil {
+			logger.Error().Err(pushErr).Msg("Error pushing unhandled job back to queue")
+			return
 		}
+		if ackErr := w.Driver.Ack(ctx, job); ackErr != nil {
+			logger.Error().Err(ackErr).Msg("Error acknowledging unhandled job")
+		}
+		return
+	}
+
+	w.fail(ctx, job, err)
+}
+
+// logFailed records a job that will not be retried with the failed job provider
+func (w *Worker) logFailed(ctx context.Context, job *queue.Job, body []byte, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	if w.FailedProvider == nil {
+		logger.Error().Msg("No failed job provider configured. Job lost")
+		return
+	}
+
+	if failErr := w.FailedProvider.Log(ctx, w.Connection, w.queueFor(job), body, queue.FormatException(err)); failErr != nil {
+		logger.Error().Err(failErr).Msg("Error logging failed job")
+	}
+}
+
+// queueFor returns the queue a job came from, defaulting to the worker's first queue
+func (w *Worker) queueFor(job *queue.Job) string {
+	if job.Queue != "" {
+		return job.Queue
 	}
+	return w.Queues[0]
 }
@@ -4,17 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/pixelvide/laravel-go/pkg/encryption"
 	"github.com/pixelvide/laravel-go/pkg/queue"
 )
 
 // MockDriver implements queue.Driver for testing
 type MockDriver struct {
-	Queue    []queue.Job
-	Pushed   []queue.Job
-	FailCall []queue.Job
+	Queue  []queue.Job
+	Pushed []queue.Job
 }
 
 func (m *MockDriver) Pop(ctx context.Context, queueName string) (*queue.Job, error) {
@@ -23,12 +26,13 @@ func (m *MockDriver) Pop(ctx context.Context, queueName string) (*queue.Job, err
 		m.Queue = m.Queue[1:]
 		return &job, nil
 	}
-	// Simulate blocking or return error to stop worker loop in test
-	return nil, context.DeadlineExceeded
+	// Simulate blocking until the worker is stopped
+	<-ctx.Done()
+	return nil, ctx.Err()
 }
 
 func (m *MockDriver) Push(ctx context.Context, queueName string, body []byte) error {
-	m.Pushed = append(m.Pushed, queue.Job{Body: body})
+	m.Pushed = append(m.Pushed, queue.Job{Queue: queueName, Body: body})
 	return nil
 }
 
@@ -36,8 +40,20 @@ func (m *MockDriver) Ack(ctx context.Context, job *queue.Job) error {
 	return nil
 }
 
-func (m *MockDriver) Fail(ctx context.Context, queueName string, body []byte, err error) error {
-	m.FailCall = append(m.FailCall, queue.Job{Body: body})
+// ReleasingDriver is a MockDriver that supports delayed release
+type ReleasingDriver struct {
+	MockDriver
+	Released []time.Duration
+	Acked    int
+}
+
+func (m *ReleasingDriver) Release(ctx context.Context, job *queue.Job, delay time.Duration) error {
+	m.Released = append(m.Released, delay)
+	return nil
+}
+
+func (m *ReleasingDriver) Ack(ctx context.Context, job *queue.Job) error {
+	m.Acked++
 	return nil
 }
 
@@ -124,3 +140,807 @@ func TestWorker_Run_Retry(t *testing.T) {
 		}
 	}
 }
+
+func TestWorker_Run_ReleaseWithBackoff(t *testing.T) {
+	jobName := "BackoffJob"
+	queue.Register(jobName, func(ctx context.Context, job *queue.Job) error {
+		return errors.New("failed")
+	})
+
+	// Laravel serializes array backoffs as a comma separated string
+	body := []byte(`{"uuid":"789","displayName":"BackoffJob","maxTries":3,"backoff":"5,30","attempts":1}`)
+
+	driver := &ReleasingDriver{
+		MockDriver: MockDriver{Queue: []queue.Job{{Body: body}}},
+	}
+
+	w := NewWorker(driver, nil, "default", 1, "test-app", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	// Second attempt uses the second backoff value
+	if len(driver.Released) != 1 || driver.Released[0] != 30*time.Second {
+		t.Errorf("Expected job to be released with a 30s delay, got %v", driver.Released)
+	}
+	if len(driver.Pushed) != 0 {
+		t.Errorf("Expected no pushed jobs when the driver can release, got %d", len(driver.Pushed))
+	}
+	if driver.Acked != 0 {
+		t.Errorf("Expected released job not to be acknowledged, got %d acks", driver.Acked)
+	}
+}
+
+// QueueMockDriver holds separate queues and records the order they are polled in
+type QueueMockDriver struct {
+	MockDriver
+	mu     sync.Mutex
+	Queues map[string][]queue.Job
+	Polled []string
+}
+
+func (m *QueueMockDriver) Pop(ctx context.Context, queueName string) (*queue.Job, error) {
+	m.mu.Lock()
+	m.Polled = append(m.Polled, queueName)
+	if jobs := m.Queues[queueName]; len(jobs) > 0 {
+		job := jobs[0]
+		m.Queues[queueName] = jobs[1:]
+		m.mu.Unlock()
+		job.Queue = queueName
+		return &job, nil
+	}
+	m.mu.Unlock()
+
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestWorker_Run_QueuePriority(t *testing.T) {
+	jobName := "PriorityJob"
+	var mu sync.Mutex
+	var handled []string
+	queue.Register(jobName, func(ctx context.Context, job *queue.Job) error {
+		mu.Lock()
+		handled = append(handled, job.Queue)
+		mu.Unlock()
+		return nil
+	})
+
+	body, _ := json.Marshal(queue.LaravelJob{UUID: "1", DisplayName: jobName})
+
+	driver := &QueueMockDriver{
+		Queues: map[string][]queue.Job{
+			"high": {{Body: body}},
+			"low":  {{Body: body}},
+		},
+	}
+
+	w := NewWorker(driver, nil, "high, low", 1, "test-app", nil)
+	if len(w.Queues) != 2 || w.Queues[0] != "high" || w.Queues[1] != "low" {
+		t.Fatalf("Expected queues [high low], got %v", w.Queues)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 2 || handled[0] != "high" || handled[1] != "low" {
+		t.Errorf("Expected jobs to be handled in priority order [high low], got %v", handled)
+	}
+}
+
+// MockFailedProvider records logged failed jobs
+type MockFailedProvider struct {
+	Connections []string
+	Logged      [][]byte
+	Exceptions  []string
+}
+
+func (m *MockFailedProvider) Log(ctx context.Context, connection string, queueName string, payload []byte, exception string) error {
+	m.Connections = append(m.Connections, connection)
+	m.Logged = append(m.Logged, payload)
+	m.Exceptions = append(m.Exceptions, exception)
+	return nil
+}
+
+func (m *MockFailedProvider) All(ctx context.Context, filter queue.FailedJobFilter) ([]queue.FailedJob, error) {
+	return nil, nil
+}
+
+func (m *MockFailedProvider) Find(ctx context.Context, id string) (*queue.FailedJob, error) {
+	return nil, nil
+}
+
+func (m *MockFailedProvider) Forget(ctx context.Context, id string) (bool, error) {
+	return false, nil
+}
+
+func (m *MockFailedProvider) Flush(ctx context.Context, hours int) error {
+	return nil
+}
+
+func TestWorker_Run_FailsUnhandledJobs(t *testing.T) {
+	driver := &ReleasingDriver{
+		MockDriver: MockDriver{Queue: []queue.Job{
+			{Body: []byte(`not json`)},
+			{Body: []byte(`{"uuid":"abc","displayName":"App\\Jobs\\Unregistered","attempts":0}`)},
+		}},
+	}
+	failed := &MockFailedProvider{}
+
+	w := NewWorker(driver, failed, "default", 1, "test-app", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if len(failed.Logged) != 2 {
+		t.Fatalf("Expected 2 failed jobs, got %d", len(failed.Logged))
+	}
+	if string(failed.Logged[0]) != "not json" {
+		t.Errorf("Expected the original body to be logged, got %q", failed.Logged[0])
+	}
+	if !strings.Contains(failed.Exceptions[0], "unable to decode job payload") {
+		t.Errorf("Unexpected exception for unparseable job: %s", failed.Exceptions[0])
+	}
+	if !strings.Contains(failed.Exceptions[1], "App\\Jobs\\Unregistered") {
+		t.Errorf("Expected the exception to name the job, got: %s", failed.Exceptions[1])
+	}
+	if driver.Acked != 2 {
+		t.Errorf("Expected both jobs to be acknowledged, got %d acks", driver.Acked)
+	}
+	if len(driver.Released) != 0 {
+		t.Errorf("Expected no released jobs, got %v", driver.Released)
+	}
+}
+
+func TestWorker_Run_ReleasesUnhandledJobs(t *testing.T) {
+	driver := &ReleasingDriver{
+		MockDriver: MockDriver{Queue: []queue.Job{
+			{Body: []byte(`{"uuid":"abc","displayName":"App\\Jobs\\HandledByPHP","attempts":0}`)},
+		}},
+	}
+	failed := &MockFailedProvider{}
+
+	w := NewWorker(driver, failed, "default", 1, "test-app", nil)
+	w.UnhandledJobs = ReleaseUnhandled
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if len(driver.Released) != 1 || driver.Released[0] != unhandledReleaseDelay {
+		t.Errorf("Expected job to be released with a %s delay, got %v", unhandledReleaseDelay, driver.Released)
+	}
+	if len(failed.Logged) != 0 {
+		t.Errorf("Expected no failed jobs, got %d", len(failed.Logged))
+	}
+	if driver.Acked != 0 {
+		t.Errorf("Expected released job not to be acknowledged, got %d acks", driver.Acked)
+	}
+}
+
+func TestWorker_Run_FailsPanickingJobWithStack(t *testing.T) {
+	queue.Register("PanicJob", func(ctx context.Context, job *queue.Job) error {
+		panic("kaboom")
+	})
+
+	driver := &MockDriver{
+		Queue: []queue.Job{{Body: []byte(`{"uuid":"abc","displayName":"PanicJob","attempts":0}`)}},
+	}
+	failed := &MockFailedProvider{}
+
+	w := NewWorker(driver, failed, "default", 1, "test-app", nil)
+	w.Connection = "database"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if len(failed.Logged) != 1 {
+		t.Fatalf("Expected 1 failed job, got %d", len(failed.Logged))
+	}
+	if failed.Connections[0] != "database" {
+		t.Errorf("Expected connection to be database, got %s", failed.Connections[0])
+	}
+	if !strings.Contains(failed.Exceptions[0], "panic: kaboom") || !strings.Contains(failed.Exceptions[0], "Stack trace:") {
+		t.Errorf("Expected exception with a stack trace, got: %s", failed.Exceptions[0])
+	}
+}
+
+func TestWorker_Run_FailsJobWithStack(t *testing.T) {
+	queue.Register("PlainErrorJob", func(ctx context.Context, job *queue.Job) error {
+		return errors.New("plain failure")
+	})
+
+	driver := &MockDriver{
+		Queue: []queue.Job{{Body: []byte(`{"uuid":"abc","displayName":"PlainErrorJob","attempts":0}`)}},
+	}
+	failed := &MockFailedProvider{}
+
+	w := NewWorker(driver, failed, "default", 1, "test-app", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if len(failed.Logged) != 1 {
+		t.Fatalf("Expected 1 failed job, got %d", len(failed.Logged))
+	}
+	if !strings.HasPrefix(failed.Exceptions[0], "*errors.errorString: plain failure in ") || !strings.Contains(failed.Exceptions[0], "Stack trace:") {
+		t.Errorf("Expected exception with a stack trace, got: %s", failed.Exceptions[0])
+	}
+}
+
+func TestWorker_Run_UsesDriverAttempts(t *testing.T) {
+	var seen int
+	queue.Register("DriverAttemptsJob", func(ctx context.Context, job *queue.Job) error {
+		seen = job.Attempts
+		return errors.New("failed")
+	})
+
+	// The payload says no attempts were made, but the driver reserved it for the third time
+	driver := &ReleasingDriver{
+		MockDriver: MockDriver{Queue: []queue.Job{{
+			Body:     []byte(`{"uuid":"abc","displayName":"DriverAttemptsJob","maxTries":3,"attempts":0}`),
+			Attempts: 3,
+		}}},
+	}
+	failed := &MockFailedProvider{}
+
+	w := NewWorker(driver, failed, "default", 1, "test-app", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if seen != 3 {
+		t.Errorf("Expected the handler to see 3 attempts, got %d", seen)
+	}
+	if len(driver.Released) != 0 {
+		t.Errorf("Expected the job not to be released after its last try, got %v", driver.Released)
+	}
+	if len(failed.Logged) != 1 {
+		t.Errorf("Expected the job to be failed, got %d failed jobs", len(failed.Logged))
+	}
+}
+
+func TestWorker_Run_RetryUntil(t *testing.T) {
+	queue.Register("RetryUntilJob", func(ctx context.Context, job *queue.Job) error {
+		return errors.New("failed")
+	})
+
+	future := time.Now().Add(time.Hour).Unix()
+	past := time.Now().Add(-time.Minute).Unix()
+
+	// retryUntil wins over maxTries; timeoutAt is its legacy name
+	driver := &ReleasingDriver{
+		MockDriver: MockDriver{Queue: []queue.Job{
+			{Body: []byte(fmt.Sprintf(`{"uuid":"a","displayName":"RetryUntilJob","maxTries":1,"retryUntil":%d}`, future)), Attempts: 5},
+			{Body: []byte(fmt.Sprintf(`{"uuid":"b","displayName":"RetryUntilJob","maxTries":10,"timeoutAt":%d}`, past)), Attempts: 1},
+		}},
+	}
+	failed := &MockFailedProvider{}
+
+	w := NewWorker(driver, failed, "default", 1, "test-app", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if len(driver.Released) != 1 {
+		t.Errorf("Expected the job within retryUntil to be released, got %v", driver.Released)
+	}
+	if len(failed.Logged) != 1 || !strings.Contains(string(failed.Logged[0]), `"uuid":"b"`) {
+		t.Errorf("Expected the expired job to be failed, got %d failed jobs", len(failed.Logged))
+	}
+}
+
+func TestWorker_Run_FailOnTimeout(t *testing.T) {
+	queue.Register("TimeoutJob", func(ctx context.Context, job *queue.Job) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	driver := &ReleasingDriver{
+		MockDriver: MockDriver{Queue: []queue.Job{
+			{Body: []byte(`{"uuid":"a","displayName":"TimeoutJob","maxTries":3,"timeout":1,"failOnTimeout":true}`)},
+		}},
+	}
+	failed := &MockFailedProvider{}
+
+	w := NewWorker(driver, failed, "default", 1, "test-app", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if len(failed.Logged) != 1 {
+		t.Fatalf("Expected the timed out job to be failed, got %d failed jobs", len(failed.Logged))
+	}
+	if !strings.Contains(failed.Exceptions[0], "TimeoutJob has timed out") {
+		t.Errorf("Unexpected exception: %s", failed.Exceptions[0])
+	}
+	if len(driver.Released) != 0 {
+		t.Errorf("Expected no release, got %v", driver.Released)
+	}
+}
+
+// LeasingDriver is a ReleasingDriver that records lease extensions
+type LeasingDriver struct {
+	ReleasingDriver
+	mu       sync.Mutex
+	Extended []time.Duration
+	AckedAt  int // Extensions made before the job was acknowledged
+}
+
+func (m *LeasingDriver) Ack(ctx context.Context, job *queue.Job) error {
+	m.AckedAt = m.extensions()
+	return m.ReleasingDriver.Ack(ctx, job)
+}
+
+func (m *LeasingDriver) ExtendLease(ctx context.Context, job *queue.Job, d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Extended = append(m.Extended, d)
+	return nil
+}
+
+func (m *LeasingDriver) extensions() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.Extended)
+}
+
+func TestWorker_Run_ExtendsLeaseOfRunningJobs(t *testing.T) {
+	queue.Register("LongRunningJob", func(ctx context.Context, job *queue.Job) error {
+		time.Sleep(250 * time.Millisecond)
+		return nil
+	})
+	queue.Register("TimingOutJob", func(ctx context.Context, job *queue.Job) error {
+		// Ignores its timeout, like a handler stuck in a blocking call
+		time.Sleep(2500 * time.Millisecond)
+		return nil
+	})
+
+	driver := &LeasingDriver{}
+	driver.Queue = []queue.Job{{Body: []byte(`{"uuid":"a","displayName":"LongRunningJob"}`)}}
+
+	w := NewWorker(driver, nil, "default", 1, "test-app", nil)
+	w.Heartbeat = 50 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if driver.AckedAt < 3 {
+		t.Fatalf("Expected the lease to be extended while the job ran, got %d extensions", driver.AckedAt)
+	}
+	if driver.Extended[0] != 150*time.Millisecond {
+		t.Errorf("Expected each extension to last three heartbeats, got %v", driver.Extended[0])
+	}
+	if driver.extensions() != driver.AckedAt {
+		t.Errorf("Expected no extensions after the job was acknowledged, got %d more", driver.extensions()-driver.AckedAt)
+	}
+	if driver.Acked != 1 {
+		t.Errorf("Expected the job to be acknowledged, got %d acks", driver.Acked)
+	}
+
+	// The heartbeat stops when the job times out, even if the handler keeps running
+	driver = &LeasingDriver{}
+	driver.Queue = []queue.Job{{Body: []byte(`{"uuid":"b","displayName":"TimingOutJob","timeout":1}`)}}
+
+	w = NewWorker(driver, nil, "default", 1, "test-app", nil)
+	w.Heartbeat = 100 * time.Millisecond
+
+	ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if n := driver.extensions(); n < 5 || n > 11 {
+		t.Errorf("Expected the lease to be extended until the job timed out, got %d extensions", n)
+	}
+}
+
+func TestWorker_Run_MaxExceptions(t *testing.T) {
+	queue.Register("MaxExceptionsJob", func(ctx context.Context, job *queue.Job) error {
+		return errors.New("failed")
+	})
+
+	body := []byte(`{"uuid":"a","displayName":"MaxExceptionsJob","maxTries":10,"maxExceptions":2}`)
+	driver := &ReleasingDriver{
+		MockDriver: MockDriver{Queue: []queue.Job{
+			{Body: body, Attempts: 1},
+			{Body: body, Attempts: 2},
+		}},
+	}
+	failed := &MockFailedProvider{}
+
+	w := NewWorker(driver, failed, "default", 1, "test-app", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if len(driver.Released) != 1 {
+		t.Errorf("Expected the first exception to release the job, got %v", driver.Released)
+	}
+	if len(failed.Logged) != 1 {
+		t.Errorf("Expected the second exception to fail the job, got %d failed jobs", len(failed.Logged))
+	}
+}
+
+func TestWorker_Run_ForgetsExceptionsOfFinishedJobs(t *testing.T) {
+	attempts := 0
+	queue.Register("FlakyJob", func(ctx context.Context, job *queue.Job) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	body := []byte(`{"uuid":"a","displayName":"FlakyJob","maxTries":10,"maxExceptions":5}`)
+	driver := &ReleasingDriver{
+		MockDriver: MockDriver{Queue: []queue.Job{
+			{Body: body, Attempts: 1},
+			{Body: body, Attempts: 2},
+		}},
+	}
+
+	w := NewWorker(driver, &MockFailedProvider{}, "default", 1, "test-app", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if len(driver.Released) != 1 || attempts != 2 {
+		t.Fatalf("Expected the job to succeed on its second attempt, got %d attempts and %v releases", attempts, driver.Released)
+	}
+	if len(w.exceptions) != 0 {
+		t.Errorf("Expected the exception count of the completed job to be forgotten, got %v", w.exceptions)
+	}
+}
+
+func TestWorker_Run_ReleaseAfter(t *testing.T) {
+	queue.Register("ReleasingJob", func(ctx context.Context, job *queue.Job) error {
+		return queue.ReleaseAfter(10 * time.Second)
+	})
+
+	body := []byte(`{"uuid":"a","displayName":"ReleasingJob","maxTries":1,"maxExceptions":1}`)
+	driver := &ReleasingDriver{
+		MockDriver: MockDriver{Queue: []queue.Job{
+			{Body: body, Attempts: 1},
+			{Body: body, Attempts: 2},
+		}},
+	}
+	failed := &MockFailedProvider{}
+
+	w := NewWorker(driver, failed, "default", 1, "test-app", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	// Releasing is not an exception, but the released job has no tries left
+	if len(driver.Released) != 1 || driver.Released[0] != 10*time.Second {
+		t.Errorf("Expected the job to be released with a 10s delay, got %v", driver.Released)
+	}
+	if len(failed.Logged) != 1 || !strings.Contains(failed.Exceptions[0], "attempted too many times") {
+		t.Errorf("Expected the second attempt to fail for exceeding its tries, got %v", failed.Exceptions)
+	}
+}
+
+// MockLockStore implements cache.LockStore for testing
+type MockLockStore struct {
+	Released []string
+}
+
+func (m *MockLockStore) GetLock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (m *MockLockStore) ReleaseLock(ctx context.Context, key string) error {
+	m.Released = append(m.Released, key)
+	return nil
+}
+
+func (m *MockLockStore) ForceReleaseLock(ctx context.Context, key string) error {
+	m.Released = append(m.Released, key)
+	return nil
+}
+
+func TestWorker_Run_ReleasesUniqueLock(t *testing.T) {
+	var releasedBeforeRun []string
+	locks := &MockLockStore{}
+
+	queue.Register("UniqueJob", func(ctx context.Context, job *queue.Job) error {
+		releasedBeforeRun = append([]string(nil), locks.Released...)
+		return nil
+	}, queue.ShouldBeUnique(func(job *queue.Job) string { return "42" }))
+	queue.Register("UniqueUntilProcessingJob", func(ctx context.Context, job *queue.Job) error {
+		releasedBeforeRun = append([]string(nil), locks.Released...)
+		return nil
+	}, queue.ShouldBeUniqueUntilProcessing(func(job *queue.Job) string { return "42" }))
+
+	tests := []struct {
+		name          string
+		beforeRunning int
+	}{
+		{"UniqueJob", 0},
+		{"UniqueUntilProcessingJob", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locks.Released = nil
+			body := []byte(`{"uuid":"a","displayName":"` + tt.name + `"}`)
+			driver := &MockDriver{Queue: []queue.Job{{Body: body}}}
+
+			w := NewWorker(driver, nil, "default", 1, "test-app", nil)
+			w.Locks = locks
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			w.Run(ctx)
+
+			want := "laravel_unique_job:" + tt.name + "42"
+			if len(locks.Released) != 1 || locks.Released[0] != want {
+				t.Errorf("Expected lock %q to be released once, got %v", want, locks.Released)
+			}
+			if len(releasedBeforeRun) != tt.beforeRunning {
+				t.Errorf("Expected %d locks released before the handler ran, got %v", tt.beforeRunning, releasedBeforeRun)
+			}
+		})
+	}
+}
+
+func TestWorker_Run_ReleasesUniqueLockOfFailedJob(t *testing.T) {
+	queue.Register("FailingUniqueJob", func(ctx context.Context, job *queue.Job) error {
+		return errors.New("failed")
+	}, queue.ShouldBeUnique(nil))
+
+	// The uniqueId property of the serialized command is used by default
+	command := `O:16:"FailingUniqueJob":1:{s:8:"uniqueId";s:2:"42";}`
+	data, _ := json.Marshal(map[string]string{"commandName": "FailingUniqueJob", "command": command})
+	body, _ := json.Marshal(queue.LaravelJob{UUID: "a", DisplayName: "FailingUniqueJob", Data: data})
+
+	driver := &MockDriver{Queue: []queue.Job{{Body: body}}}
+	locks := &MockLockStore{}
+
+	w := NewWorker(driver, &MockFailedProvider{}, "default", 1, "test-app", nil)
+	w.Locks = locks
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if len(locks.Released) != 1 || locks.Released[0] != "laravel_unique_job:FailingUniqueJob42" {
+		t.Errorf("Expected the unique lock to be released when the job failed, got %v", locks.Released)
+	}
+}
+
+func TestWorker_Run_DispatchesNextJobInChain(t *testing.T) {
+	queue.Register("FirstChainedJob", func(ctx context.Context, job *queue.Job) error {
+		return nil
+	})
+
+	// Bus::chain([new FirstChainedJob, new SecondChainedJob])->onQueue('chains')
+	command := `O:15:"FirstChainedJob":3:{s:10:"chainQueue";s:6:"chains";s:5:"queue";s:6:"chains";s:7:"chained";a:1:{i:0;s:40:"O:16:"SecondChainedJob":1:{s:1:"n";i:2;}";}}`
+	data, _ := json.Marshal(map[string]string{"commandName": "FirstChainedJob", "command": command})
+	body, _ := json.Marshal(queue.LaravelJob{UUID: "a", DisplayName: "FirstChainedJob", Data: data})
+
+	driver := &MockDriver{Queue: []queue.Job{{Body: body}}}
+	w := NewWorker(driver, nil, "default", 1, "test-app", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if len(driver.Pushed) != 1 {
+		t.Fatalf("Expected the next job of the chain to be pushed, got %d jobs", len(driver.Pushed))
+	}
+	var next queue.LaravelJob
+	if err := json.Unmarshal(driver.Pushed[0].Body, &next); err != nil {
+		t.Fatalf("Invalid payload pushed: %v", err)
+	}
+	if next.DisplayName != "SecondChainedJob" {
+		t.Errorf("Expected SecondChainedJob to be pushed, got %s", next.DisplayName)
+	}
+	if driver.Pushed[0].Queue != "chains" {
+		t.Errorf("Expected the next job to be pushed onto the chain queue, got %q", driver.Pushed[0].Queue)
+	}
+}
+
+// LockCheckingDriver records which unique locks were released when a job was pushed
+type LockCheckingDriver struct {
+	MockDriver
+	Locks          *MockLockStore
+	ReleasedAtPush []string
+}
+
+func (m *LockCheckingDriver) Push(ctx context.Context, queueName string, body []byte) error {
+	m.ReleasedAtPush = append([]string(nil), m.Locks.Released...)
+	return m.MockDriver.Push(ctx, queueName, body)
+}
+
+func TestWorker_Run_HoldsUniqueLockUntilChainIsDispatched(t *testing.T) {
+	queue.Register("UniqueChainedJob", func(ctx context.Context, job *queue.Job) error {
+		return nil
+	}, queue.ShouldBeUnique(func(job *queue.Job) string { return "42" }))
+
+	command := `O:16:"UniqueChainedJob":1:{s:7:"chained";a:1:{i:0;s:40:"O:16:"SecondChainedJob":1:{s:1:"n";i:2;}";}}`
+	data, _ := json.Marshal(map[string]string{"commandName": "UniqueChainedJob", "command": command})
+	body, _ := json.Marshal(queue.LaravelJob{UUID: "a", DisplayName: "UniqueChainedJob", Data: data})
+
+	locks := &MockLockStore{}
+	driver := &LockCheckingDriver{MockDriver: MockDriver{Queue: []queue.Job{{Body: body}}}, Locks: locks}
+
+	w := NewWorker(driver, nil, "default", 1, "test-app", nil)
+	w.Locks = locks
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if len(driver.Pushed) != 1 {
+		t.Fatalf("Expected the next job of the chain to be pushed, got %d jobs", len(driver.Pushed))
+	}
+	if len(driver.ReleasedAtPush) != 0 {
+		t.Errorf("Expected the unique lock to be held while the chain was dispatched, got %v released", driver.ReleasedAtPush)
+	}
+	if len(locks.Released) != 1 || locks.Released[0] != "laravel_unique_job:UniqueChainedJob42" {
+		t.Errorf("Expected the unique lock to be released after the chain was dispatched, got %v", locks.Released)
+	}
+}
+
+// MockBatchRecorder implements BatchRecorder for testing
+type MockBatchRecorder struct {
+	Succeeded []string
+	Failed    []string
+}
+
+func (m *MockBatchRecorder) RecordSuccessfulJob(ctx context.Context, batchID string, jobID string) error {
+	m.Succeeded = append(m.Succeeded, batchID+"/"+jobID)
+	return nil
+}
+
+func (m *MockBatchRecorder) RecordFailedJob(ctx context.Context, batchID string, jobID string, err error) error {
+	m.Failed = append(m.Failed, batchID+"/"+jobID)
+	return nil
+}
+
+func TestWorker_Run_RecordsBatchedJobs(t *testing.T) {
+	queue.Register("BatchedJob", func(ctx context.Context, job *queue.Job) error {
+		if job.GetArg("fail") == true {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	batched := func(uuid string, fail bool) []byte {
+		command := fmt.Sprintf(`O:10:"BatchedJob":2:{s:7:"batchId";s:7:"batch-1";s:4:"fail";b:%d;}`, map[bool]int{false: 0, true: 1}[fail])
+		data, _ := json.Marshal(map[string]string{"commandName": "BatchedJob", "command": command})
+		body, _ := json.Marshal(queue.LaravelJob{UUID: uuid, DisplayName: "BatchedJob", Data: data})
+		return body
+	}
+
+	driver := &MockDriver{Queue: []queue.Job{{Body: batched("job-1", false)}, {Body: batched("job-2", true)}}}
+	batches := &MockBatchRecorder{}
+
+	w := NewWorker(driver, &MockFailedProvider{}, "default", 1, "test-app", nil)
+	w.Batches = batches
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if len(batches.Succeeded) != 1 || batches.Succeeded[0] != "batch-1/job-1" {
+		t.Errorf("Expected job-1 to be recorded as successful, got %v", batches.Succeeded)
+	}
+	if len(batches.Failed) != 1 || batches.Failed[0] != "batch-1/job-2" {
+		t.Errorf("Expected job-2 to be recorded as failed, got %v", batches.Failed)
+	}
+}
+
+func TestWorker_Run_DecryptsEncryptedJobs(t *testing.T) {
+	encrypter, err := encryption.NewEncrypter([]byte("abcdefghijklmnopqrstuvwxyz123456"), "AES-256-CBC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var token any
+	queue.Register("EncryptedJob", func(ctx context.Context, job *queue.Job) error {
+		token = job.GetArg("token")
+		return nil
+	})
+
+	command, err := encrypter.Encrypt(`O:12:"EncryptedJob":1:{s:5:"token";s:6:"s3cret";}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(map[string]string{"commandName": "EncryptedJob", "command": command})
+	body, _ := json.Marshal(queue.LaravelJob{UUID: "a", DisplayName: "EncryptedJob", Data: data})
+
+	driver := &MockDriver{Queue: []queue.Job{{Body: body}}}
+	w := NewWorker(driver, nil, "default", 1, "test-app", nil)
+	w.Encrypter = encrypter
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if token != "s3cret" {
+		t.Errorf("Expected the handler to see the decrypted token, got %v", token)
+	}
+}
+
+func TestWorker_Run_FailsJobsThatCannotBeDecrypted(t *testing.T) {
+	encrypter, err := encryption.NewEncrypter([]byte("abcdefghijklmnopqrstuvwxyz123456"), "AES-256-CBC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongKey, err := encryption.NewEncrypter([]byte("0123456789abcdef0123456789abcdef"), "AES-256-CBC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handled := false
+	queue.Register("WrongKeyJob", func(ctx context.Context, job *queue.Job) error {
+		handled = true
+		return nil
+	})
+
+	command, err := encrypter.Encrypt(`O:11:"WrongKeyJob":1:{s:5:"token";s:6:"s3cret";}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(map[string]string{"commandName": "WrongKeyJob", "command": command})
+	body, _ := json.Marshal(queue.LaravelJob{UUID: "a", DisplayName: "WrongKeyJob", Data: data})
+
+	driver := &ReleasingDriver{MockDriver: MockDriver{Queue: []queue.Job{{Body: body}}}}
+	failed := &MockFailedProvider{}
+	w := NewWorker(driver, failed, "default", 1, "test-app", nil)
+	w.Encrypter = wrongKey
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	if handled {
+		t.Error("Expected the handler not to run without the decrypted command")
+	}
+	if len(failed.Logged) != 1 {
+		t.Fatalf("Expected the job to be failed, got %d failed jobs", len(failed.Logged))
+	}
+	if !strings.Contains(failed.Exceptions[0], "the command cannot be decrypted") {
+		t.Errorf("Unexpected exception: %s", failed.Exceptions[0])
+	}
+	if driver.Acked != 1 {
+		t.Errorf("Expected the failed job to be removed from the queue, got %d acks", driver.Acked)
+	}
+}